@@ -1,15 +1,31 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"fmt"
+	"io/fs"
 	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/soulteary/ip-helper/model/banner"
 	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/dns"
 	"github.com/soulteary/ip-helper/model/ftp"
 	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/leaktest"
+	"github.com/soulteary/ip-helper/model/mux"
 	configParser "github.com/soulteary/ip-helper/model/parse-config"
+	proxyProtocol "github.com/soulteary/ip-helper/model/proxy-protocol"
+	sshServer "github.com/soulteary/ip-helper/model/ssh-server"
+	"github.com/soulteary/ip-helper/model/stun"
+	"github.com/soulteary/ip-helper/model/supervisor"
 	"github.com/soulteary/ip-helper/model/telnet"
 	"github.com/soulteary/ip-helper/model/web"
+	"github.com/soulteary/ip-helper/model/whois"
 )
 
 //go:embed public
@@ -17,14 +33,129 @@ var EmbedFS embed.FS
 
 func main() {
 	config := configParser.Parse()
+	if config.PrintConfig {
+		fmt.Print(configParser.Dump(config))
+		return
+	}
+	if err := configParser.Validate(config); err != nil {
+		log.Fatalf("配置错误:\n%v\n", err)
+		return
+	}
 
-	ipdb, err := ipInfo.InitIPDB("./data/ipipfree.ipdb")
+	ipdb, err := ipInfo.LoadProviders(config.Providers)
 	if err != nil {
 		log.Fatalf("初始化 IP 数据库失败: %v\n", err)
 		return
 	}
 
-	go telnet.Server(&ipdb, define.TELNET_PORT)
-	go ftp.Server(&ipdb, define.FTP_PORT)
-	web.Server(config, &ipdb)
+	// 收到 SIGINT 或 SIGTERM 后停止接受新连接，并等待进行中的连接处理完成
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	go ipdb.Watch(ctx, config.ReloadInterval, reloadSignals)
+
+	// 部署在 TCP 负载均衡之后时，从 PROXY protocol 头部获取客户端地址
+	wrapListener := func(listener net.Listener) net.Listener { return listener }
+	if config.ProxyProtocol {
+		trusted, _ := ipInfo.ParseTrustedProxies(config.ProxyProtocolTrusted)
+		wrapListener = func(listener net.Listener) net.Listener {
+			return proxyProtocol.NewListener(listener, trusted, config.ProxyProtocolTimeout)
+		}
+	}
+
+	services := []supervisor.Service{}
+	if config.EnableTelnet {
+		services = append(services, supervisor.TCP("TELNET", config.TelnetAddr, func(ctx context.Context, listener net.Listener) error {
+			return telnet.Serve(ctx, ipdb, wrapListener(listener))
+		}))
+	}
+	if config.EnableFTP {
+		passive, _ := ftp.ParsePassive(config.FTPPassiveAddr, config.FTPPassivePorts)
+		services = append(services, supervisor.TCP("FTP", config.FTPAddr, func(ctx context.Context, listener net.Listener) error {
+			return ftp.Serve(ctx, ipdb, passive, wrapListener(listener))
+		}))
+	}
+	// WHOIS、邮件等端口上基于文本行的简单协议，只在欢迎信息和响应中返回查询结果
+	banners := []struct {
+		enabled  bool
+		addr     string
+		protocol *banner.Protocol
+	}{
+		{config.EnableWhois, config.WhoisAddr, whois.Protocol},
+		{config.EnableSMTP, config.SMTPAddr, banner.SMTP},
+		{config.EnablePOP3, config.POP3Addr, banner.POP3},
+		{config.EnableIMAP, config.IMAPAddr, banner.IMAP},
+		{config.EnableFinger, config.FingerAddr, banner.Finger},
+	}
+	for _, b := range banners {
+		if !b.enabled {
+			continue
+		}
+		protocol := b.protocol
+		services = append(services, supervisor.TCP(protocol.Name, b.addr, func(ctx context.Context, listener net.Listener) error {
+			return protocol.Serve(ctx, ipdb, wrapListener(listener))
+		}))
+	}
+	if config.EnableSSH {
+		hostKey, err := sshServer.LoadHostKey(config.SSHHostKey)
+		if err != nil {
+			log.Fatalf("初始化 SSH 服务失败: %v\n", err)
+			return
+		}
+		if config.SSHHostKey == "" {
+			log.Println("提醒：SSH 服务使用临时主机密钥，重启后客户端会提示主机密钥变化，可以设置 `SSH_HOST_KEY` 保存密钥")
+		}
+		sshConfig := sshServer.NewConfig(hostKey)
+		services = append(services, supervisor.TCP("SSH", config.SSHAddr, func(ctx context.Context, listener net.Listener) error {
+			return sshServer.Serve(ctx, ipdb, sshConfig, wrapListener(listener))
+		}))
+	}
+	// DNS 泄露测试与 STUN 地址对比依赖对应的服务记录的数据，未启用时 WEB 服务不提供
+	features := web.Features{}
+	// 静态资源编译在程序中，不依赖运行时的工作目录
+	if assets, err := fs.Sub(EmbedFS, "public"); err == nil {
+		features.Assets = assets
+	}
+	if config.EnableDNS {
+		features.LeakTest = leaktest.NewStore(define.LEAKTEST_SESSION_TTL)
+		handler := dns.NewHandler(ipdb, config.DNSZone)
+		handler.EnableLeakTest(config.LeakTestZone, features.LeakTest)
+		services = append(services,
+			supervisor.UDP("DNS", config.DNSAddr, func(ctx context.Context, conn net.PacketConn) error {
+				return dns.ServeUDP(ctx, handler, conn)
+			}),
+			supervisor.TCP("DNS", config.DNSAddr, func(ctx context.Context, listener net.Listener) error {
+				return dns.ServeTCP(ctx, handler, wrapListener(listener))
+			}),
+		)
+	}
+	if config.EnableSTUN {
+		features.STUN = stun.NewObservations(define.STUN_OBSERVATION_TTL)
+		handler := stun.NewHandler(config.STUNSoftware, features.STUN)
+		services = append(services,
+			supervisor.UDP("STUN", config.STUNAddr, func(ctx context.Context, conn net.PacketConn) error {
+				return stun.ServeUDP(ctx, handler, conn)
+			}),
+			supervisor.TCP("STUN", config.STUNAddr, func(ctx context.Context, listener net.Listener) error {
+				return stun.ServeTCP(ctx, handler, wrapListener(listener))
+			}),
+		)
+	}
+	if config.EnableWeb {
+		services = append(services, supervisor.TCP("WEB", ":"+config.Port, func(ctx context.Context, listener net.Listener) error {
+			if !config.Multiplex {
+				return web.Serve(ctx, config, ipdb, features, wrapListener(listener))
+			}
+			// WEB 端口同时接受已开启的 TELNET 和 FTP 服务的客户端
+			return mux.Serve(ctx, config, ipdb, wrapListener(listener), func(ctx context.Context, listener net.Listener) error {
+				return web.Serve(ctx, config, ipdb, features, listener)
+			})
+		}))
+	}
+
+	code := supervisor.Run(ctx, config.ShutdownTimeout, services...)
+	stop()
+	os.Exit(code)
 }
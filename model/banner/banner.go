@@ -0,0 +1,137 @@
+package banner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/supervisor"
+)
+
+// Handler 处理一个 TCP 连接，返回前应该关闭连接
+type Handler func(ipdb ipInfo.Provider, conn net.Conn)
+
+// Server 监听端口并确认数据库可用后提供服务，用于单独运行某个协议
+func Server(name string, ipdb ipInfo.Provider, port string, handler Handler) error {
+	listener, err := net.Listen("tcp", port)
+	if err != nil {
+		return fmt.Errorf("%s 服务器启动失败: %v", name, err)
+	}
+	defer listener.Close()
+
+	info := ipInfo.Lookup(ipdb, "127.0.0.1")
+	if len(info.Info) == 0 {
+		return fmt.Errorf("IP 数据库加载失败")
+	}
+
+	log.Printf("%s 服务器已启动，监听端口: %s\n", name, port)
+
+	return Serve(context.Background(), name, ipdb, listener, handler)
+}
+
+// Serve 在已绑定的监听器上提供服务，ctx 结束后等待进行中的连接处理完成
+func Serve(ctx context.Context, name string, ipdb ipInfo.Provider, listener net.Listener, handler Handler) error {
+	return supervisor.ServeConn(ctx, name, listener, func(conn net.Conn) {
+		handler(ipdb, conn)
+	})
+}
+
+// Protocol 基于文本行的简单协议，连接建立后发送欢迎信息，之后逐行应答客户端的命令
+type Protocol struct {
+	Name string
+	// Greeting 返回连接建立后发送的欢迎信息，为 nil 时等待客户端先发送
+	Greeting func(s *Session) string
+	// Respond 处理一行输入，quit 为 true 时发送响应后关闭连接，响应为空时不发送
+	Respond func(s *Session, line string) (reply string, quit bool)
+	// TooLong 输入过长时的响应，为空时直接关闭连接
+	TooLong string
+	// QuitTooLong 为 true 时发送 TooLong 后关闭连接，用于只处理一行查询的协议
+	QuitTooLong bool
+	// Timeout 等待每行输入的超时时间，为 0 时使用 BANNER_IDLE_TIMEOUT
+	Timeout time.Duration
+	// MaxLineLength 单行输入的最大长度，为 0 时使用 BANNER_MAX_LINE_LENGTH
+	MaxLineLength int
+}
+
+// Session 一个连接的状态，提供给协议的格式化函数使用
+type Session struct {
+	IPDB       ipInfo.Provider
+	ClientIP   string
+	RemoteAddr net.Addr
+}
+
+// Summary 返回 `IP 地址信息` 格式的单行查询结果
+func (s *Session) Summary(ip string) string {
+	info := fn.RemoveDuplicates(ipInfo.Lookup(s.IPDB, ip).Info)
+	return strings.TrimSpace(ip + " " + strings.Join(info, " "))
+}
+
+// Server 单独运行该协议
+func (p *Protocol) Server(ipdb ipInfo.Provider, port string) error {
+	return Server(p.Name, ipdb, port, p.HandleConnection)
+}
+
+// Serve 在已绑定的监听器上提供该协议的服务
+func (p *Protocol) Serve(ctx context.Context, ipdb ipInfo.Provider, listener net.Listener) error {
+	return Serve(ctx, p.Name, ipdb, listener, p.HandleConnection)
+}
+
+// HandleConnection 发送欢迎信息后逐行处理命令，直到协议要求关闭连接、客户端断开或空闲超时
+func (p *Protocol) HandleConnection(ipdb ipInfo.Provider, conn net.Conn) {
+	defer conn.Close()
+	s := &Session{IPDB: ipdb, ClientIP: fn.GetBaseIP(conn.RemoteAddr().String()), RemoteAddr: conn.RemoteAddr()}
+	if p.Greeting != nil && !p.send(conn, p.Greeting(s)) {
+		return
+	}
+
+	timeout, maxLineLength := p.Timeout, p.MaxLineLength
+	if timeout == 0 {
+		timeout = define.BANNER_IDLE_TIMEOUT
+	}
+	if maxLineLength == 0 {
+		maxLineLength = define.BANNER_MAX_LINE_LENGTH
+	}
+	reader := bufio.NewReaderSize(conn, maxLineLength)
+	for {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		line, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// 丢弃过长命令的剩余部分
+			for err == bufio.ErrBufferFull {
+				_, err = reader.ReadSlice('\n')
+			}
+			if err != nil || p.TooLong == "" || !p.send(conn, p.TooLong) || p.QuitTooLong {
+				return
+			}
+			continue
+		}
+		// 客户端没有发送换行就关闭了写入时，仍然处理最后一行
+		if err != nil && len(line) == 0 {
+			return
+		}
+
+		reply, quit := p.Respond(s, strings.TrimRight(string(line), "\r\n"))
+		if !p.send(conn, reply) || quit || err != nil {
+			return
+		}
+	}
+}
+
+// send 发送响应并补充结尾的换行，响应为空时不发送
+func (p *Protocol) send(conn net.Conn, message string) bool {
+	if message == "" {
+		return true
+	}
+	if _, err := conn.Write([]byte(message + "\r\n")); err != nil {
+		log.Printf("%s 服务发送消息时发生错误: %v\n", p.Name, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package banner_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/soulteary/ip-helper/model/banner"
+	"github.com/soulteary/ip-helper/model/testutil"
+)
+
+// startServer 在随机端口上运行协议，返回监听地址
+func startServer(t *testing.T, protocol *banner.Protocol) string {
+	t.Helper()
+	memory := testutil.MemoryProvider(t)
+	return testutil.Serve(t, func(ctx context.Context, listener net.Listener) error {
+		return protocol.Serve(ctx, memory, listener)
+	})
+}
+
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	t.Helper()
+	conn := testutil.Dial(t, addr)
+	return &client{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *client) send(line string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(line + "\r\n")); err != nil {
+		c.t.Fatalf("发送失败: %v", err)
+	}
+}
+
+// expect 读取一行并检查前缀
+func (c *client) expect(prefix string) string {
+	c.t.Helper()
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("读取响应失败，期望 %q: %v", prefix, err)
+	}
+	if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, "\r\n") {
+		c.t.Fatalf("响应应该以 %q 开头并以 CRLF 结尾，实际为 %q", prefix, line)
+	}
+	return line
+}
+
+// expectClosed 检查服务端已经关闭连接
+func (c *client) expectClosed() {
+	c.t.Helper()
+	if rest, err := io.ReadAll(c.reader); err != nil || len(rest) != 0 {
+		c.t.Errorf("连接应该已经关闭，实际读取到 %q %v", rest, err)
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	c := dial(t, startServer(t, banner.SMTP))
+	if line := c.expect("220 ip-helper ESMTP 127.0.0.1"); !strings.Contains(line, "本机地址") {
+		t.Errorf("欢迎信息应该包含查询结果，实际为 %q", line)
+	}
+	c.send("EHLO client.example.com")
+	c.expect("250-ip-helper Hello [127.0.0.1]")
+	c.expect("250-127.0.0.1 本机地址")
+	c.expect("250 ")
+	c.send("MAIL FROM:<a@example.com>")
+	c.expect("550 ")
+	c.send("UNKNOWN")
+	c.expect("502 ")
+	c.send(strings.Repeat("A", 1024))
+	c.expect("500 ")
+	c.send("NOOP")
+	c.expect("250 ")
+	c.send("QUIT")
+	c.expect("221 ")
+	c.expectClosed()
+}
+
+func TestPOP3(t *testing.T) {
+	c := dial(t, startServer(t, banner.POP3))
+	c.expect("+OK ip-helper POP3 ready, 127.0.0.1 本机地址")
+	c.send("CAPA")
+	c.expect("+OK")
+	c.expect("USER")
+	c.expect(".")
+	c.send("USER anonymous")
+	c.expect("+OK 127.0.0.1")
+	c.send("PASS secret")
+	c.expect("+OK")
+	c.send("STAT")
+	c.expect("+OK 0 0")
+	c.send("LIST 1")
+	c.expect("-ERR no such message")
+	c.send("UIDL 1")
+	c.expect("-ERR no such message")
+	c.send("LIST")
+	c.expect("+OK 0 messages")
+	c.expect(".")
+	c.send("RETR 1")
+	c.expect("-ERR")
+	c.send("QUIT")
+	c.expect("+OK")
+	c.expectClosed()
+}
+
+func TestIMAP(t *testing.T) {
+	c := dial(t, startServer(t, banner.IMAP))
+	c.expect("* OK [CAPABILITY IMAP4rev1 LOGINDISABLED] ip-helper IMAP ready, 127.0.0.1 本机地址")
+	c.send("a1 CAPABILITY")
+	c.expect("* CAPABILITY IMAP4rev1")
+	c.expect("a1 OK")
+	c.send("a2 LOGIN user pass")
+	c.expect("a2 NO [AUTHENTICATIONFAILED] 127.0.0.1")
+	c.send("a3")
+	c.expect("* BAD")
+	c.send("a4 SELECT INBOX")
+	c.expect("a4 BAD")
+	c.send("a5 logout")
+	c.expect("* BYE")
+	c.expect("a5 OK")
+	c.expectClosed()
+}
+
+func TestFinger(t *testing.T) {
+	addr := startServer(t, banner.Finger)
+
+	c := dial(t, addr)
+	c.send("")
+	c.expect("127.0.0.1 本机地址")
+	c.expectClosed()
+
+	c = dial(t, addr)
+	c.send("/W 192.0.2.1")
+	c.expect("192.0.2.1 测试网络")
+	c.expectClosed()
+
+	// 非 IP 的查询返回客户端自己的信息
+	c = dial(t, addr)
+	c.send("root")
+	c.expect("127.0.0.1 本机地址")
+	c.expectClosed()
+
+	// 过长的查询直接关闭连接
+	c = dial(t, addr)
+	c.send(strings.Repeat("A", 1024))
+	c.expectClosed()
+}
+
+func TestServerStartupError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	defer listener.Close()
+
+	err = banner.SMTP.Server(testutil.MemoryProvider(t), listener.Addr().String())
+	if err == nil || !strings.Contains(err.Error(), "SMTP 服务器启动失败") {
+		t.Errorf("端口被占用时应该返回启动失败，实际为 %v", err)
+	}
+}
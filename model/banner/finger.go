@@ -0,0 +1,22 @@
+package banner
+
+import (
+	"strings"
+
+	"github.com/soulteary/ip-helper/model/fn"
+)
+
+// Finger 按 RFC 1288 读取一行查询后输出结果并关闭连接，
+// 查询为 IP 地址时返回该地址的信息，否则返回客户端自己的信息
+var Finger = &Protocol{
+	Name: "FINGER",
+	Respond: func(s *Session, line string) (string, bool) {
+		// 忽略表示详细输出的 /W 前缀
+		query := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "/W"))
+		ip := s.ClientIP
+		if fn.IsValidIPAddress(query) {
+			ip = query
+		}
+		return s.Summary(ip), true
+	},
+}
@@ -0,0 +1,31 @@
+package banner
+
+import "strings"
+
+// IMAP 欢迎信息中包含查询结果，不支持登录
+var IMAP = &Protocol{
+	Name: "IMAP",
+	Greeting: func(s *Session) string {
+		return "* OK [CAPABILITY IMAP4rev1 LOGINDISABLED] ip-helper IMAP ready, " + s.Summary(s.ClientIP)
+	},
+	Respond: func(s *Session, line string) (string, bool) {
+		// 命令以客户端生成的标签开头，响应需要带上相同的标签
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return "* BAD Missing command tag.", false
+		}
+		tag, command := fields[0], strings.ToUpper(fields[1])
+		switch command {
+		case "CAPABILITY":
+			return "* CAPABILITY IMAP4rev1 LOGINDISABLED\r\n" + tag + " OK CAPABILITY completed.", false
+		case "NOOP":
+			return "* OK " + s.Summary(s.ClientIP) + "\r\n" + tag + " OK NOOP completed.", false
+		case "LOGIN", "AUTHENTICATE":
+			return tag + " NO [AUTHENTICATIONFAILED] " + s.Summary(s.ClientIP), false
+		case "LOGOUT":
+			return "* BYE ip-helper IMAP server logging out\r\n" + tag + " OK LOGOUT completed.", true
+		}
+		return tag + " BAD Command not implemented.", false
+	},
+	TooLong: "* BAD Line too long.",
+}
@@ -0,0 +1,32 @@
+package banner
+
+import "strings"
+
+// POP3 欢迎信息中包含查询结果，接受任意用户登录，邮箱始终为空
+var POP3 = &Protocol{
+	Name: "POP3",
+	Greeting: func(s *Session) string {
+		return "+OK ip-helper POP3 ready, " + s.Summary(s.ClientIP)
+	},
+	Respond: func(s *Session, line string) (string, bool) {
+		command, argument, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "CAPA":
+			return "+OK Capability list follows\r\nUSER\r\n.", false
+		case "USER", "PASS", "NOOP", "RSET":
+			return "+OK " + s.Summary(s.ClientIP), false
+		case "STAT":
+			return "+OK 0 0", false
+		case "LIST", "UIDL":
+			// 指定邮件编号时只返回单行响应，邮箱为空所以编号总是不存在
+			if strings.TrimSpace(argument) != "" {
+				return "-ERR no such message", false
+			}
+			return "+OK 0 messages\r\n.", false
+		case "QUIT":
+			return "+OK Bye", true
+		}
+		return "-ERR Command not implemented.", false
+	},
+	TooLong: "-ERR Line too long.",
+}
@@ -0,0 +1,34 @@
+package banner
+
+import "strings"
+
+// SMTP 欢迎信息和 EHLO 响应中包含查询结果，不接收邮件
+var SMTP = &Protocol{
+	Name: "SMTP",
+	Greeting: func(s *Session) string {
+		return "220 ip-helper ESMTP " + s.Summary(s.ClientIP)
+	},
+	Respond: func(s *Session, line string) (string, bool) {
+		command, _, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO":
+			return "250-ip-helper Hello [" + s.ClientIP + "]\r\n" +
+				"250-" + s.Summary(s.ClientIP) + "\r\n" +
+				"250 8BITMIME", false
+		case "HELO":
+			return "250 ip-helper Hello [" + s.ClientIP + "] " + s.Summary(s.ClientIP), false
+		case "NOOP", "RSET":
+			return "250 2.0.0 OK", false
+		case "MAIL", "RCPT", "DATA", "VRFY", "EXPN":
+			return "550 5.7.1 This server does not accept mail.", false
+		case "HELP":
+			return "214 2.0.0 Send EHLO to see your IP address.", false
+		case "QUIT":
+			return "221 2.0.0 Bye", true
+		case "":
+			return "500 5.5.2 Syntax error, command unrecognized.", false
+		}
+		return "502 5.5.1 Command not implemented.", false
+	},
+	TooLong: "500 5.5.2 Line too long.",
+}
@@ -0,0 +1,15 @@
+package define
+
+import "time"
+
+var (
+	SMTP_PORT   = ":25"
+	POP3_PORT   = ":110"
+	IMAP_PORT   = ":143"
+	FINGER_PORT = ":79"
+
+	// 连接空闲超过该时间后自动断开
+	BANNER_IDLE_TIMEOUT = 60 * time.Second
+	// 单行命令的最大长度，与 SMTP 的命令行长度限制相同
+	BANNER_MAX_LINE_LENGTH = 512
+)
@@ -1,9 +1,67 @@
 package define
 
+import "time"
+
 type Config struct {
 	Debug bool
 
 	Domain string
 	Port   string
 	Token  string
+
+	TrustedProxies  []string
+	ClientIPHeaders []string
+	// DownloadTools 没有可识别的 Accept 请求头时，按 User-Agent 判断为命令行工具的关键字
+	DownloadTools []string
+	// TemplateDir 自定义页面模板和静态资源的目录，为空时使用内置模板
+	TemplateDir string
+	// AssetsDir 静态资源目录，其中的文件优先于内置的静态资源
+	AssetsDir string
+	// BatchMaxItems 批量查询接口单次最多查询的 IP 数量
+	BatchMaxItems int
+
+	DBPath         string
+	Providers      []string
+	ReloadInterval time.Duration
+
+	EnableWeb    bool
+	EnableTelnet bool
+	EnableFTP    bool
+	EnableDNS    bool
+	EnableSTUN   bool
+	EnableWhois  bool
+	EnableSMTP   bool
+	EnablePOP3   bool
+	EnableIMAP   bool
+	EnableFinger bool
+	EnableSSH    bool
+	TelnetAddr   string
+	FTPAddr      string
+	DNSAddr      string
+	DNSZone      string
+	LeakTestZone string
+	STUNAddr     string
+	STUNSoftware string
+	WhoisAddr    string
+	SMTPAddr     string
+	POP3Addr     string
+	IMAPAddr     string
+	FingerAddr   string
+	SSHAddr      string
+	SSHHostKey   string
+
+	// FTPPassiveAddr、FTPPassivePorts FTP 被动模式告知客户端的地址和数据连接的端口范围
+	FTPPassiveAddr  string
+	FTPPassivePorts string
+
+	ShutdownTimeout time.Duration
+
+	ProxyProtocol        bool
+	ProxyProtocolTrusted []string
+	ProxyProtocolTimeout time.Duration
+
+	Multiplex        bool
+	MultiplexTimeout time.Duration
+
+	PrintConfig bool
 }
@@ -0,0 +1,16 @@
+package define
+
+import "time"
+
+var (
+	DNS_PORT = ":53"
+
+	// TCP 连接空闲超过该时间后自动断开
+	DNS_TCP_IDLE_TIMEOUT = 10 * time.Second
+	// 不支持 EDNS 的客户端，UDP 响应的最大长度
+	DNS_UDP_MAX_SIZE = 512
+	// 支持 EDNS 时 UDP 响应的最大长度，避免 IP 分片
+	DNS_EDNS_MAX_SIZE = 1232
+	// 否定应答的缓存时间，写入 SOA 记录的 TTL 与 MINIMUM 字段
+	DNS_NEGATIVE_TTL = 60 * time.Second
+)
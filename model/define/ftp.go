@@ -1,5 +1,12 @@
 package define
 
+import "time"
+
 var (
 	FTP_PORT = ":21"
+
+	// 控制连接空闲超过该时间后自动断开
+	FTP_IDLE_TIMEOUT = 60 * time.Second
+	// 等待客户端建立数据连接以及传输数据的超时时间
+	FTP_DATA_TIMEOUT = 10 * time.Second
 )
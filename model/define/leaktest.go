@@ -0,0 +1,14 @@
+package define
+
+import "time"
+
+var (
+	// 泄露测试会话的有效期，超过后查询结果会被清除
+	LEAKTEST_SESSION_TTL = 10 * time.Minute
+	// 同时保留的会话数量上限，超出时清除最早创建的会话
+	LEAKTEST_MAX_SESSIONS = 10000
+	// 每个会话最多记录的解析器数量
+	LEAKTEST_MAX_RESOLVERS = 32
+	// 每个会话需要浏览器解析的域名数量
+	LEAKTEST_NAMES = 6
+)
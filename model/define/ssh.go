@@ -0,0 +1,12 @@
+package define
+
+import "time"
+
+var (
+	SSH_PORT = ":22"
+
+	// SSH_SERVER_VERSION 握手时发送的版本标识
+	SSH_SERVER_VERSION = "SSH-2.0-ip-helper"
+	// 完成握手并输出查询结果的最长时间，超时后断开连接
+	SSH_TIMEOUT = 30 * time.Second
+)
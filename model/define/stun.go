@@ -0,0 +1,16 @@
+package define
+
+import "time"
+
+var (
+	STUN_PORT = ":3478"
+
+	// SOFTWARE 属性的默认值，为空时不发送
+	STUN_SOFTWARE = "ip-helper"
+	// TCP 连接空闲超过该时间后自动断开
+	STUN_TCP_IDLE_TIMEOUT = 30 * time.Second
+	// STUN 观察到的映射地址的保留时间，用于与 HTTP 请求的地址对比
+	STUN_OBSERVATION_TTL = 5 * time.Minute
+	// 同时保留的映射地址数量上限
+	STUN_MAX_OBSERVATIONS = 10000
+)
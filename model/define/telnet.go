@@ -1,5 +1,15 @@
 package define
 
+import "time"
+
 var (
 	TELNET_PORT = ":23"
+
+	// 连接空闲超过该时间后自动断开
+	TELNET_IDLE_TIMEOUT = 60 * time.Second
+	// 输出查询结果后等待客户端输入的时间，超时后再进入交互模式，
+	// 只读取第一行的脚本不会收到协商数据和提示符
+	TELNET_PROMPT_DELAY = 200 * time.Millisecond
+	// 单行命令的最大长度
+	TELNET_MAX_LINE_LENGTH = 256
 )
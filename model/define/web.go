@@ -0,0 +1,19 @@
+package define
+
+import "time"
+
+var (
+	// 文件名不带内容指纹的静态资源的缓存时间，过期后通过 ETag 重新验证
+	ASSETS_MAX_AGE = time.Hour
+	// 文件名带有内容指纹的静态资源内容不会变化，可以长期缓存
+	ASSETS_IMMUTABLE_MAX_AGE = 365 * 24 * time.Hour
+	// 查询指定 IP 的结果的缓存时间，过期后通过包含数据库版本的 ETag 重新验证
+	LOOKUP_MAX_AGE = 5 * time.Minute
+
+	// 批量查询接口单次最多查询的 IP 数量，流式接口不限制数量
+	BATCH_MAX_ITEMS = 1000
+	// 批量查询中每一项输入的最大长度
+	BATCH_MAX_ITEM_LENGTH = 256
+	// 流式批量查询每输出多少条结果发送一次
+	BATCH_FLUSH_ITEMS = 100
+)
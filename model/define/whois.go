@@ -0,0 +1,12 @@
+package define
+
+import "time"
+
+var (
+	WHOIS_PORT = ":43"
+
+	// 等待客户端发送查询的超时时间
+	WHOIS_TIMEOUT = 10 * time.Second
+	// 查询的最大长度
+	WHOIS_MAX_QUERY_LENGTH = 256
+)
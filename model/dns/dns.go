@@ -0,0 +1,176 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/leaktest"
+	"github.com/soulteary/ip-helper/model/supervisor"
+)
+
+// Handler 应答指定区域内的查询，返回发起查询的解析器地址及其地理位置
+type Handler struct {
+	ipdb ipInfo.Provider
+	zone string
+
+	leakZone  string
+	leakStore *leaktest.Store
+}
+
+func NewHandler(ipdb ipInfo.Provider, zone string) *Handler {
+	return &Handler{ipdb: ipdb, zone: normalizeZone(zone)}
+}
+
+// EnableLeakTest 应答 `<token>.<n>.<zone>` 格式的查询，并将解析器地址记录到对应的测试会话
+func (h *Handler) EnableLeakTest(zone string, store *leaktest.Store) {
+	h.leakZone, h.leakStore = normalizeZone(zone), store
+}
+
+func normalizeZone(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(zone, "."))
+}
+
+func inZone(name string, zone string) bool {
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// answerLeakTest 在查询 `<token>.<n>.<zone>` 时记录解析器地址，不存在的会话返回 NXDOMAIN
+//
+// 开启 QNAME 最小化的解析器会先查询区域本身和 `<n>.<zone>`，这两级域名返回 NODATA，
+// 返回 NXDOMAIN 会让解析器按 RFC 8020 认为其下不存在任何域名，不再发送完整的查询
+func (h *Handler) answerLeakTest(query *message, remote netip.Addr) []byte {
+	if query.question.name == h.leakZone {
+		return encodeNegative(query, RCODE_SUCCESS, h.leakZone)
+	}
+	labels := strings.Split(strings.TrimSuffix(query.question.name, "."+h.leakZone), ".")
+	sequence := labels[len(labels)-1]
+	if _, err := strconv.Atoi(sequence); err != nil || len(labels) > 2 {
+		return encodeNegative(query, RCODE_NAME_ERROR, h.leakZone)
+	}
+	if len(labels) == 2 && !h.leakStore.Record(labels[0], remote.String()) {
+		return encodeNegative(query, RCODE_NAME_ERROR, h.leakZone)
+	}
+	return encodeNegative(query, RCODE_SUCCESS, h.leakZone)
+}
+
+func (h *Handler) describe(label string, value string, ip string) string {
+	return strings.TrimSpace(label + " " + value + " " + strings.Join(ipInfo.Lookup(h.ipdb, ip).Info, " "))
+}
+
+// answers 根据查询类型生成应答记录
+//
+// A 与 AAAA 返回解析器的地址，TXT 同时返回解析器和 Client Subnet 的地理位置
+func (h *Handler) answers(query *message, remote netip.Addr) []record {
+	answers := []record{}
+	switch query.question.qtype {
+	case TYPE_A:
+		if remote.Is4() {
+			answers = append(answers, record{TYPE_A, remote.AsSlice()})
+		}
+	case TYPE_AAAA:
+		if remote.Is6() {
+			answers = append(answers, record{TYPE_AAAA, remote.AsSlice()})
+		}
+	case TYPE_TXT:
+		answers = append(answers, record{TYPE_TXT, txtData(h.describe("resolver", remote.String(), remote.String()))})
+		// 前缀长度为 0 表示客户端不希望透露子网
+		if query.edns != nil && query.edns.clientSubnet.Bits() > 0 {
+			subnet := query.edns.clientSubnet
+			answers = append(answers, record{TYPE_TXT, txtData(h.describe("client-subnet", subnet.String(), subnet.Addr().String()))})
+		}
+	}
+	return answers
+}
+
+// Answer 处理一条查询，UDP 响应超出客户端能接收的长度时设置 TC 标志让客户端改用 TCP 重试
+//
+// 无法识别的消息返回 nil，不做应答
+func (h *Handler) Answer(msg []byte, remote net.Addr, udp bool) []byte {
+	query, err := parseMessage(msg)
+	if query == nil {
+		return nil
+	}
+	if err != nil {
+		query.question = question{}
+		return encodeResponse(query, RCODE_FORMAT_ERROR, nil, false)
+	}
+	if query.flags&opcodeMask != 0 {
+		return encodeResponse(query, RCODE_NOT_IMPLEMENTED, nil, false)
+	}
+	if query.edns != nil && query.edns.version != 0 {
+		return encodeResponse(query, RCODE_BAD_VERSION, nil, false)
+	}
+	leakTest := h.leakStore != nil && inZone(query.question.name, h.leakZone)
+	if query.question.qclass != CLASS_IN || (!leakTest && !inZone(query.question.name, h.zone)) {
+		return encodeResponse(query, RCODE_REFUSED, nil, false)
+	}
+
+	ip, err := netip.ParseAddr(fn.GetBaseIP(remote.String()))
+	if err != nil {
+		return encodeResponse(query, RCODE_REFUSED, nil, false)
+	}
+	if leakTest {
+		return h.answerLeakTest(query, ip.Unmap())
+	}
+	response := encodeResponse(query, RCODE_SUCCESS, h.answers(query, ip.Unmap()), false)
+	if udp && len(response) > udpMaxSize(query) {
+		return encodeResponse(query, RCODE_SUCCESS, nil, true)
+	}
+	return response
+}
+
+// udpMaxSize 根据客户端声明的 EDNS 缓冲区大小计算 UDP 响应的最大长度
+func udpMaxSize(query *message) int {
+	if query.edns == nil {
+		return define.DNS_UDP_MAX_SIZE
+	}
+	return max(define.DNS_UDP_MAX_SIZE, min(int(query.edns.udpSize), define.DNS_EDNS_MAX_SIZE))
+}
+
+// ServeUDP 在 UDP 上提供服务，ctx 结束后等待进行中的查询处理完成
+func ServeUDP(ctx context.Context, handler *Handler, conn net.PacketConn) error {
+	return supervisor.ServePacket(ctx, "DNS", conn, func(msg []byte, addr net.Addr) []byte {
+		return handler.Answer(msg, addr, true)
+	})
+}
+
+// ServeTCP 在 TCP 上提供服务，同一连接上可以连续发送多条查询
+func ServeTCP(ctx context.Context, handler *Handler, listener net.Listener) error {
+	return supervisor.ServeConn(ctx, "DNS", listener, func(conn net.Conn) {
+		HandleConnection(handler, conn)
+	})
+}
+
+// HandleConnection 处理 TCP 连接，每条消息前有两个字节的长度
+func HandleConnection(handler *Handler, conn net.Conn) {
+	defer conn.Close()
+	length := make([]byte, 2)
+	for {
+		conn.SetReadDeadline(time.Now().Add(define.DNS_TCP_IDLE_TIMEOUT))
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return
+		}
+		msg := make([]byte, binary.BigEndian.Uint16(length))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+		response := handler.Answer(msg, conn.RemoteAddr(), false)
+		if response == nil {
+			return
+		}
+		if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...)); err != nil {
+			log.Printf("DNS 服务发送消息时发生错误: %v\n", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package dns_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/dns"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/leaktest"
+)
+
+type queryOptions struct {
+	opcode  int
+	edns    bool
+	udpSize uint16
+	// Client Subnet 选项的内容
+	subnet []byte
+}
+
+func buildQuery(name string, qtype uint16, options queryOptions) []byte {
+	msg := []byte{0x12, 0x34, byte(options.opcode << 3), 0, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(name, ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, dns.CLASS_IN)
+	if options.edns {
+		msg[11] = 1
+		msg = append(msg, 0)
+		msg = binary.BigEndian.AppendUint16(msg, dns.TYPE_OPT)
+		msg = binary.BigEndian.AppendUint16(msg, options.udpSize)
+		msg = append(msg, 0, 0, 0, 0)
+		if options.subnet != nil {
+			msg = binary.BigEndian.AppendUint16(msg, uint16(4+len(options.subnet)))
+			msg = binary.BigEndian.AppendUint16(msg, dns.OPTION_CLIENT_SUBNET)
+			msg = binary.BigEndian.AppendUint16(msg, uint16(len(options.subnet)))
+			msg = append(msg, options.subnet...)
+		} else {
+			msg = binary.BigEndian.AppendUint16(msg, 0)
+		}
+	}
+	return msg
+}
+
+type response struct {
+	rcode     int
+	truncated bool
+	answers   []string
+	// 权威部分的记录类型
+	authority []uint16
+	// OPT 记录中的选项数据
+	options []byte
+}
+
+// parseResponse 解析测试用的响应，answers 中 TXT 记录合并为字符串，A 与 AAAA 记录转换为地址
+func parseResponse(t *testing.T, msg []byte) response {
+	t.Helper()
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg) != 0x1234 || msg[2]&0x80 == 0 {
+		t.Fatalf("响应头部错误: %x", msg)
+	}
+	r := response{rcode: int(msg[3] & 0xf), truncated: msg[2]&0x02 != 0}
+	qdcount, ancount := binary.BigEndian.Uint16(msg[4:]), binary.BigEndian.Uint16(msg[6:])
+	nscount, arcount := binary.BigEndian.Uint16(msg[8:]), binary.BigEndian.Uint16(msg[10:])
+
+	offset := 12
+	skipName := func() {
+		for msg[offset] != 0 {
+			if msg[offset]&0xc0 == 0xc0 {
+				offset++
+				break
+			}
+			offset += int(msg[offset]) + 1
+		}
+		offset++
+	}
+	for i := 0; i < int(qdcount); i++ {
+		skipName()
+		offset += 4
+	}
+	for i := 0; i < int(ancount)+int(nscount)+int(arcount); i++ {
+		skipName()
+		rtype := binary.BigEndian.Uint16(msg[offset:])
+		if i >= int(ancount) && i < int(ancount)+int(nscount) {
+			r.authority = append(r.authority, rtype)
+			offset += 10 + int(binary.BigEndian.Uint16(msg[offset+8:]))
+			continue
+		}
+		if rtype == dns.TYPE_OPT {
+			r.rcode |= int(msg[offset+4]) << 4
+		}
+		length := int(binary.BigEndian.Uint16(msg[offset+8:]))
+		data := msg[offset+10 : offset+10+length]
+		offset += 10 + length
+
+		switch rtype {
+		case dns.TYPE_A, dns.TYPE_AAAA:
+			r.answers = append(r.answers, net.IP(data).String())
+		case dns.TYPE_TXT:
+			text := ""
+			for len(data) > 0 {
+				length := int(data[0])
+				text += string(data[1 : 1+length])
+				data = data[1+length:]
+			}
+			r.answers = append(r.answers, text)
+		case dns.TYPE_OPT:
+			r.options = data
+		}
+	}
+	return r
+}
+
+func TestAnswer(t *testing.T) {
+	ipdb, _ := ipInfo.NewMemoryProvider("memory", map[string][]string{
+		"127.0.0.0/8":    {"本机地址"},
+		"203.0.113.0/24": {"测试网络"},
+		"198.51.100.0/24": {
+			strings.Repeat("很长的地址", 40),
+		},
+	})
+	handler := dns.NewHandler(ipdb, "MyIP.example.com.")
+	local := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353}
+	long := &net.UDPAddr{IP: net.ParseIP("198.51.100.1"), Port: 5353}
+
+	tests := []struct {
+		name          string
+		query         []byte
+		remote        net.Addr
+		tcp           bool
+		wantRcode     int
+		wantAnswers   []string
+		wantTruncated bool
+		wantOptions   []byte
+	}{
+		{
+			name:        "TXT",
+			query:       buildQuery("myip.example.com", dns.TYPE_TXT, queryOptions{}),
+			remote:      local,
+			wantAnswers: []string{"resolver 127.0.0.1 本机地址"},
+		},
+		{
+			name:        "TXT with client subnet",
+			query:       buildQuery("random.myip.example.com", dns.TYPE_TXT, queryOptions{edns: true, udpSize: 4096, subnet: []byte{0, 1, 24, 0, 203, 0, 113}}),
+			remote:      local,
+			wantAnswers: []string{"resolver 127.0.0.1 本机地址", "client-subnet 203.0.113.0/24 测试网络"},
+			wantOptions: []byte{0, 8, 0, 7, 0, 1, 24, 24, 203, 0, 113},
+		},
+		{
+			name:        "Client subnet opt-out",
+			query:       buildQuery("myip.example.com", dns.TYPE_TXT, queryOptions{edns: true, udpSize: 4096, subnet: []byte{0, 1, 0, 0}}),
+			remote:      local,
+			wantAnswers: []string{"resolver 127.0.0.1 本机地址"},
+			wantOptions: []byte{0, 8, 0, 4, 0, 1, 0, 0},
+		},
+		{
+			name:        "A",
+			query:       buildQuery("MYIP.example.com", dns.TYPE_A, queryOptions{}),
+			remote:      local,
+			wantAnswers: []string{"127.0.0.1"},
+		},
+		{
+			name:   "AAAA for IPv4 resolver",
+			query:  buildQuery("myip.example.com", dns.TYPE_AAAA, queryOptions{}),
+			remote: local,
+		},
+		{
+			name:        "AAAA",
+			query:       buildQuery("myip.example.com", dns.TYPE_AAAA, queryOptions{}),
+			remote:      &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 5353},
+			wantAnswers: []string{"2001:db8::1"},
+		},
+		{
+			name:      "Outside zone",
+			query:     buildQuery("example.com", dns.TYPE_TXT, queryOptions{}),
+			remote:    local,
+			wantRcode: dns.RCODE_REFUSED,
+		},
+		{
+			name:      "Invalid client subnet",
+			query:     buildQuery("myip.example.com", dns.TYPE_TXT, queryOptions{edns: true, udpSize: 4096, subnet: []byte{0, 1, 16, 0, 203, 0, 113}}),
+			remote:    local,
+			wantRcode: dns.RCODE_FORMAT_ERROR,
+		},
+		{
+			name:      "Unsupported opcode",
+			query:     buildQuery("myip.example.com", dns.TYPE_TXT, queryOptions{opcode: 2}),
+			remote:    local,
+			wantRcode: dns.RCODE_NOT_IMPLEMENTED,
+		},
+		{
+			name:          "Truncated over UDP",
+			query:         buildQuery("myip.example.com", dns.TYPE_TXT, queryOptions{}),
+			remote:        long,
+			wantTruncated: true,
+		},
+		{
+			name:        "Large EDNS buffer",
+			query:       buildQuery("myip.example.com", dns.TYPE_TXT, queryOptions{edns: true, udpSize: 4096}),
+			remote:      long,
+			wantAnswers: []string{"resolver 198.51.100.1 " + strings.Repeat("很长的地址", 40)},
+			wantOptions: []byte{},
+		},
+		{
+			name:        "Not truncated over TCP",
+			query:       buildQuery("myip.example.com", dns.TYPE_TXT, queryOptions{}),
+			remote:      long,
+			tcp:         true,
+			wantAnswers: []string{"resolver 198.51.100.1 " + strings.Repeat("很长的地址", 40)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := parseResponse(t, handler.Answer(tt.query, tt.remote, !tt.tcp))
+			if r.rcode != tt.wantRcode {
+				t.Errorf("响应码应该为 %d，实际为 %d", tt.wantRcode, r.rcode)
+			}
+			if r.truncated != tt.wantTruncated {
+				t.Errorf("截断标志应该为 %v，实际为 %v", tt.wantTruncated, r.truncated)
+			}
+			if strings.Join(r.answers, "\n") != strings.Join(tt.wantAnswers, "\n") {
+				t.Errorf("应答记录应该为 %q，实际为 %q", tt.wantAnswers, r.answers)
+			}
+			if tt.wantOptions != nil && string(r.options) != string(tt.wantOptions) {
+				t.Errorf("OPT 选项应该为 %v，实际为 %v", tt.wantOptions, r.options)
+			}
+		})
+	}
+
+	if handler.Answer([]byte{1, 2, 3}, local, true) != nil {
+		t.Error("过短的消息不应该应答")
+	}
+}
+
+func TestAnswerQuestionEcho(t *testing.T) {
+	ipdb, _ := ipInfo.NewMemoryProvider("memory", map[string][]string{"127.0.0.0/8": {"本机地址"}})
+	handler := dns.NewHandler(ipdb, "myip.example.com")
+	local := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353}
+
+	// 问题部分需要与查询逐字节一致，使用 DNS 0x20 的解析器会丢弃大小写不同的响应
+	t.Run("Mixed case", func(t *testing.T) {
+		query := buildQuery("rAnDoM.MyIp.eXaMpLe.CoM", dns.TYPE_A, queryOptions{})
+		msg := handler.Answer(query, local, true)
+		if r := parseResponse(t, msg); r.rcode != dns.RCODE_SUCCESS || strings.Join(r.answers, ",") != "127.0.0.1" {
+			t.Fatalf("混合大小写的查询应该正常应答，实际为 %d %v", r.rcode, r.answers)
+		}
+		if string(msg[12:len(query)]) != string(query[12:]) {
+			t.Errorf("问题部分应该为 %q，实际为 %q", query[12:], msg[12:len(query)])
+		}
+	})
+
+	// 标签中的点号不是分隔符，`MyIp.example` 和 `com` 两个标签组成的域名不属于 myip.example.com
+	t.Run("Literal dot in label", func(t *testing.T) {
+		query := []byte{0x12, 0x34, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0}
+		query = append(query, 12)
+		query = append(query, "MyIp.example"...)
+		query = append(query, 3)
+		query = append(query, "com"...)
+		query = append(query, 0, 0, dns.TYPE_A, 0, dns.CLASS_IN)
+		msg := handler.Answer(query, local, true)
+		if r := parseResponse(t, msg); r.rcode != dns.RCODE_REFUSED {
+			t.Errorf("区域外的查询应该返回 REFUSED，实际为 %d", r.rcode)
+		}
+		if string(msg[12:len(query)]) != string(query[12:]) {
+			t.Errorf("问题部分应该为 %q，实际为 %q", query[12:], msg[12:len(query)])
+		}
+	})
+}
+
+func TestLeakTest(t *testing.T) {
+	ipdb, _ := ipInfo.NewMemoryProvider("memory", nil)
+	store := leaktest.NewStore(time.Minute)
+	handler := dns.NewHandler(ipdb, "myip.example.com")
+	handler.EnableLeakTest("leak.example.com", store)
+	token, _ := store.NewSession()
+	resolver := &net.UDPAddr{IP: net.ParseIP("192.0.2.53"), Port: 5353}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantRcode int
+	}{
+		{"Session name", token + ".1.leak.example.com", dns.RCODE_SUCCESS},
+		{"Unknown session", "unknown.1.leak.example.com", dns.RCODE_NAME_ERROR},
+		{"Sequence without token", "1.leak.example.com", dns.RCODE_SUCCESS},
+		{"Token without sequence", token + ".leak.example.com", dns.RCODE_NAME_ERROR},
+		{"Invalid sequence", token + ".x.leak.example.com", dns.RCODE_NAME_ERROR},
+		{"Too many labels", "a." + token + ".1.leak.example.com", dns.RCODE_NAME_ERROR},
+		{"Zone apex", "leak.example.com", dns.RCODE_SUCCESS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := parseResponse(t, handler.Answer(buildQuery(tt.query, dns.TYPE_A, queryOptions{}), resolver, true))
+			if r.rcode != tt.wantRcode || len(r.answers) != 0 {
+				t.Errorf("响应码应该为 %d 且没有应答记录，实际为 %d %q", tt.wantRcode, r.rcode, r.answers)
+			}
+			if len(r.authority) != 1 || r.authority[0] != dns.TYPE_SOA {
+				t.Errorf("否定应答的权威部分应该包含 SOA 记录，实际为 %v", r.authority)
+			}
+		})
+	}
+
+	resolvers, _ := store.Resolvers(token)
+	if len(resolvers) != 1 || resolvers[0].IP != "192.0.2.53" {
+		t.Errorf("只应该在查询完整域名时记录解析器，实际为 %+v", resolvers)
+	}
+}
+
+func TestLeakTestQNAMEMinimisation(t *testing.T) {
+	ipdb, _ := ipInfo.NewMemoryProvider("memory", nil)
+	store := leaktest.NewStore(time.Minute)
+	handler := dns.NewHandler(ipdb, "myip.example.com")
+	handler.EnableLeakTest("leak.example.com", store)
+	token, _ := store.NewSession()
+	resolver := &net.UDPAddr{IP: net.ParseIP("192.0.2.53"), Port: 5353}
+
+	// 解析器逐级查询，前面的查询返回 NXDOMAIN 时不会继续查询下一级
+	for _, query := range []struct {
+		name  string
+		qtype uint16
+	}{
+		{"example.com", dns.TYPE_A},
+		{"leak.example.com", dns.TYPE_A},
+		{"3.leak.example.com", dns.TYPE_A},
+		{token + ".3.leak.example.com", dns.TYPE_AAAA},
+	} {
+		r := parseResponse(t, handler.Answer(buildQuery(query.name, query.qtype, queryOptions{}), resolver, true))
+		if query.name == "example.com" {
+			if r.rcode != dns.RCODE_REFUSED {
+				t.Errorf("%s 不属于区域，应该拒绝，实际为 %d", query.name, r.rcode)
+			}
+			continue
+		}
+		if r.rcode != dns.RCODE_SUCCESS {
+			t.Fatalf("%s 应该返回 NOERROR，实际为 %d", query.name, r.rcode)
+		}
+	}
+
+	resolvers, _ := store.Resolvers(token)
+	if len(resolvers) != 1 || resolvers[0].IP != "192.0.2.53" {
+		t.Errorf("逐级查询后应该记录解析器，实际为 %+v", resolvers)
+	}
+}
+
+func TestServe(t *testing.T) {
+	ipdb, _ := ipInfo.NewMemoryProvider("memory", map[string][]string{"127.0.0.0/8": {"本机地址"}})
+	handler := dns.NewHandler(ipdb, "myip.example.com")
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听 UDP 失败: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听 TCP 失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 2)
+	go func() { done <- dns.ServeUDP(ctx, handler, packetConn) }()
+	go func() { done <- dns.ServeTCP(ctx, handler, listener) }()
+
+	query := buildQuery("myip.example.com", dns.TYPE_TXT, queryOptions{})
+
+	udpConn, err := net.Dial("udp", packetConn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("连接 UDP 失败: %v", err)
+	}
+	defer udpConn.Close()
+	udpConn.SetDeadline(time.Now().Add(2 * time.Second))
+	udpConn.Write(query)
+	buf := make([]byte, 512)
+	n, err := udpConn.Read(buf)
+	if err != nil {
+		t.Fatalf("读取 UDP 响应失败: %v", err)
+	}
+	if r := parseResponse(t, buf[:n]); len(r.answers) != 1 || r.answers[0] != "resolver 127.0.0.1 本机地址" {
+		t.Errorf("UDP 应答错误: %q", r.answers)
+	}
+
+	tcpConn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("连接 TCP 失败: %v", err)
+	}
+	defer tcpConn.Close()
+	tcpConn.SetDeadline(time.Now().Add(2 * time.Second))
+	// 同一连接上连续发送两条查询
+	for i := 0; i < 2; i++ {
+		tcpConn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(query))), query...))
+		length := make([]byte, 2)
+		if _, err := io.ReadFull(tcpConn, length); err != nil {
+			t.Fatalf("读取 TCP 响应失败: %v", err)
+		}
+		msg := make([]byte, binary.BigEndian.Uint16(length))
+		if _, err := io.ReadFull(tcpConn, msg); err != nil {
+			t.Fatalf("读取 TCP 响应失败: %v", err)
+		}
+		if r := parseResponse(t, msg); len(r.answers) != 1 {
+			t.Errorf("TCP 应答错误: %q", r.answers)
+		}
+	}
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("服务不应该返回错误，实际为 %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("服务应该在 ctx 结束后返回")
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"strings"
+
+	"github.com/soulteary/ip-helper/model/define"
+)
+
+// 资源记录类型
+const (
+	TYPE_A    = 1
+	TYPE_SOA  = 6
+	TYPE_TXT  = 16
+	TYPE_AAAA = 28
+	TYPE_OPT  = 41
+
+	CLASS_IN = 1
+)
+
+// 响应码，参考 RFC 1035 与 RFC 6891
+const (
+	RCODE_SUCCESS         = 0
+	RCODE_FORMAT_ERROR    = 1
+	RCODE_NAME_ERROR      = 3
+	RCODE_NOT_IMPLEMENTED = 4
+	RCODE_REFUSED         = 5
+	RCODE_BAD_VERSION     = 16
+)
+
+// EDNS Client Subnet 选项，参考 RFC 7871
+const OPTION_CLIENT_SUBNET = 8
+
+const (
+	flagResponse      = 1 << 15
+	flagAuthoritative = 1 << 10
+	flagTruncated     = 1 << 9
+	flagRecursion     = 1 << 8
+	opcodeMask        = 0xf << 11
+)
+
+const headerLength = 12
+
+var errFormat = errors.New("DNS 消息格式错误")
+
+type question struct {
+	// name 小写且不带结尾点号的域名，只用于匹配区域
+	name string
+	// raw 查询中域名的原始编码，响应中原样返回，保留 DNS 0x20 随机化的大小写
+	raw    []byte
+	qtype  uint16
+	qclass uint16
+}
+
+type edns struct {
+	udpSize uint16
+	version uint8
+	// 客户端未携带 Client Subnet 选项时为零值
+	clientSubnet netip.Prefix
+}
+
+type message struct {
+	id       uint16
+	flags    uint16
+	question question
+	// 查询未携带 OPT 记录时为 nil
+	edns *edns
+}
+
+type record struct {
+	rtype uint16
+	data  []byte
+}
+
+// readName 读取域名，支持压缩指针
+//
+// 返回小写且不带结尾点号的域名，标签中的点号和反斜杠会被转义，
+// 以及展开压缩指针后保留原始大小写的编码
+func readName(msg []byte, offset int) (string, []byte, int, error) {
+	var name strings.Builder
+	raw := []byte{}
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", nil, 0, errFormat
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.ToLower(name.String()), append(raw, 0), next, nil
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(msg) || jumps > 10 {
+				return "", nil, 0, errFormat
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3fff)
+			jumps++
+		case length&0xc0 != 0:
+			return "", nil, 0, errFormat
+		default:
+			if offset+1+length > len(msg) {
+				return "", nil, 0, errFormat
+			}
+			label := msg[offset+1 : offset+1+length]
+			if name.Len() > 0 {
+				name.WriteByte('.')
+			}
+			for _, c := range label {
+				if c == '.' || c == '\\' {
+					name.WriteByte('\\')
+				}
+				name.WriteByte(c)
+			}
+			raw = append(raw, msg[offset:offset+1+length]...)
+			offset += 1 + length
+		}
+	}
+}
+
+// parseClientSubnet 解析 Client Subnet 选项，地址中超出前缀长度的位必须为 0
+func parseClientSubnet(data []byte) (netip.Prefix, error) {
+	if len(data) < 4 {
+		return netip.Prefix{}, errFormat
+	}
+	family, source := binary.BigEndian.Uint16(data), int(data[2])
+	address := data[4:]
+
+	var size int
+	switch family {
+	case 1:
+		size = 4
+	case 2:
+		size = 16
+	default:
+		return netip.Prefix{}, errFormat
+	}
+	if source > size*8 || len(address) != (source+7)/8 {
+		return netip.Prefix{}, errFormat
+	}
+	full := make([]byte, size)
+	copy(full, address)
+	addr, _ := netip.AddrFromSlice(full)
+	prefix := netip.PrefixFrom(addr, source)
+	if prefix.Masked().Addr() != addr {
+		return netip.Prefix{}, errFormat
+	}
+	return prefix, nil
+}
+
+func parseOPT(header []byte, rdata []byte) (*edns, error) {
+	result := &edns{
+		udpSize: binary.BigEndian.Uint16(header[2:]),
+		version: header[5],
+	}
+	for len(rdata) > 0 {
+		if len(rdata) < 4 {
+			return nil, errFormat
+		}
+		code, length := binary.BigEndian.Uint16(rdata), int(binary.BigEndian.Uint16(rdata[2:]))
+		if len(rdata) < 4+length {
+			return nil, errFormat
+		}
+		if code == OPTION_CLIENT_SUBNET {
+			prefix, err := parseClientSubnet(rdata[4 : 4+length])
+			if err != nil {
+				return nil, err
+			}
+			result.clientSubnet = prefix
+		}
+		rdata = rdata[4+length:]
+	}
+	return result, nil
+}
+
+// parseMessage 解析只包含一个问题的查询，id 与 flags 在格式错误时也会尽量返回，用于构造错误响应
+func parseMessage(msg []byte) (*message, error) {
+	if len(msg) < headerLength {
+		return nil, errFormat
+	}
+	m := &message{
+		id:    binary.BigEndian.Uint16(msg),
+		flags: binary.BigEndian.Uint16(msg[2:]),
+	}
+	if m.flags&flagResponse != 0 {
+		return nil, errFormat
+	}
+	qdcount := binary.BigEndian.Uint16(msg[4:])
+	records := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+	if qdcount != 1 {
+		return m, errFormat
+	}
+
+	name, raw, offset, err := readName(msg, headerLength)
+	if err != nil || offset+4 > len(msg) {
+		return m, errFormat
+	}
+	m.question = question{
+		name:   name,
+		raw:    raw,
+		qtype:  binary.BigEndian.Uint16(msg[offset:]),
+		qclass: binary.BigEndian.Uint16(msg[offset+2:]),
+	}
+	offset += 4
+
+	for i := 0; i < records; i++ {
+		_, _, end, err := readName(msg, offset)
+		if err != nil || end+10 > len(msg) {
+			return m, errFormat
+		}
+		header := msg[end : end+10]
+		length := int(binary.BigEndian.Uint16(header[8:]))
+		if end+10+length > len(msg) {
+			return m, errFormat
+		}
+		if binary.BigEndian.Uint16(header) == TYPE_OPT {
+			if m.edns != nil {
+				return m, errFormat
+			}
+			m.edns, err = parseOPT(header, msg[end+10:end+10+length])
+			if err != nil {
+				return m, err
+			}
+		}
+		offset = end + 10 + length
+	}
+	return m, nil
+}
+
+// txtData 编码 TXT 记录，超过 255 字节的字符串会被拆分
+func txtData(text string) []byte {
+	data := []byte{}
+	for {
+		chunk := text
+		if len(chunk) > 255 {
+			chunk = chunk[:255]
+		}
+		data = append(data, byte(len(chunk)))
+		data = append(data, chunk...)
+		text = text[len(chunk):]
+		if text == "" {
+			return data
+		}
+	}
+}
+
+func appendName(buf []byte, name string) []byte {
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			buf = append(buf, byte(len(label)))
+			buf = append(buf, label...)
+		}
+	}
+	return append(buf, 0)
+}
+
+// soaData 编码区域的 SOA 记录，只用于否定应答，序列号等字段没有实际意义
+func soaData(zone string) []byte {
+	data := appendName(nil, zone)
+	data = appendName(data, "hostmaster."+zone)
+	negativeTTL := uint32(define.DNS_NEGATIVE_TTL.Seconds())
+	for _, value := range []uint32{1, 3600, 600, 86400, negativeTTL} {
+		data = binary.BigEndian.AppendUint32(data, value)
+	}
+	return data
+}
+
+// encodeResponse 构造响应，answers 为 nil 时只包含问题部分
+func encodeResponse(query *message, rcode int, answers []record, truncated bool) []byte {
+	return encodeMessage(query, rcode, answers, "", truncated)
+}
+
+// encodeNegative 构造 NXDOMAIN 或 NODATA 响应，权威部分包含 zone 的 SOA 记录，解析器据此缓存否定应答
+func encodeNegative(query *message, rcode int, zone string) []byte {
+	return encodeMessage(query, rcode, nil, zone, false)
+}
+
+func encodeMessage(query *message, rcode int, answers []record, soaZone string, truncated bool) []byte {
+	flags := uint16(flagResponse|flagAuthoritative) | query.flags&(opcodeMask|flagRecursion) | uint16(rcode&0xf)
+	if truncated {
+		flags |= flagTruncated
+		answers = nil
+	}
+	arcount := 0
+	if query.edns != nil {
+		arcount = 1
+	}
+	qdcount := 0
+	if query.question.qtype != 0 {
+		qdcount = 1
+	}
+
+	buf := make([]byte, headerLength, 512)
+	binary.BigEndian.PutUint16(buf, query.id)
+	binary.BigEndian.PutUint16(buf[2:], flags)
+	binary.BigEndian.PutUint16(buf[4:], uint16(qdcount))
+	binary.BigEndian.PutUint16(buf[6:], uint16(len(answers)))
+	if soaZone != "" {
+		binary.BigEndian.PutUint16(buf[8:], 1)
+	}
+	binary.BigEndian.PutUint16(buf[10:], uint16(arcount))
+
+	if qdcount == 1 {
+		buf = append(buf, query.question.raw...)
+		buf = binary.BigEndian.AppendUint16(buf, query.question.qtype)
+		buf = binary.BigEndian.AppendUint16(buf, query.question.qclass)
+	}
+	for _, answer := range answers {
+		// 指向问题中的域名
+		buf = append(buf, 0xc0, headerLength)
+		buf = binary.BigEndian.AppendUint16(buf, answer.rtype)
+		buf = binary.BigEndian.AppendUint16(buf, CLASS_IN)
+		// TTL 为 0，避免解析器缓存查询结果
+		buf = binary.BigEndian.AppendUint32(buf, 0)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(answer.data)))
+		buf = append(buf, answer.data...)
+	}
+	if soaZone != "" {
+		soa := soaData(soaZone)
+		buf = appendName(buf, soaZone)
+		buf = binary.BigEndian.AppendUint16(buf, TYPE_SOA)
+		buf = binary.BigEndian.AppendUint16(buf, CLASS_IN)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(define.DNS_NEGATIVE_TTL.Seconds()))
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(soa)))
+		buf = append(buf, soa...)
+	}
+
+	if query.edns != nil {
+		buf = append(buf, 0)
+		buf = binary.BigEndian.AppendUint16(buf, TYPE_OPT)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(define.DNS_EDNS_MAX_SIZE))
+		buf = append(buf, byte(rcode>>4), 0, 0, 0)
+
+		options := []byte{}
+		if subnet := query.edns.clientSubnet; subnet.IsValid() {
+			// 应答内容与整个子网相关，scope 与 source 前缀长度相同
+			address := subnet.Addr().AsSlice()[:(subnet.Bits()+7)/8]
+			family := uint16(1)
+			if subnet.Addr().Is6() {
+				family = 2
+			}
+			options = binary.BigEndian.AppendUint16(options, OPTION_CLIENT_SUBNET)
+			options = binary.BigEndian.AppendUint16(options, uint16(4+len(address)))
+			options = binary.BigEndian.AppendUint16(options, family)
+			options = append(options, byte(subnet.Bits()), byte(subnet.Bits()))
+			options = append(options, address...)
+		}
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(options)))
+		buf = append(buf, options...)
+	}
+	return buf
+}
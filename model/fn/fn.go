@@ -2,11 +2,10 @@ package fn
 
 import (
 	"bytes"
-	"fmt"
-	"io"
 	"net"
-	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -53,21 +52,31 @@ func IsValidIPAddress(ip string) bool {
 	return false
 }
 
-func IsDownloadTool(userAgent string) bool {
-	ua := strings.ToLower(userAgent)
+// DEFAULT_DOWNLOAD_TOOLS 默认识别为命令行工具或程序的 User-Agent 关键字
+var DEFAULT_DOWNLOAD_TOOLS = []string{
+	"curl",
+	"wget",
+	"aria2",
+	"python-requests",
+	"axios",
+	"got",
+	"postman",
+	"httpie",
+	"go-http-client",
+	"powershell",
+	"okhttp",
+}
 
-	downloadTools := []string{
-		"curl",
-		"wget",
-		"aria2",
-		"python-requests",
-		"axios",
-		"got",
-		"postman",
+// IsDownloadTool 根据 User-Agent 判断请求是否来自命令行工具，tools 为空时使用默认的关键字列表
+func IsDownloadTool(userAgent string, tools ...string) bool {
+	ua := strings.ToLower(userAgent)
+	if len(tools) == 0 {
+		tools = DEFAULT_DOWNLOAD_TOOLS
 	}
 
-	for _, tool := range downloadTools {
-		if strings.Contains(ua, tool) {
+	for _, tool := range tools {
+		tool = strings.ToLower(strings.TrimSpace(tool))
+		if tool != "" && strings.Contains(ua, tool) {
 			return true
 		}
 	}
@@ -75,6 +84,43 @@ func IsDownloadTool(userAgent string) bool {
 	return false
 }
 
+// ParseAcceptLanguage 解析 Accept-Language 请求头，按权重从高到低返回语言列表
+func ParseAcceptLanguage(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+	languages := []language{}
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			value, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = value
+		}
+		if quality <= 0 {
+			continue
+		}
+		languages = append(languages, language{tag: tag, quality: quality})
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	result := []string{}
+	for _, language := range languages {
+		result = append(result, language.tag)
+	}
+	return result
+}
+
 func GetDomainOnly(urlStr string) string {
 	targetURL := urlStr
 	if !strings.Contains(urlStr, "://") {
@@ -111,21 +157,3 @@ func GetBaseIP(addrWithPort string) string {
 	}
 	return host
 }
-
-func HTTPGet(link string) ([]byte, error) {
-	resp, err := http.Get(link)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("服务器返回非200状态码: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应内容失败: %v", err)
-	}
-	return body, nil
-}
@@ -1,12 +1,8 @@
 package fn_test
 
 import (
-	"bytes"
-	"net/http"
-	"net/http/httptest"
 	"strings"
 	"testing"
-	"time"
 
 	fn "github.com/soulteary/ip-helper/model/fn"
 )
@@ -122,6 +118,9 @@ func TestIsDownloadTool(t *testing.T) {
 		{"Regular browser", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36", false},
 		{"Empty string", "", false},
 		{"Postman agent", "PostmanRuntime/7.26.8", true},
+		{"HTTPie", "HTTPie/3.2.2", true},
+		{"Go http client", "Go-http-client/1.1", true},
+		{"PowerShell", "Mozilla/5.0 (Windows NT 10.0; Microsoft Windows 10.0.19045; en-US) PowerShell/7.4.0", true},
 	}
 
 	for _, tt := range tests {
@@ -131,6 +130,38 @@ func TestIsDownloadTool(t *testing.T) {
 			}
 		})
 	}
+
+	// 自定义关键字列表时不再使用默认列表
+	if !fn.IsDownloadTool("MyScript/1.0", "myscript") {
+		t.Error("IsDownloadTool should match custom tools case-insensitively")
+	}
+	if fn.IsDownloadTool("curl/7.64.1", "myscript") {
+		t.Error("IsDownloadTool should not use default tools when custom tools are given")
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected []string
+	}{
+		{"Empty header", "", []string{}},
+		{"Single language", "en", []string{"en"}},
+		{"Browser default", "zh-CN,zh;q=0.9,en;q=0.8", []string{"zh-CN", "zh", "en"}},
+		{"Unordered weights", "en;q=0.5, fr , de;q=0.7", []string{"fr", "de", "en"}},
+		{"Wildcard and zero weight", "*;q=0.1, ja;q=0, ko", []string{"ko"}},
+		{"Invalid weight", "en;q=abc, fr", []string{"fr"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fn.ParseAcceptLanguage(tt.header)
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", tt.header, got, tt.expected)
+			}
+		})
+	}
 }
 
 func TestGetDomainOnly(t *testing.T) {
@@ -199,160 +230,3 @@ func TestGetBaseIP(t *testing.T) {
 		})
 	}
 }
-
-func TestHTTPGet(t *testing.T) {
-	tests := []struct {
-		name          string
-		setupServer   func() (*httptest.Server, error)
-		expectedError string
-		expectedBody  []byte
-	}{
-		{
-			name: "Success response",
-			setupServer: func() (*httptest.Server, error) {
-				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte("test content"))
-				})), nil
-			},
-			expectedBody: []byte("test content"),
-		},
-		{
-			name: "Non-200 status code",
-			setupServer: func() (*httptest.Server, error) {
-				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					w.WriteHeader(http.StatusNotFound)
-				})), nil
-			},
-			expectedError: "服务器返回非200状态码: 404",
-		},
-		{
-			name: "Network error - connection refused",
-			setupServer: func() (*httptest.Server, error) {
-				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
-				ts.Close() // Close immediately to simulate connection error
-				return ts, nil
-			},
-			expectedError: "connection refused",
-		},
-		{
-			name: "Invalid URL",
-			setupServer: func() (*httptest.Server, error) {
-				return nil, nil // No server needed
-			},
-			expectedError: "unsupported protocol scheme",
-		},
-		{
-			name: "Read body error",
-			setupServer: func() (*httptest.Server, error) {
-				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					w.Header().Set("Content-Length", "100") // 声明100字节的内容
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte("incomplete")) // 但只写入一部分数据
-					// 刷新数据，确保部分数据被发送
-					if f, ok := w.(http.Flusher); ok {
-						f.Flush()
-					}
-					// 等待一小段时间确保数据被发送
-					time.Sleep(10 * time.Millisecond)
-					// 然后关闭连接，导致读取错误
-					if cn, ok := w.(http.CloseNotifier); ok {
-						cn.(interface{ CloseConnection() }).CloseConnection()
-					}
-				})), nil
-			},
-			expectedError: "unexpected EOF",
-		},
-		{
-			name: "Empty response body",
-			setupServer: func() (*httptest.Server, error) {
-				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					w.WriteHeader(http.StatusOK)
-				})), nil
-			},
-			expectedBody: []byte{},
-		},
-		{
-			name: "Large response body",
-			setupServer: func() (*httptest.Server, error) {
-				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					w.WriteHeader(http.StatusOK)
-					// Generate 1MB of data
-					data := make([]byte, 1024*1024)
-					for i := range data {
-						data[i] = byte(i % 256)
-					}
-					w.Write(data)
-				})), nil
-			},
-			expectedBody: nil, // We'll check the length instead
-		},
-		{
-			name: "Slow response",
-			setupServer: func() (*httptest.Server, error) {
-				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					time.Sleep(100 * time.Millisecond) // Simulate slow response
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte("delayed content"))
-				})), nil
-			},
-			expectedBody: []byte("delayed content"),
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var ts *httptest.Server
-			var testURL string
-
-			// Setup test server if provided
-			if tt.setupServer != nil {
-				var err error
-				ts, err = tt.setupServer()
-				if err != nil {
-					t.Fatalf("Failed to setup test server: %v", err)
-				}
-				if ts != nil {
-					defer ts.Close()
-					testURL = ts.URL
-				}
-			}
-
-			if testURL == "" && tt.name == "Invalid URL" {
-				testURL = "invalid-url"
-			}
-
-			// Test the HTTPGet function
-			body, err := fn.HTTPGet(testURL)
-
-			// Verify error cases
-			if tt.expectedError != "" {
-				if err == nil {
-					t.Errorf("Expected error containing '%s' but got nil", tt.expectedError)
-				} else if !strings.Contains(err.Error(), tt.expectedError) {
-					t.Errorf("Expected error containing '%s' but got '%s'", tt.expectedError, err.Error())
-				}
-				return
-			}
-
-			// Verify success cases
-			if err != nil {
-				t.Errorf("Unexpected error: %v", err)
-				return
-			}
-
-			// Special handling for large response test
-			if tt.name == "Large response body" {
-				if len(body) != 1024*1024 {
-					t.Errorf("Expected body length %d, got %d", 1024*1024, len(body))
-				}
-				return
-			}
-
-			// Verify response body
-			if tt.expectedBody != nil && !bytes.Equal(body, tt.expectedBody) {
-				t.Errorf("Expected body %q, got %q", tt.expectedBody, body)
-			}
-		})
-	}
-}
@@ -1,55 +1,43 @@
 package ftp
 
 import (
-	"bytes"
-	"fmt"
-	"log"
+	"context"
 	"net"
 
-	"github.com/soulteary/ip-helper/model/fn"
+	"github.com/soulteary/ip-helper/model/banner"
 	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
-	"github.com/soulteary/ip-helper/model/response"
 )
 
-func Server(ipdb *ipInfo.IPDB, port string) error {
-	listener, err := net.Listen("tcp", port)
-	if err != nil {
-		return fmt.Errorf("FTP 服务器启动失败: %v", err)
-	}
-	defer listener.Close()
+func Server(ipdb ipInfo.Provider, port string) error {
+	return banner.Server("FTP", ipdb, port, HandleConnection)
+}
 
-	info := ipdb.FindByIPIP("127.0.0.1")
-	if len(info) == 0 {
-		return fmt.Errorf("IP 数据库加载失败")
-	}
+// Serve 在已绑定的监听器上提供服务，ctx 结束后等待进行中的连接处理完成
+func Serve(ctx context.Context, ipdb ipInfo.Provider, passive Passive, listener net.Listener) error {
+	return banner.Serve(ctx, "FTP", ipdb, listener, Handler(passive))
+}
 
-	log.Println("FTP 服务器已启动，监听端口:", port)
+// HandleConnection 处理 FTP 控制连接，欢迎信息中直接包含查询结果，
+// 登录后可以通过被动模式下载 ip.json 和 ip.txt
+func HandleConnection(ipdb ipInfo.Provider, conn net.Conn) {
+	Handler(Passive{})(ipdb, conn)
+}
 
-	for {
-		conn, err := listener.Accept()
-		if err != nil {
-			log.Printf("FTP 服务器接受连接时发生错误: %v\n", err)
-			continue
-		}
-		go HandleConnection(ipdb, conn)
+// Handler 返回使用指定被动模式设置处理控制连接的函数
+func Handler(passive Passive) banner.Handler {
+	return func(ipdb ipInfo.Provider, conn net.Conn) {
+		defer conn.Close()
+		newSession(ipdb, passive, conn).run()
 	}
 }
 
-func HandleConnection(ipdb *ipInfo.IPDB, conn net.Conn) {
-	defer conn.Close()
-
-	clientIP := fn.GetBaseIP(conn.RemoteAddr().String())
-	info := ipdb.FindByIPIP(clientIP)
+// Greet 只发送欢迎信息，用于在确定客户端协议之前先行应答
+func Greet(ipdb ipInfo.Provider, conn net.Conn) bool {
+	return newSession(ipdb, Passive{}, conn).greet()
+}
 
-	sendBuf := [][]byte{
-		[]byte("220"),
-		response.RenderJSON(clientIP, info),
-		[]byte("\r\n"),
-	}
-	_, err := conn.Write(bytes.Join(sendBuf, []byte(" ")))
-	if err != nil {
-		log.Println("FTP 服务发送消息时发生错误: ", err)
-		return
-	}
-	conn.Close()
+// Resume 处理已经发送过欢迎信息的控制连接
+func Resume(ipdb ipInfo.Provider, passive Passive, conn net.Conn) {
+	defer conn.Close()
+	newSession(ipdb, passive, conn).serve()
 }
@@ -0,0 +1,62 @@
+package ftp
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Passive 被动模式的数据连接设置，零值表示告知客户端控制连接的本地地址，并使用系统分配的端口
+//
+// 部署在 NAT 或负载均衡之后时，控制连接的本地地址是内网地址，需要设置公网地址，
+// 并将端口范围转发到本服务
+type Passive struct {
+	// Addr 告知客户端的数据连接地址
+	Addr netip.Addr
+	// MinPort、MaxPort 数据连接的端口范围，为 0 时由系统分配
+	MinPort int
+	MaxPort int
+}
+
+// ParsePassive 解析被动模式的公网地址和 `起始-结束` 格式的端口范围，均可以为空
+func ParsePassive(addr string, ports string) (Passive, error) {
+	passive := Passive{}
+	if addr = strings.TrimSpace(addr); addr != "" {
+		ip, err := netip.ParseAddr(addr)
+		if err != nil {
+			return passive, fmt.Errorf("无效的被动模式地址: %s", addr)
+		}
+		passive.Addr = ip.Unmap()
+	}
+	if ports = strings.TrimSpace(ports); ports != "" {
+		start, end, _ := strings.Cut(ports, "-")
+		min, err1 := strconv.Atoi(strings.TrimSpace(start))
+		max, err2 := strconv.Atoi(strings.TrimSpace(end))
+		if err1 != nil || err2 != nil || min < 1 || max > 65535 || min > max {
+			return passive, fmt.Errorf("无效的被动模式端口范围，应为 `起始-结束`: %s", ports)
+		}
+		passive.MinPort, passive.MaxPort = min, max
+	}
+	return passive, nil
+}
+
+// listen 在端口范围内监听数据连接，从随机位置开始依次尝试，避免并发会话总是争用同一个端口
+func (p Passive) listen(ip net.IP) (*net.TCPListener, error) {
+	if p.MinPort == 0 {
+		return net.ListenTCP("tcp", &net.TCPAddr{IP: ip})
+	}
+	count := p.MaxPort - p.MinPort + 1
+	offset := rand.IntN(count)
+	var err error
+	for i := 0; i < count; i++ {
+		var listener *net.TCPListener
+		listener, err = net.ListenTCP("tcp", &net.TCPAddr{IP: ip, Port: p.MinPort + (offset+i)%count})
+		if err == nil {
+			return listener, nil
+		}
+	}
+	return nil, fmt.Errorf("端口范围 %d-%d 已全部占用: %v", p.MinPort, p.MaxPort, err)
+}
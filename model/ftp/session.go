@@ -0,0 +1,319 @@
+package ftp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/response"
+)
+
+// 控制连接单行命令的最大长度
+const MAX_COMMAND_LENGTH = 512
+
+var errCommandTooLong = errors.New("命令过长")
+
+// 只读的虚拟文件，内容为客户端 IP 的查询结果
+var files = []string{"ip.json", "ip.txt"}
+
+// session 一个 FTP 控制连接的状态
+type session struct {
+	ipdb     ipInfo.Provider
+	conn     net.Conn
+	reader   *bufio.Reader
+	clientIP string
+	// proxied 客户端地址来自 PROXY protocol 头部，数据连接会从负载均衡转发过来，无法校验来源地址
+	proxied bool
+
+	passiveConfig Passive
+
+	user     string
+	loggedIn bool
+	passive  net.Listener
+}
+
+func newSession(ipdb ipInfo.Provider, passive Passive, conn net.Conn) *session {
+	s := &session{
+		ipdb:          ipdb,
+		conn:          conn,
+		reader:        bufio.NewReaderSize(conn, MAX_COMMAND_LENGTH),
+		clientIP:      fn.GetBaseIP(conn.RemoteAddr().String()),
+		passiveConfig: passive,
+	}
+	if proxied, ok := conn.(interface{ Proxied() bool }); ok {
+		s.proxied = proxied.Proxied()
+	}
+	return s
+}
+
+func (s *session) reply(code int, message string) bool {
+	_, err := fmt.Fprintf(s.conn, "%d %s\r\n", code, message)
+	if err != nil {
+		log.Printf("FTP 服务发送消息时发生错误: %v\n", err)
+		return false
+	}
+	return true
+}
+
+func (s *session) readCommand() (string, string, error) {
+	s.conn.SetReadDeadline(time.Now().Add(define.FTP_IDLE_TIMEOUT))
+	line, err := s.reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// 丢弃过长命令的剩余部分
+		for err == bufio.ErrBufferFull {
+			_, err = s.reader.ReadSlice('\n')
+		}
+		if err != nil {
+			return "", "", err
+		}
+		return "", "", errCommandTooLong
+	}
+	if err != nil {
+		return "", "", err
+	}
+	command, argument, _ := strings.Cut(strings.TrimRight(string(line), "\r\n"), " ")
+	return strings.ToUpper(command), strings.TrimSpace(argument), nil
+}
+
+func (s *session) close() {
+	if s.passive != nil {
+		s.passive.Close()
+		s.passive = nil
+	}
+}
+
+// greet 发送包含查询结果的欢迎信息
+func (s *session) greet() bool {
+	greeting := response.RenderJSON(s.clientIP, ipInfo.Lookup(s.ipdb, s.clientIP))
+	return s.reply(220, string(greeting))
+}
+
+// run 发送欢迎信息后处理控制连接上的命令
+func (s *session) run() {
+	if s.greet() {
+		s.serve()
+	}
+}
+
+// serve 处理控制连接上的命令，直到客户端退出或超时
+func (s *session) serve() {
+	defer s.close()
+
+	for {
+		command, argument, err := s.readCommand()
+		if err == errCommandTooLong {
+			if !s.reply(500, "Command line too long.") {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				s.reply(421, "Timeout.")
+			}
+			return
+		}
+		if !s.handle(command, argument) {
+			return
+		}
+	}
+}
+
+// handle 处理单条命令，返回 false 时关闭连接
+func (s *session) handle(command string, argument string) bool {
+	switch command {
+	case "USER":
+		if !strings.EqualFold(argument, "anonymous") && !strings.EqualFold(argument, "ftp") {
+			s.user = ""
+			return s.reply(530, "Only anonymous login is allowed.")
+		}
+		s.user, s.loggedIn = argument, false
+		return s.reply(331, "Please specify the password.")
+	case "PASS":
+		if s.user == "" {
+			return s.reply(503, "Login with USER first.")
+		}
+		s.loggedIn = true
+		return s.reply(230, "Login successful.")
+	case "QUIT":
+		s.reply(221, "Goodbye.")
+		return false
+	case "SYST":
+		return s.reply(215, "UNIX Type: L8")
+	case "FEAT":
+		_, err := fmt.Fprint(s.conn, "211-Features:\r\n EPSV\r\n PASV\r\n SIZE\r\n UTF8\r\n211 End\r\n")
+		return err == nil
+	case "OPTS":
+		if strings.EqualFold(argument, "UTF8 ON") {
+			return s.reply(200, "Always in UTF8 mode.")
+		}
+		return s.reply(501, "Option not understood.")
+	case "NOOP":
+		return s.reply(200, "NOOP ok.")
+	}
+
+	if !s.loggedIn {
+		return s.reply(530, "Please login with USER and PASS.")
+	}
+
+	switch command {
+	case "PWD", "XPWD":
+		return s.reply(257, `"/" is the current directory`)
+	case "CWD", "XCWD":
+		if argument == "/" || argument == "." || argument == "" {
+			return s.reply(250, "Directory successfully changed.")
+		}
+		return s.reply(550, "Failed to change directory.")
+	case "CDUP", "XCUP":
+		return s.reply(250, "Directory successfully changed.")
+	case "TYPE":
+		switch strings.ToUpper(argument) {
+		case "A", "A N", "I", "L 8":
+			return s.reply(200, "Switching to "+argument+" mode.")
+		}
+		return s.reply(504, "Unrecognised TYPE command.")
+	case "MODE":
+		if strings.EqualFold(argument, "S") {
+			return s.reply(200, "Mode set to S.")
+		}
+		return s.reply(504, "Bad MODE command.")
+	case "STRU":
+		if strings.EqualFold(argument, "F") {
+			return s.reply(200, "Structure set to F.")
+		}
+		return s.reply(504, "Bad STRU command.")
+	case "PASV":
+		return s.handlePassive(false, argument)
+	case "EPSV":
+		return s.handlePassive(true, argument)
+	case "LIST", "NLST":
+		return s.handleList(command)
+	case "SIZE":
+		content, ok := s.file(argument)
+		if !ok {
+			return s.reply(550, "Could not get file size.")
+		}
+		return s.reply(213, fmt.Sprint(len(content)))
+	case "RETR":
+		return s.handleRetr(argument)
+	case "PORT", "EPRT":
+		return s.reply(502, "Active mode is not supported, use PASV or EPSV.")
+	}
+	return s.reply(502, "Command not implemented.")
+}
+
+// file 返回虚拟文件的内容
+func (s *session) file(name string) ([]byte, bool) {
+	switch strings.TrimPrefix(name, "/") {
+	case "ip.json":
+		return append(response.RenderJSON(s.clientIP, ipInfo.Lookup(s.ipdb, s.clientIP)), '\n'), true
+	case "ip.txt":
+		return response.RenderText(s.clientIP, ipInfo.Lookup(s.ipdb, s.clientIP)), true
+	}
+	return nil, false
+}
+
+func (s *session) handlePassive(extended bool, argument string) bool {
+	if extended && strings.EqualFold(argument, "ALL") {
+		return s.reply(200, "EPSV ALL ok.")
+	}
+	s.close()
+
+	local, ok := s.conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return s.reply(425, "Cannot open data connection.")
+	}
+	ip4 := local.IP.To4()
+	if s.passiveConfig.Addr.IsValid() {
+		ip4 = net.IP(s.passiveConfig.Addr.AsSlice()).To4()
+	}
+	if !extended && ip4 == nil {
+		return s.reply(425, "PASV is not supported over IPv6, use EPSV.")
+	}
+
+	listener, err := s.passiveConfig.listen(local.IP)
+	if err != nil {
+		log.Printf("FTP 服务创建数据连接失败: %v\n", err)
+		return s.reply(425, "Cannot open data connection.")
+	}
+	s.passive = listener
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	if extended {
+		return s.reply(229, fmt.Sprintf("Entering Extended Passive Mode (|||%d|).", port))
+	}
+	return s.reply(227, fmt.Sprintf("Entering Passive Mode (%d,%d,%d,%d,%d,%d).", ip4[0], ip4[1], ip4[2], ip4[3], port>>8, port&0xff))
+}
+
+// acceptData 等待客户端建立数据连接，只接受与控制连接来自同一地址的连接，
+// 控制连接经过 PROXY protocol 时数据连接来自负载均衡，不校验来源地址
+func (s *session) acceptData() (net.Conn, error) {
+	if s.passive == nil {
+		return nil, fmt.Errorf("需要先使用 PASV 或 EPSV")
+	}
+	defer s.close()
+
+	listener := s.passive.(*net.TCPListener)
+	listener.SetDeadline(time.Now().Add(define.FTP_DATA_TIMEOUT))
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if !s.proxied && fn.GetBaseIP(conn.RemoteAddr().String()) != s.clientIP {
+			conn.Close()
+			continue
+		}
+		conn.SetDeadline(time.Now().Add(define.FTP_DATA_TIMEOUT))
+		return conn, nil
+	}
+}
+
+// transfer 通过数据连接发送内容
+func (s *session) transfer(message string, content []byte) bool {
+	if s.passive == nil {
+		return s.reply(425, "Use PASV or EPSV first.")
+	}
+	if !s.reply(150, message) {
+		return false
+	}
+	data, err := s.acceptData()
+	if err != nil {
+		return s.reply(425, "Failed to establish connection.")
+	}
+	_, err = data.Write(content)
+	data.Close()
+	if err != nil {
+		return s.reply(426, "Failure writing network stream.")
+	}
+	return s.reply(226, "Transfer complete.")
+}
+
+func (s *session) handleList(command string) bool {
+	listing := []string{}
+	modTime := time.Now().UTC().Format("Jan _2 15:04")
+	for _, name := range files {
+		if command == "NLST" {
+			listing = append(listing, name)
+			continue
+		}
+		content, _ := s.file(name)
+		listing = append(listing, fmt.Sprintf("-r--r--r-- 1 ftp ftp %d %s %s", len(content), modTime, name))
+	}
+	return s.transfer("Here comes the directory listing.", []byte(strings.Join(listing, "\r\n")+"\r\n"))
+}
+
+func (s *session) handleRetr(name string) bool {
+	content, ok := s.file(name)
+	if !ok {
+		return s.reply(550, "Failed to open file.")
+	}
+	return s.transfer(fmt.Sprintf("Opening BINARY mode data connection for %s (%d bytes).", strings.TrimPrefix(name, "/"), len(content)), content)
+}
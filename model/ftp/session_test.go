@@ -0,0 +1,212 @@
+package ftp_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/ftp"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	proxyProtocol "github.com/soulteary/ip-helper/model/proxy-protocol"
+	"github.com/soulteary/ip-helper/model/testutil"
+)
+
+type ftpClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// readReply 读取一条完整的响应，多行响应会合并返回
+func (c *ftpClient) readReply() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("读取响应失败: %v", err)
+	}
+	reply := line
+	if len(line) > 3 && line[3] == '-' {
+		end := line[:3] + " "
+		for !strings.HasPrefix(line, end) {
+			line, err = c.reader.ReadString('\n')
+			if err != nil {
+				c.t.Fatalf("读取响应失败: %v", err)
+			}
+			reply += line
+		}
+	}
+	return reply
+}
+
+func (c *ftpClient) command(command string, wantCode string) string {
+	c.t.Helper()
+	fmt.Fprintf(c.conn, "%s\r\n", command)
+	reply := c.readReply()
+	if !strings.HasPrefix(reply, wantCode) {
+		c.t.Fatalf("%s 应该返回 %s，实际为: %s", command, wantCode, reply)
+	}
+	return reply
+}
+
+// download 通过被动模式执行命令并返回数据连接上收到的内容
+func (c *ftpClient) download(passive string, command string) string {
+	c.t.Helper()
+	reply := c.command(passive, map[string]string{"PASV": "227", "EPSV": "229"}[passive])
+
+	var port int
+	if passive == "EPSV" {
+		fmt.Sscanf(reply[strings.Index(reply, "|||")+3:], "%d", &port)
+	} else {
+		var h1, h2, h3, h4, p1, p2 int
+		fmt.Sscanf(reply[strings.Index(reply, "(")+1:], "%d,%d,%d,%d,%d,%d", &h1, &h2, &h3, &h4, &p1, &p2)
+		port = p1<<8 | p2
+	}
+	data, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		c.t.Fatalf("建立数据连接失败: %v", err)
+	}
+	defer data.Close()
+
+	c.command(command, "150")
+	data.SetReadDeadline(time.Now().Add(2 * time.Second))
+	content, err := io.ReadAll(data)
+	if err != nil {
+		c.t.Fatalf("读取数据失败: %v", err)
+	}
+	if reply := c.readReply(); !strings.HasPrefix(reply, "226") {
+		c.t.Fatalf("传输完成后应该返回 226，实际为: %s", reply)
+	}
+	return string(content)
+}
+
+// serve 在随机端口上启动 FTP 服务，wrap 不为空时用于包装监听器
+func serve(t *testing.T, passive ftp.Passive, wrap func(net.Listener) net.Listener) string {
+	t.Helper()
+	ipdb := testutil.MemoryProvider(t)
+	return testutil.Serve(t, func(ctx context.Context, listener net.Listener) error {
+		if wrap != nil {
+			listener = wrap(listener)
+		}
+		return ftp.Serve(ctx, ipdb, passive, listener)
+	})
+}
+
+// login 连接服务并以匿名账号登录，欢迎信息不会被读取
+func login(t *testing.T, addr string, header string) *ftpClient {
+	t.Helper()
+	conn := testutil.Dial(t, addr)
+	conn.Write([]byte(header))
+	client := &ftpClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+	client.readReply()
+	client.command("USER anonymous", "331")
+	client.command("PASS guest@example.com", "230")
+	return client
+}
+
+func TestSession(t *testing.T) {
+	conn := testutil.Dial(t, serve(t, ftp.Passive{}, nil))
+	client := &ftpClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+
+	if greeting := client.readReply(); !strings.HasPrefix(greeting, "220 {") || !strings.Contains(greeting, "本机地址") {
+		t.Errorf("欢迎信息应该包含查询结果，实际为: %s", greeting)
+	}
+
+	client.command("PWD", "530")
+	client.command("USER admin", "530")
+	client.command("PASS secret", "503")
+	client.command("USER anonymous", "331")
+	client.command("PASS guest@example.com", "230")
+	client.command("SYST", "215 UNIX")
+	client.command("PWD", `257 "/"`)
+	if feat := client.command("FEAT", "211"); !strings.Contains(feat, " EPSV\r\n") || !strings.HasSuffix(feat, "211 End\r\n") {
+		t.Errorf("FEAT 响应格式错误: %q", feat)
+	}
+	client.command("TYPE I", "200")
+	client.command("SIZE ip.txt", "213")
+	client.command("RETR /etc/passwd", "550")
+	client.command("LIST", "425")
+
+	if text := client.download("EPSV", "RETR ip.txt"); text != "127.0.0.1\n本机地址\n" {
+		t.Errorf("ip.txt 内容错误: %q", text)
+	}
+	if json := client.download("PASV", "RETR ip.json"); !strings.Contains(json, `"ip":"127.0.0.1"`) {
+		t.Errorf("ip.json 内容错误: %q", json)
+	}
+	if listing := client.download("PASV", "LIST"); !strings.Contains(listing, "ip.json") || !strings.Contains(listing, "ip.txt") {
+		t.Errorf("LIST 应该列出所有文件: %q", listing)
+	}
+	if names := client.download("EPSV", "NLST"); names != "ip.json\r\nip.txt\r\n" {
+		t.Errorf("NLST 内容错误: %q", names)
+	}
+
+	client.command("PORT 127,0,0,1,4,1", "502")
+	client.command("QUIT", "221")
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := client.reader.ReadString('\n'); err == nil {
+		t.Error("QUIT 后连接应该关闭")
+	}
+}
+
+func TestPassiveSettings(t *testing.T) {
+	// 先占用一个端口再释放，作为只包含一个端口的范围
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	port := probe.Addr().(*net.TCPAddr).Port
+	probe.Close()
+
+	passive, err := ftp.ParsePassive("198.51.100.7", fmt.Sprintf("%d-%d", port, port))
+	if err != nil {
+		t.Fatalf("解析被动模式设置失败: %v", err)
+	}
+	client := login(t, serve(t, passive, nil), "")
+
+	want := fmt.Sprintf("(198,51,100,7,%d,%d)", port>>8, port&0xff)
+	if reply := client.command("PASV", "227"); !strings.Contains(reply, want) {
+		t.Errorf("PASV 应该告知公网地址和范围内的端口 %s，实际为: %s", want, reply)
+	}
+	if reply := client.command("EPSV", "229"); !strings.Contains(reply, fmt.Sprintf("(|||%d|)", port)) {
+		t.Errorf("EPSV 应该使用范围内的端口 %d，实际为: %s", port, reply)
+	}
+}
+
+func TestParsePassive(t *testing.T) {
+	tests := []struct {
+		addr, ports string
+		wantErr     bool
+	}{
+		{"", "", false},
+		{"203.0.113.10", "30000-30100", false},
+		{"2001:db8::1", "", false},
+		{"example.com", "", true},
+		{"", "30000", true},
+		{"", "30100-30000", true},
+		{"", "0-10", true},
+		{"", "60000-70000", true},
+	}
+	for _, tt := range tests {
+		_, err := ftp.ParsePassive(tt.addr, tt.ports)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePassive(%q, %q) error = %v, wantErr %v", tt.addr, tt.ports, err, tt.wantErr)
+		}
+	}
+}
+
+func TestProxiedDataConnection(t *testing.T) {
+	trusted, _ := ipInfo.ParseTrustedProxies([]string{"127.0.0.1"})
+	addr := serve(t, ftp.Passive{}, func(listener net.Listener) net.Listener {
+		return proxyProtocol.NewListener(listener, trusted, time.Second)
+	})
+	// 控制连接的客户端地址来自 PROXY 头部，数据连接从负载均衡的地址建立
+	client := login(t, addr, "PROXY TCP4 192.0.2.7 127.0.0.1 51234 21\r\n")
+	if text := client.download("EPSV", "RETR ip.txt"); text != "192.0.2.7\n测试网络\n" {
+		t.Errorf("ip.txt 内容错误: %q", text)
+	}
+}
@@ -0,0 +1,142 @@
+package ipInfo
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CSVProvider 基于本地 CSV 地址段文件的查询后端
+//
+// 每行格式为 `起始IP,结束IP,国家,地区,城市,运营商` 或 `CIDR,国家,地区,城市,运营商`，
+// 信息列可以省略，以 `#` 开头的行会被忽略，地址段之间不应重叠
+type CSVProvider struct {
+	name   string
+	ranges []ipRange
+}
+
+type ipRange struct {
+	start netip.Addr
+	end   netip.Addr
+	info  []string
+}
+
+func InitCSV(path string) (*CSVProvider, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewCSV(path, buffer)
+}
+
+// NewCSV 解析已读入内存的文件内容，source 为文件路径，用于生成名称
+func NewCSV(source string, buffer []byte) (*CSVProvider, error) {
+	reader := csv.NewReader(bytes.NewReader(buffer))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &CSVProvider{name: "csv:" + filepath.Base(source)}
+	for i, record := range records {
+		item, err := parseCSVRange(record)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", i+1, err)
+		}
+		provider.ranges = append(provider.ranges, item)
+	}
+	sort.Slice(provider.ranges, func(i, j int) bool {
+		return provider.ranges[i].start.Less(provider.ranges[j].start)
+	})
+	return provider, nil
+}
+
+func parseCSVRange(record []string) (ipRange, error) {
+	if strings.Contains(record[0], "/") {
+		prefix, err := parsePrefix(record[0])
+		if err != nil {
+			return ipRange{}, err
+		}
+		return ipRange{start: prefix.Addr(), end: lastAddr(prefix), info: trimFields(record[1:])}, nil
+	}
+	if len(record) < 2 {
+		return ipRange{}, fmt.Errorf("缺少结束 IP")
+	}
+	start, err := netip.ParseAddr(strings.TrimSpace(record[0]))
+	if err != nil {
+		return ipRange{}, fmt.Errorf("无效的起始 IP: %s", record[0])
+	}
+	end, err := netip.ParseAddr(strings.TrimSpace(record[1]))
+	if err != nil {
+		return ipRange{}, fmt.Errorf("无效的结束 IP: %s", record[1])
+	}
+	start, end = start.Unmap(), end.Unmap()
+	if start.Is4() != end.Is4() || end.Less(start) {
+		return ipRange{}, fmt.Errorf("无效的地址段: %s - %s", record[0], record[1])
+	}
+	return ipRange{start: start, end: end, info: trimFields(record[2:])}, nil
+}
+
+func trimFields(fields []string) []string {
+	result := make([]string, 0, len(fields))
+	for _, field := range fields {
+		result = append(result, strings.TrimSpace(field))
+	}
+	return result
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 0x80 >> (bit % 8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+func (p *CSVProvider) Name() string {
+	return p.name
+}
+
+func (p *CSVProvider) Languages() []string {
+	return nil
+}
+
+func (p *CSVProvider) Find(ip string, languages ...string) (Result, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Result{}, fmt.Errorf("无效的 IP 地址: %s", ip)
+	}
+	addr = addr.Unmap()
+
+	// 找到最后一个起始地址不大于目标地址的地址段
+	i := sort.Search(len(p.ranges), func(i int) bool {
+		return addr.Less(p.ranges[i].start)
+	})
+	if i > 0 {
+		item := p.ranges[i-1]
+		if item.start.Is4() == addr.Is4() && !item.end.Less(addr) {
+			return resultFromInfo(p.name, item.info), nil
+		}
+	}
+	return Result{}, fmt.Errorf("%w: %s", ErrNotFound, ip)
+}
+
+// samples 返回第一个、中间和最后一个地址段的起始地址，用于加载后的校验
+func (p *CSVProvider) samples() []string {
+	addrs := []string{}
+	if len(p.ranges) == 0 {
+		return addrs
+	}
+	for _, i := range []int{0, len(p.ranges) / 2, len(p.ranges) - 1} {
+		addrs = append(addrs, p.ranges[i].start.String())
+	}
+	return addrs
+}
@@ -0,0 +1,83 @@
+package ipInfo_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+func TestInitCSV(t *testing.T) {
+	tempDir := t.TempDir()
+	csvPath := filepath.Join(tempDir, "ranges.csv")
+	content := `# 起始IP,结束IP,信息
+1.0.0.0,1.0.0.255,澳大利亚,
+1.0.1.0, 1.0.3.255 ,中国,福建
+8.8.8.0/24,美国,GOOGLE.COM
+2001:db8::/32,文档地址
+`
+	if err := os.WriteFile(csvPath, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	provider, err := ipInfo.InitCSV(csvPath)
+	if err != nil {
+		t.Fatalf("InitCSV() error = %v", err)
+	}
+	if provider.Name() != "csv:ranges.csv" {
+		t.Errorf("Name() = %v", provider.Name())
+	}
+
+	tests := []struct {
+		ip      string
+		want    []string
+		wantErr bool
+	}{
+		{ip: "1.0.0.1", want: []string{"澳大利亚", ""}},
+		{ip: "1.0.2.200", want: []string{"中国", "福建"}},
+		{ip: "8.8.8.8", want: []string{"美国", "GOOGLE.COM"}},
+		{ip: "2001:db8:ffff::1", want: []string{"文档地址"}},
+		{ip: "1.0.4.0", wantErr: true},
+		{ip: "0.0.0.1", wantErr: true},
+		{ip: "2001:db9::1", wantErr: true},
+		{ip: "invalid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := provider.Find(tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Find() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got.Info, tt.want) {
+				t.Errorf("Find() = %v, want %v", got.Info, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitCSV_Invalid(t *testing.T) {
+	tempDir := t.TempDir()
+	tests := map[string]string{
+		"missing end":   "1.0.0.0\n",
+		"invalid start": "a.b.c.d,1.0.0.255\n",
+		"reverse range": "1.0.0.255,1.0.0.0\n",
+		"mixed family":  "1.0.0.0,::1\n",
+		"invalid cidr":  "1.0.0.0/99,测试\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			csvPath := filepath.Join(tempDir, name+".csv")
+			os.WriteFile(csvPath, []byte(content), 0644)
+			if _, err := ipInfo.InitCSV(csvPath); err == nil {
+				t.Error("InitCSV() 应该返回错误")
+			}
+		})
+	}
+
+	if _, err := ipInfo.InitCSV(filepath.Join(tempDir, "nonexistent.csv")); err == nil {
+		t.Error("文件不存在时应该返回错误")
+	}
+}
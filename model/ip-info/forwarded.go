@@ -0,0 +1,85 @@
+package ipInfo
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// splitQuoted 按分隔符拆分字符串，忽略引号内的分隔符
+func splitQuoted(s string, sep byte) []string {
+	parts := []string{}
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var b strings.Builder
+	escaped := false
+	for i := 1; i < len(s)-1; i++ {
+		if !escaped && s[i] == '\\' {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// forwardedNode 将 RFC 7239 的节点标识转换为 IP 地址，去掉端口和 IPv6 的方括号
+//
+// `unknown` 和以 `_` 开头的混淆标识无法转换为地址，会原样返回
+func forwardedNode(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, found := strings.Cut(node, ":"); found {
+		if _, err := netip.ParseAddr(host); err == nil {
+			return host
+		}
+	}
+	return node
+}
+
+// ParseForwarded 解析 RFC 7239 的 Forwarded 请求头，按代理顺序返回每一跳的 `for` 节点
+//
+// 多个请求头按出现顺序合并，缺少 `for` 参数的跳返回 `unknown`
+func ParseForwarded(values []string) []string {
+	nodes := []string{}
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			if strings.TrimSpace(element) == "" {
+				continue
+			}
+			node := "unknown"
+			for _, pair := range splitQuoted(element, ';') {
+				key, value, found := strings.Cut(pair, "=")
+				if found && strings.EqualFold(strings.TrimSpace(key), "for") {
+					node = forwardedNode(unquote(value))
+				}
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
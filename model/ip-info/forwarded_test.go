@@ -0,0 +1,34 @@
+package ipInfo_test
+
+import (
+	"reflect"
+	"testing"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+func TestParseForwarded(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{"Single IPv4", []string{"for=192.0.2.60;proto=http;by=203.0.113.43"}, []string{"192.0.2.60"}},
+		{"Case insensitive key", []string{"For=192.0.2.60"}, []string{"192.0.2.60"}},
+		{"Quoted IPv6 with port", []string{`for="[2001:db8:cafe::17]:4711"`}, []string{"2001:db8:cafe::17"}},
+		{"IPv4 with port", []string{`for="192.0.2.43:47011"`}, []string{"192.0.2.43"}},
+		{"Obfuscated identifiers", []string{"for=_hidden, for=unknown, for=_SEVKISEK"}, []string{"_hidden", "unknown", "_SEVKISEK"}},
+		{"Missing for parameter", []string{"proto=https;by=203.0.113.43"}, []string{"unknown"}},
+		{"Quoted separators", []string{`for="_a,b;c", for=198.51.100.17`}, []string{"_a,b;c", "198.51.100.17"}},
+		{"Multiple header lines", []string{"for=192.0.2.43", "for=198.51.100.17"}, []string{"192.0.2.43", "198.51.100.17"}},
+		{"Empty elements", []string{" , for=192.0.2.43,"}, []string{"192.0.2.43"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ipInfo.ParseForwarded(tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseForwarded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
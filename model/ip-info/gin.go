@@ -7,39 +7,60 @@ import (
 	"github.com/soulteary/ip-helper/model/fn"
 )
 
+// DEFAULT_CLIENT_IP_HEADERS 默认按顺序读取的客户端 IP 请求头
+//
+// 常见的反向代理（例如 nginx）只会设置这两个请求头，并原样转发客户端自带的 Forwarded，
+// 所以 Forwarded 和 CDN 的请求头需要在确认代理会覆盖它们后再手动开启
+var DEFAULT_CLIENT_IP_HEADERS = []string{"X-Forwarded-For", "X-Real-IP"}
+
 type Info struct {
 	ClientIP     string `json:"client_ip"`
 	ProxyIP      string `json:"proxy_ip,omitempty"`
 	IsProxy      bool   `json:"is_proxy"`
 	ForwardedFor string `json:"forwarded_for,omitempty"`
+	Forwarded    string `json:"forwarded,omitempty"`
 	RealIP       string `json:"real_ip"`
+	// RealIPHeader 真实 IP 的来源请求头，直接连接时为空
+	RealIPHeader string `json:"real_ip_header,omitempty"`
 }
 
-func AnalyzeRequestData(c *gin.Context) Info {
+// AnalyzeRequestData 分析请求的来源地址
+//
+// 只有直接连接的地址属于受信任的代理时，才会按 headers 的顺序读取请求头，使用第一个存在的请求头，
+// 这个请求头无法解析出地址时保留直接连接的地址，不会继续读取优先级更低的请求头。
+// Forwarded 按 RFC 7239 解析，其他请求头按逗号分隔的地址列表处理，
+// 列表都会从右向左跳过受信任的代理。
+func AnalyzeRequestData(c *gin.Context, trusted *TrustedProxies, headers []string) Info {
 	var ipInfo Info
 
 	ipInfo.ClientIP = c.ClientIP()
+	remoteIP := c.RemoteIP()
+	ipInfo.RealIP = remoteIP
+	ipInfo.ForwardedFor = c.GetHeader("X-Forwarded-For")
+	ipInfo.Forwarded = strings.Join(c.Request.Header.Values("Forwarded"), ", ")
+
+	if trusted.Contains(remoteIP) {
+		for _, header := range headers {
+			header = strings.TrimSpace(header)
+			values := c.Request.Header.Values(header)
+			if len(values) == 0 {
+				continue
+			}
 
-	forwardedFor := c.GetHeader("X-Forwarded-For")
-	if forwardedFor != "" {
-		ipInfo.ForwardedFor = forwardedFor
-		ips := strings.Split(forwardedFor, ",")
-		if len(ips) > 0 {
-			ipInfo.RealIP = strings.TrimSpace(ips[0])
-			if len(ips) > 1 {
+			var hops []string
+			if strings.EqualFold(header, "Forwarded") {
+				hops = ParseForwarded(values)
+			} else {
+				hops = strings.Split(strings.Join(values, ","), ",")
+			}
+			if realIP, found := trusted.ResolveForwardedFor(hops); found && realIP != remoteIP {
 				ipInfo.IsProxy = true
-				ipInfo.ProxyIP = strings.TrimSpace(ips[len(ips)-1])
+				ipInfo.ProxyIP = remoteIP
+				ipInfo.RealIP = realIP
+				ipInfo.RealIPHeader = header
 			}
+			break
 		}
-	} else {
-		ipInfo.RealIP = ipInfo.ClientIP
-	}
-
-	xRealIP := c.GetHeader("X-Real-IP")
-	if xRealIP != "" && xRealIP != ipInfo.RealIP {
-		ipInfo.IsProxy = true
-		ipInfo.ProxyIP = ipInfo.ClientIP
-		ipInfo.RealIP = xRealIP
 	}
 
 	if fn.IsPrivateIP(ipInfo.ClientIP) {
@@ -12,6 +12,8 @@ func TestAnalyzeRequestData(t *testing.T) {
 	tests := []struct {
 		name       string
 		remoteAddr string
+		trusted    []string
+		ipHeaders  []string
 		headers    map[string]string
 		want       ipInfo.Info
 	}{
@@ -28,6 +30,7 @@ func TestAnalyzeRequestData(t *testing.T) {
 		{
 			name:       "Connection with X-Forwarded-For",
 			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.4"},
 			headers: map[string]string{
 				"X-Forwarded-For": "5.6.7.8, 1.2.3.4",
 			},
@@ -37,19 +40,22 @@ func TestAnalyzeRequestData(t *testing.T) {
 				RealIP:       "5.6.7.8",
 				IsProxy:      true,
 				ForwardedFor: "5.6.7.8, 1.2.3.4",
+				RealIPHeader: "X-Forwarded-For",
 			},
 		},
 		{
 			name:       "Connection with X-Real-IP",
 			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.0/24"},
 			headers: map[string]string{
 				"X-Real-IP": "5.6.7.8",
 			},
 			want: ipInfo.Info{
-				ClientIP: "5.6.7.8",
-				ProxyIP:  "",
-				RealIP:   "5.6.7.8",
-				IsProxy:  false,
+				ClientIP:     "5.6.7.8",
+				ProxyIP:      "1.2.3.4",
+				RealIP:       "5.6.7.8",
+				IsProxy:      true,
+				RealIPHeader: "X-Real-IP",
 			},
 		},
 		{
@@ -63,8 +69,25 @@ func TestAnalyzeRequestData(t *testing.T) {
 			},
 		},
 		{
-			name:       "Connection with multiple X-Forwarded-For IPs",
+			name:       "Stops at the first untrusted hop",
 			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.4"},
+			headers: map[string]string{
+				"X-Forwarded-For": "5.6.7.8, 9.10.11.12, 1.2.3.4",
+			},
+			want: ipInfo.Info{
+				ClientIP:     "9.10.11.12",
+				ProxyIP:      "1.2.3.4",
+				RealIP:       "9.10.11.12",
+				IsProxy:      true,
+				ForwardedFor: "5.6.7.8, 9.10.11.12, 1.2.3.4",
+				RealIPHeader: "X-Forwarded-For",
+			},
+		},
+		{
+			name:       "Connection with multiple trusted X-Forwarded-For IPs",
+			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.4", "9.10.11.0/24"},
 			headers: map[string]string{
 				"X-Forwarded-For": "5.6.7.8, 9.10.11.12, 1.2.3.4",
 			},
@@ -74,21 +97,145 @@ func TestAnalyzeRequestData(t *testing.T) {
 				RealIP:       "5.6.7.8",
 				IsProxy:      true,
 				ForwardedFor: "5.6.7.8, 9.10.11.12, 1.2.3.4",
+				RealIPHeader: "X-Forwarded-For",
 			},
 		},
 		{
-			name:       "Connection with both X-Forwarded-For and different X-Real-IP",
+			name:       "X-Forwarded-For takes precedence over X-Real-IP",
 			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.4"},
 			headers: map[string]string{
 				"X-Forwarded-For": "5.6.7.8, 1.2.3.4",
 				"X-Real-IP":       "9.10.11.12",
 			},
 			want: ipInfo.Info{
 				ClientIP:     "5.6.7.8",
-				ProxyIP:      "5.6.7.8",    // ClientIP 被设置为代理IP
-				RealIP:       "9.10.11.12", // X-Real-IP 的值覆盖了之前的 RealIP
+				ProxyIP:      "1.2.3.4",
+				RealIP:       "5.6.7.8",
 				IsProxy:      true,
 				ForwardedFor: "5.6.7.8, 1.2.3.4",
+				RealIPHeader: "X-Forwarded-For",
+			},
+		},
+		{
+			name:       "Spoofed headers from untrusted client",
+			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"127.0.0.1"},
+			headers: map[string]string{
+				"X-Forwarded-For": "5.6.7.8",
+				"X-Real-IP":       "9.10.11.12",
+			},
+			want: ipInfo.Info{
+				ClientIP:     "1.2.3.4",
+				RealIP:       "1.2.3.4",
+				IsProxy:      false,
+				ForwardedFor: "5.6.7.8",
+			},
+		},
+		{
+			name:       "Invalid X-Forwarded-For keeps the remote address",
+			remoteAddr: "[::1]:1234",
+			trusted:    []string{"::1"},
+			headers: map[string]string{
+				"X-Forwarded-For": "5.6.7.8, unknown",
+				"X-Real-IP":       "2001:db8::1",
+			},
+			want: ipInfo.Info{
+				RealIP:       "::1",
+				ForwardedFor: "5.6.7.8, unknown",
+			},
+		},
+		{
+			name:       "RFC 7239 Forwarded header",
+			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.4", "198.51.100.17"},
+			ipHeaders:  []string{"Forwarded", "X-Forwarded-For"},
+			headers: map[string]string{
+				"Forwarded": `for="[2001:db8:cafe::17]:4711";proto=https, for=198.51.100.17:8080;by=1.2.3.4`,
+			},
+			want: ipInfo.Info{
+				ProxyIP:      "1.2.3.4",
+				RealIP:       "2001:db8:cafe::17",
+				IsProxy:      true,
+				Forwarded:    `for="[2001:db8:cafe::17]:4711";proto=https, for=198.51.100.17:8080;by=1.2.3.4`,
+				RealIPHeader: "Forwarded",
+			},
+		},
+		{
+			name:       "Obfuscated Forwarded node keeps the remote address",
+			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.4"},
+			ipHeaders:  []string{"Forwarded", "X-Forwarded-For"},
+			headers: map[string]string{
+				"Forwarded":       "for=_hidden, for=1.2.3.4",
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			want: ipInfo.Info{
+				RealIP:       "1.2.3.4",
+				ForwardedFor: "5.6.7.8",
+				Forwarded:    "for=_hidden, for=1.2.3.4",
+			},
+		},
+		{
+			name:       "Unknown Forwarded node keeps the remote address",
+			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.4"},
+			ipHeaders:  []string{"Forwarded", "X-Real-IP"},
+			headers: map[string]string{
+				"Forwarded": "for=unknown",
+				"X-Real-IP": "5.6.7.8",
+			},
+			want: ipInfo.Info{
+				RealIP:    "1.2.3.4",
+				Forwarded: "for=unknown",
+			},
+		},
+		{
+			name:       "Forwarded is ignored by default",
+			remoteAddr: "127.0.0.1:1234",
+			trusted:    []string{"127.0.0.0/8"},
+			headers: map[string]string{
+				"Forwarded":       "for=6.6.6.6",
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			want: ipInfo.Info{
+				ClientIP:     "5.6.7.8",
+				ProxyIP:      "127.0.0.1",
+				RealIP:       "5.6.7.8",
+				IsProxy:      true,
+				ForwardedFor: "5.6.7.8",
+				Forwarded:    "for=6.6.6.6",
+				RealIPHeader: "X-Forwarded-For",
+			},
+		},
+		{
+			name:       "CDN header in configured order",
+			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.4"},
+			ipHeaders:  []string{"CF-Connecting-IP", "True-Client-IP", "X-Forwarded-For"},
+			headers: map[string]string{
+				"True-Client-IP":  "9.10.11.12",
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			want: ipInfo.Info{
+				ClientIP:     "9.10.11.12",
+				ProxyIP:      "1.2.3.4",
+				RealIP:       "9.10.11.12",
+				IsProxy:      true,
+				ForwardedFor: "5.6.7.8",
+				RealIPHeader: "True-Client-IP",
+			},
+		},
+		{
+			name:       "Headers not in the configured list are ignored",
+			remoteAddr: "1.2.3.4:1234",
+			trusted:    []string{"1.2.3.4"},
+			headers: map[string]string{
+				"CF-Connecting-IP": "9.10.11.12",
+			},
+			want: ipInfo.Info{
+				ClientIP: "1.2.3.4",
+				RealIP:   "1.2.3.4",
 			},
 		},
 	}
@@ -97,15 +244,23 @@ func TestAnalyzeRequestData(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// 创建一个带有信任代理设置的路由器
+			trusted, err := ipInfo.ParseTrustedProxies(tt.trusted)
+			if err != nil {
+				t.Fatalf("ParseTrustedProxies() error = %v", err)
+			}
+
+			// 创建一个与 AnalyzeRequestData 使用相同信任代理设置的路由器
+			ipHeaders := tt.ipHeaders
+			if ipHeaders == nil {
+				ipHeaders = ipInfo.DEFAULT_CLIENT_IP_HEADERS
+			}
 			router := gin.New()
-			router.SetTrustedProxies([]string{"0.0.0.0/0", "::/0"})
+			router.SetTrustedProxies(trusted.CIDRs())
+			router.RemoteIPHeaders = ipHeaders
 
-			// 添加测试路由
+			var got ipInfo.Info
 			router.GET("/test", func(c *gin.Context) {
-				result := ipInfo.AnalyzeRequestData(c)
-				// 将结果存储在context中以便后续检查
-				c.Set("result", result)
+				got = ipInfo.AnalyzeRequestData(c, trusted, ipHeaders)
 			})
 
 			// 创建请求
@@ -117,21 +272,12 @@ func TestAnalyzeRequestData(t *testing.T) {
 				req.Header.Set(key, value)
 			}
 
-			// 创建响应记录器
-			w := httptest.NewRecorder()
-
 			// 执行请求
-			router.ServeHTTP(w, req)
-
-			// 创建新的context来获取结果
-			c, _ := gin.CreateTestContext(w)
-			c.Request = req
-
-			// 直接调用被测试的函数
-			got := ipInfo.AnalyzeRequestData(c)
+			router.ServeHTTP(httptest.NewRecorder(), req)
 
 			// 验证结果
-			if got.ClientIP != tt.want.ClientIP {
+			// gin 不解析 Forwarded 请求头，只在指定时校验 ClientIP
+			if tt.want.ClientIP != "" && got.ClientIP != tt.want.ClientIP {
 				t.Errorf("ClientIP = %v, want %v", got.ClientIP, tt.want.ClientIP)
 			}
 			if got.ProxyIP != tt.want.ProxyIP {
@@ -146,6 +292,40 @@ func TestAnalyzeRequestData(t *testing.T) {
 			if got.ForwardedFor != tt.want.ForwardedFor {
 				t.Errorf("ForwardedFor = %v, want %v", got.ForwardedFor, tt.want.ForwardedFor)
 			}
+			if got.Forwarded != tt.want.Forwarded {
+				t.Errorf("Forwarded = %v, want %v", got.Forwarded, tt.want.Forwarded)
+			}
+			if got.RealIPHeader != tt.want.RealIPHeader {
+				t.Errorf("RealIPHeader = %v, want %v", got.RealIPHeader, tt.want.RealIPHeader)
+			}
 		})
 	}
 }
+
+func TestParseTrustedProxies(t *testing.T) {
+	trusted, err := ipInfo.ParseTrustedProxies([]string{"10.0.0.0/8", " 192.168.1.1 ", "", "::ffff:172.16.0.1"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	for ip, want := range map[string]bool{
+		"10.1.2.3":        true,
+		"::ffff:10.1.2.3": true,
+		"192.168.1.1":     true,
+		"192.168.1.2":     false,
+		"172.16.0.1":      true,
+		"invalid":         false,
+	} {
+		if got := trusted.Contains(ip); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", ip, got, want)
+		}
+	}
+
+	if _, err := ipInfo.ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("ParseTrustedProxies() should reject invalid CIDR")
+	}
+
+	var none *ipInfo.TrustedProxies
+	if none.Contains("127.0.0.1") || len(none.CIDRs()) != 0 {
+		t.Error("nil TrustedProxies should not trust any address")
+	}
+}
@@ -1,6 +1,8 @@
 package ipInfo
 
 import (
+	"time"
+
 	"github.com/soulteary/ipdb-go"
 )
 
@@ -15,3 +17,53 @@ func InitIPDB(ipipDB string) (IPDB, error) {
 	}
 	return IPDB{IPIP: ipip}, nil
 }
+
+// NewIPDB 从已读入内存的文件内容创建数据库
+func NewIPDB(buffer []byte) (IPDB, error) {
+	ipip, err := ipdb.NewCityFromBytes(buffer)
+	if err != nil {
+		return IPDB{}, err
+	}
+	return IPDB{IPIP: ipip}, nil
+}
+
+func (db IPDB) Name() string {
+	return "ipip"
+}
+
+func (db IPDB) BuildTime() time.Time {
+	return db.IPIP.BuildTime()
+}
+
+func (db IPDB) Languages() []string {
+	return db.IPIP.Languages()
+}
+
+func (db IPDB) Find(ip string, languages ...string) (Result, error) {
+	language := negotiateLanguage(db.Languages(), languages, "CN")
+	record, err := db.IPIP.FindMap(ip, language)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// 按数据库字段顺序保留原始信息
+	info := []string{}
+	for _, field := range db.IPIP.Fields() {
+		info = append(info, record[field])
+	}
+
+	return Result{
+		Country:     record["country_name"],
+		CountryCode: record["country_code"],
+		Region:      record["region_name"],
+		City:        record["city_name"],
+		ISP:         record["isp_domain"],
+		Owner:       record["owner_domain"],
+		Timezone:    record["timezone"],
+		Latitude:    parseCoordinate(record["latitude"]),
+		Longitude:   parseCoordinate(record["longitude"]),
+		Source:      db.Name(),
+		Language:    language,
+		Info:        info,
+	}, nil
+}
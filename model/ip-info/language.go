@@ -0,0 +1,63 @@
+package ipInfo
+
+import (
+	"strings"
+)
+
+// 数据库中使用的非标准语言代码
+var languageAliases = map[string]string{
+	"cn":      "zh-cn",
+	"zh-hans": "zh-cn",
+	"zh-hant": "zh-tw",
+}
+
+func canonicalLanguage(language string) string {
+	language = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+	if alias, ok := languageAliases[language]; ok {
+		return alias
+	}
+	return language
+}
+
+func primaryLanguage(language string) string {
+	primary, _, _ := strings.Cut(language, "-")
+	return primary
+}
+
+// MatchLanguage 从数据库支持的语言中选出最符合偏好的一个，没有匹配时返回空字符串
+func MatchLanguage(supported []string, preferred []string) string {
+	for _, want := range preferred {
+		want = canonicalLanguage(want)
+		if want == "" {
+			continue
+		}
+		for _, language := range supported {
+			if canonicalLanguage(language) == want {
+				return language
+			}
+		}
+		for _, language := range supported {
+			if primaryLanguage(canonicalLanguage(language)) == primaryLanguage(want) {
+				return language
+			}
+		}
+	}
+	return ""
+}
+
+// negotiateLanguage 协商查询语言，偏好语言都不支持时回退到默认语言或数据库中的第一个语言
+func negotiateLanguage(supported []string, preferred []string, defaults ...string) string {
+	if language := MatchLanguage(supported, preferred); language != "" {
+		return language
+	}
+	if language := MatchLanguage(supported, defaults); language != "" {
+		return language
+	}
+	if len(supported) > 0 {
+		return supported[0]
+	}
+	if len(defaults) > 0 {
+		return defaults[0]
+	}
+	return ""
+}
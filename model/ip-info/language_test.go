@@ -0,0 +1,35 @@
+package ipInfo_test
+
+import (
+	"testing"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		name      string
+		supported []string
+		preferred []string
+		want      string
+	}{
+		{"IPIP chinese", []string{"CN", "EN"}, []string{"zh-CN", "en"}, "CN"},
+		{"IPIP chinese by primary tag", []string{"CN", "EN"}, []string{"zh"}, "CN"},
+		{"IPIP english", []string{"CN", "EN"}, []string{"en-US", "zh"}, "EN"},
+		{"Exact match first", []string{"zh-TW", "zh-CN"}, []string{"zh-CN"}, "zh-CN"},
+		{"Underscore tag", []string{"en", "pt-BR"}, []string{"pt_br"}, "pt-BR"},
+		{"Script subtag", []string{"en", "zh-CN"}, []string{"zh-Hans"}, "zh-CN"},
+		{"Skip unsupported", []string{"CN", "EN"}, []string{"fr", "en"}, "EN"},
+		{"No match", []string{"CN"}, []string{"ja"}, ""},
+		{"No preference", []string{"CN"}, nil, ""},
+		{"Unsupported database", nil, []string{"en"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ipInfo.MatchLanguage(tt.supported, tt.preferred); got != tt.want {
+				t.Errorf("MatchLanguage(%v, %v) = %v, want %v", tt.supported, tt.preferred, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package ipInfo
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// MemoryProvider 基于内存数据的查询后端，便于测试和少量自定义数据
+type MemoryProvider struct {
+	name    string
+	records map[netip.Prefix][]string
+}
+
+// NewMemoryProvider 使用 IP 或 CIDR 作为键创建内存查询后端，值按 `国家,地区,城市,运营商` 排列
+func NewMemoryProvider(name string, records map[string][]string) (*MemoryProvider, error) {
+	provider := &MemoryProvider{name: name, records: map[netip.Prefix][]string{}}
+	for key, info := range records {
+		prefix, err := parsePrefix(key)
+		if err != nil {
+			return nil, err
+		}
+		provider.records[prefix] = info
+	}
+	return provider, nil
+}
+
+func (p *MemoryProvider) Name() string {
+	return p.name
+}
+
+func (p *MemoryProvider) Languages() []string {
+	return nil
+}
+
+func (p *MemoryProvider) Find(ip string, languages ...string) (Result, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Result{}, fmt.Errorf("无效的 IP 地址: %s", ip)
+	}
+	addr = addr.Unmap()
+
+	var matched []string
+	bits := -1
+	for prefix, info := range p.records {
+		if prefix.Contains(addr) && prefix.Bits() > bits {
+			matched, bits = info, prefix.Bits()
+		}
+	}
+	if bits < 0 {
+		return Result{}, fmt.Errorf("%w: %s", ErrNotFound, ip)
+	}
+	return resultFromInfo(p.name, matched), nil
+}
+
+// samples 返回每个地址段的起始地址，用于加载后的校验
+func (p *MemoryProvider) samples() []string {
+	addrs := make([]string, 0, len(p.records))
+	for prefix := range p.records {
+		addrs = append(addrs, prefix.Addr().String())
+	}
+	return addrs
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("无效的 CIDR: %s", s)
+		}
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("无效的 IP 地址: %s", s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
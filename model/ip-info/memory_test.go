@@ -0,0 +1,52 @@
+package ipInfo_test
+
+import (
+	"reflect"
+	"testing"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+func TestMemoryProvider(t *testing.T) {
+	provider, err := ipInfo.NewMemoryProvider("memory", map[string][]string{
+		"10.0.0.0/8":     {"局域网"},
+		"10.1.0.0/16":    {"办公网"},
+		"192.168.1.1":    {"网关"},
+		"2001:db8::/32":  {"文档地址"},
+		"::ffff:1.2.3.4": {"映射地址"},
+	})
+	if err != nil {
+		t.Fatalf("NewMemoryProvider() error = %v", err)
+	}
+
+	tests := []struct {
+		ip      string
+		want    []string
+		wantErr bool
+	}{
+		{ip: "10.2.3.4", want: []string{"局域网"}},
+		{ip: "10.1.2.3", want: []string{"办公网"}},
+		{ip: "192.168.1.1", want: []string{"网关"}},
+		{ip: "2001:db8::1", want: []string{"文档地址"}},
+		{ip: "1.2.3.4", want: []string{"映射地址"}},
+		{ip: "::ffff:10.1.2.3", want: []string{"办公网"}},
+		{ip: "192.168.1.2", wantErr: true},
+		{ip: "invalid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := provider.Find(tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Find() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got.Info, tt.want) {
+				t.Errorf("Find() = %v, want %v", got.Info, tt.want)
+			}
+		})
+	}
+
+	if _, err := ipInfo.NewMemoryProvider("memory", map[string][]string{"not-an-ip": {}}); err == nil {
+		t.Error("无效的键应该返回错误")
+	}
+}
@@ -0,0 +1,414 @@
+package ipInfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"time"
+)
+
+// MMDB 读取 MaxMind DB 格式（.mmdb）数据库的查询后端
+//
+// 仅实现了查询所需的只读解码，数据结构兼容 GeoIP2 / GeoLite2 City 与 Country 数据库
+type MMDB struct {
+	buffer       []byte
+	data         []byte
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	ipv4Start    uint
+	DatabaseType string
+	BuildEpoch   uint64
+	languages    []string
+}
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+const mmdbDataSectionSeparator = 16
+
+func InitMMDB(path string) (*MMDB, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMMDB(buffer)
+}
+
+func NewMMDB(buffer []byte) (*MMDB, error) {
+	start := bytes.LastIndex(buffer, mmdbMetadataMarker)
+	if start == -1 {
+		return nil, fmt.Errorf("无效的 MMDB 文件: 未找到元数据")
+	}
+	metaDecoder := mmdbDecoder{buffer: buffer[start+len(mmdbMetadataMarker):]}
+	value, _, err := metaDecoder.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 MMDB 元数据: %v", err)
+	}
+	metadata, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("无效的 MMDB 元数据")
+	}
+
+	db := &MMDB{buffer: buffer}
+	db.nodeCount = uint(mmdbUint(metadata["node_count"]))
+	db.recordSize = uint(mmdbUint(metadata["record_size"]))
+	db.ipVersion = uint(mmdbUint(metadata["ip_version"]))
+	db.BuildEpoch = mmdbUint(metadata["build_epoch"])
+	db.DatabaseType, _ = metadata["database_type"].(string)
+	if languages, ok := metadata["languages"].([]any); ok {
+		for _, language := range languages {
+			if s, ok := language.(string); ok {
+				db.languages = append(db.languages, s)
+			}
+		}
+	}
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fmt.Errorf("不支持的 MMDB 记录长度: %d", db.recordSize)
+	}
+
+	treeSize := db.nodeCount * db.recordSize / 4
+	dataStart := treeSize + mmdbDataSectionSeparator
+	if dataStart > uint(start) {
+		return nil, fmt.Errorf("无效的 MMDB 文件: 数据区越界")
+	}
+	db.data = buffer[dataStart:start]
+
+	// IPv6 数据库中 IPv4 地址位于 ::/96 下
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node, err = db.readNode(node, 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+func (db *MMDB) Name() string {
+	if db.DatabaseType != "" {
+		return "mmdb:" + db.DatabaseType
+	}
+	return "mmdb"
+}
+
+func (db *MMDB) BuildTime() time.Time {
+	return time.Unix(int64(db.BuildEpoch), 0)
+}
+
+func (db *MMDB) Languages() []string {
+	return db.languages
+}
+
+func (db *MMDB) Find(ip string, languages ...string) (Result, error) {
+	record, err := db.Lookup(ip)
+	if err != nil {
+		return Result{}, err
+	}
+
+	language := negotiateLanguage(db.languages, languages, "zh-CN", "en")
+	result := Result{
+		Country:     mmdbName(record["country"], language),
+		CountryCode: mmdbString(record["country"], "iso_code"),
+		Region:      mmdbName(mmdbFirst(record["subdivisions"]), language),
+		City:        mmdbName(record["city"], language),
+		ISP:         mmdbString(record, "isp"),
+		Owner:       mmdbString(record, "autonomous_system_organization"),
+		Timezone:    mmdbString(record["location"], "time_zone"),
+		Source:      db.Name(),
+		Language:    language,
+	}
+	if location, ok := record["location"].(map[string]any); ok {
+		if latitude, ok := location["latitude"].(float64); ok {
+			result.Latitude = &latitude
+		}
+		if longitude, ok := location["longitude"].(float64); ok {
+			result.Longitude = &longitude
+		}
+	}
+	result.Info = []string{result.Country, result.Region, result.City}
+	return result, nil
+}
+
+// Lookup 返回 IP 对应的原始记录
+func (db *MMDB) Lookup(ip string) (map[string]any, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 IP 地址: %s", ip)
+	}
+	addr = addr.Unmap()
+	if addr.Is6() && db.ipVersion == 4 {
+		return nil, fmt.Errorf("IPv4 数据库不支持查询 IPv6 地址: %s", ip)
+	}
+
+	raw := addr.AsSlice()
+	node := uint(0)
+	if addr.Is4() && db.ipVersion == 6 {
+		node = db.ipv4Start
+	}
+	for i := 0; i < len(raw)*8 && node < db.nodeCount; i++ {
+		bit := uint(raw[i/8]>>(7-uint(i%8))) & 1
+		node, err = db.readNode(node, bit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if node <= db.nodeCount {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, ip)
+	}
+
+	offset := node - db.nodeCount - mmdbDataSectionSeparator
+	decoder := mmdbDecoder{buffer: db.data}
+	value, _, err := decoder.decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("无效的 MMDB 记录")
+	}
+	return record, nil
+}
+
+func (db *MMDB) readNode(node uint, bit uint) (uint, error) {
+	base := node * db.recordSize / 4
+	if base+db.recordSize/4 > uint(len(db.buffer)) {
+		return 0, fmt.Errorf("无效的 MMDB 文件: 节点越界")
+	}
+	b := db.buffer[base:]
+	switch db.recordSize {
+	case 24:
+		off := bit * 3
+		return uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2]), nil
+	case 28:
+		if bit == 0 {
+			return (uint(b[3])&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return (uint(b[3])&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	default:
+		off := bit * 4
+		return uint(binary.BigEndian.Uint32(b[off:])), nil
+	}
+}
+
+func mmdbName(value any, language string) string {
+	record, ok := value.(map[string]any)
+	if !ok {
+		return ""
+	}
+	names, ok := record["names"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	if name, ok := names[language].(string); ok {
+		return name
+	}
+	name, _ := names["en"].(string)
+	return name
+}
+
+func mmdbString(value any, key string) string {
+	record, ok := value.(map[string]any)
+	if !ok {
+		return ""
+	}
+	s, _ := record[key].(string)
+	return s
+}
+
+func mmdbFirst(value any) any {
+	if list, ok := value.([]any); ok && len(list) > 0 {
+		return list[0]
+	}
+	return nil
+}
+
+func mmdbUint(value any) uint64 {
+	switch v := value.(type) {
+	case uint64:
+		return v
+	case int32:
+		return uint64(v)
+	}
+	return 0
+}
+
+type mmdbDecoder struct {
+	buffer []byte
+	depth  int
+}
+
+// 防止构造的数据通过指针形成环引用
+const mmdbMaxDepth = 512
+
+func (d *mmdbDecoder) decode(offset uint) (any, uint, error) {
+	if offset >= uint(len(d.buffer)) {
+		return nil, 0, fmt.Errorf("无效的 MMDB 数据: 偏移越界")
+	}
+	ctrl := d.buffer[offset]
+	offset++
+	kind := uint(ctrl >> 5)
+
+	// 指针
+	if kind == 1 {
+		pointer, next, err := d.decodePointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		if pointer < uint(len(d.buffer)) && d.buffer[pointer]>>5 == 1 {
+			return nil, 0, fmt.Errorf("无效的 MMDB 数据: 指针指向指针")
+		}
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+
+	// 扩展类型
+	if kind == 0 {
+		if offset >= uint(len(d.buffer)) {
+			return nil, 0, fmt.Errorf("无效的 MMDB 数据: 类型越界")
+		}
+		kind = 7 + uint(d.buffer[offset])
+		offset++
+	}
+
+	size, offset, err := d.decodeSize(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch kind {
+	case 7, 11:
+		if d.depth >= mmdbMaxDepth {
+			return nil, 0, fmt.Errorf("无效的 MMDB 数据: 嵌套过深")
+		}
+		d.depth++
+		defer func() { d.depth-- }()
+		if kind == 7 {
+			return d.decodeMap(size, offset)
+		}
+		return d.decodeArray(size, offset)
+	case 14:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buffer)) {
+		return nil, 0, fmt.Errorf("无效的 MMDB 数据: 长度越界")
+	}
+	raw := d.buffer[offset:end]
+	switch kind {
+	case 2:
+		return string(raw), end, nil
+	case 3:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("无效的 MMDB double 长度: %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), end, nil
+	case 4, 10:
+		return append([]byte(nil), raw...), end, nil
+	case 5, 6, 9:
+		var value uint64
+		for _, b := range raw {
+			value = value<<8 | uint64(b)
+		}
+		return value, end, nil
+	case 8:
+		var value uint32
+		for _, b := range raw {
+			value = value<<8 | uint32(b)
+		}
+		return int32(value), end, nil
+	case 15:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("无效的 MMDB float 长度: %d", size)
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(raw)), end, nil
+	}
+	return nil, 0, fmt.Errorf("不支持的 MMDB 数据类型: %d", kind)
+}
+
+func (d *mmdbDecoder) decodePointer(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl>>3)&0x3 + 1
+	if offset+size > uint(len(d.buffer)) {
+		return 0, 0, fmt.Errorf("无效的 MMDB 数据: 指针越界")
+	}
+	raw := d.buffer[offset : offset+size]
+	var prefix uint
+	if size != 4 {
+		prefix = uint(ctrl & 0x7)
+	}
+	value := prefix
+	for _, b := range raw {
+		value = value<<8 | uint(b)
+	}
+	switch size {
+	case 2:
+		value += 2048
+	case 3:
+		value += 526336
+	}
+	return value, offset + size, nil
+}
+
+func (d *mmdbDecoder) decodeSize(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1F)
+	if size < 29 {
+		return size, offset, nil
+	}
+	n := size - 28
+	if offset+n > uint(len(d.buffer)) {
+		return 0, 0, fmt.Errorf("无效的 MMDB 数据: 长度越界")
+	}
+	var value uint
+	for _, b := range d.buffer[offset : offset+n] {
+		value = value<<8 | uint(b)
+	}
+	switch size {
+	case 29:
+		value += 29
+	case 30:
+		value += 285
+	default:
+		value += 65821
+	}
+	return value, offset + n, nil
+}
+
+func (d *mmdbDecoder) decodeMap(size uint, offset uint) (any, uint, error) {
+	result := make(map[string]any, size)
+	for i := uint(0); i < size; i++ {
+		key, next, err := d.decode(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, 0, fmt.Errorf("无效的 MMDB 数据: 键不是字符串")
+		}
+		value, next, err := d.decode(next)
+		if err != nil {
+			return nil, 0, err
+		}
+		result[name] = value
+		offset = next
+	}
+	return result, offset, nil
+}
+
+func (d *mmdbDecoder) decodeArray(size uint, offset uint) (any, uint, error) {
+	result := make([]any, 0, size)
+	for i := uint(0); i < size; i++ {
+		value, next, err := d.decode(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, value)
+		offset = next
+	}
+	return result, offset, nil
+}
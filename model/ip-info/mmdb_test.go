@@ -0,0 +1,194 @@
+package ipInfo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+// 以下辅助函数用于生成最小化的 MMDB 测试数据
+
+func mmdbEncodeString(s string) []byte {
+	return append([]byte{2<<5 | byte(len(s))}, s...)
+}
+
+func mmdbEncodeUint32(v uint32) []byte {
+	return []byte{6<<5 | 4, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+func mmdbEncodeDouble(v float64) []byte {
+	buf := []byte{3<<5 | 8}
+	return binary.BigEndian.AppendUint64(buf, math.Float64bits(v))
+}
+
+func mmdbEncodeArray(items ...[]byte) []byte {
+	// 数组为扩展类型 11
+	buf := []byte{byte(len(items)), 11 - 7}
+	for _, item := range items {
+		buf = append(buf, item...)
+	}
+	return buf
+}
+
+func mmdbEncodeMap(m map[string][]byte) []byte {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	buf := []byte{7<<5 | byte(len(m))}
+	for _, key := range keys {
+		buf = append(buf, mmdbEncodeString(key)...)
+		buf = append(buf, m[key]...)
+	}
+	return buf
+}
+
+func mmdbEncodeNames(names map[string]string) []byte {
+	encoded := map[string][]byte{}
+	for lang, name := range names {
+		encoded[lang] = mmdbEncodeString(name)
+	}
+	return mmdbEncodeMap(map[string][]byte{"names": mmdbEncodeMap(encoded)})
+}
+
+// buildMMDB 生成只包含一个 IPv4 地址段的 24 位记录数据库
+func buildMMDB(prefix [4]byte, bits int, record []byte) []byte {
+	nodeCount := uint32(bits)
+	dataPointer := nodeCount + 16
+	tree := []byte{}
+	for i := 0; i < bits; i++ {
+		next := uint32(i + 1)
+		if i == bits-1 {
+			next = dataPointer
+		}
+		left, right := nodeCount, nodeCount
+		if prefix[i/8]>>(7-uint(i%8))&1 == 0 {
+			left = next
+		} else {
+			right = next
+		}
+		tree = append(tree, byte(left>>16), byte(left>>8), byte(left))
+		tree = append(tree, byte(right>>16), byte(right>>8), byte(right))
+	}
+
+	buf := bytes.NewBuffer(tree)
+	buf.Write(make([]byte, 16))
+	buf.Write(record)
+	buf.WriteString("\xAB\xCD\xEFMaxMind.com")
+	buf.Write(mmdbEncodeMap(map[string][]byte{
+		"node_count":    mmdbEncodeUint32(nodeCount),
+		"record_size":   mmdbEncodeUint32(24),
+		"ip_version":    mmdbEncodeUint32(4),
+		"database_type": mmdbEncodeString("Test-City"),
+		"languages":     mmdbEncodeArray(mmdbEncodeString("en"), mmdbEncodeString("zh-CN")),
+	}))
+	return buf.Bytes()
+}
+
+func TestInitMMDB(t *testing.T) {
+	record := mmdbEncodeMap(map[string][]byte{
+		"country": mmdbEncodeMap(map[string][]byte{
+			"iso_code": mmdbEncodeString("CN"),
+			"names":    mmdbEncodeMap(map[string][]byte{"en": mmdbEncodeString("China"), "zh-CN": mmdbEncodeString("中国")}),
+		}),
+		"subdivisions": mmdbEncodeArray(mmdbEncodeNames(map[string]string{"en": "Beijing"})),
+		"location":     mmdbEncodeMap(map[string][]byte{"time_zone": mmdbEncodeString("Asia/Shanghai"), "latitude": mmdbEncodeDouble(39.9)}),
+	})
+	dbPath := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(dbPath, buildMMDB([4]byte{1, 2, 3, 0}, 24, record), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	db, err := ipInfo.InitMMDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitMMDB() error = %v", err)
+	}
+	if db.Name() != "mmdb:Test-City" {
+		t.Errorf("Name() = %v", db.Name())
+	}
+	if !reflect.DeepEqual(db.Languages(), []string{"en", "zh-CN"}) {
+		t.Errorf("Languages() = %v", db.Languages())
+	}
+
+	result, err := db.Find("1.2.3.4")
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if result.CountryCode != "CN" || result.Timezone != "Asia/Shanghai" || result.Source != "mmdb:Test-City" {
+		t.Errorf("Find() = %+v, 结构化字段不正确", result)
+	}
+	if result.Latitude == nil || *result.Latitude != 39.9 || result.Longitude != nil {
+		t.Errorf("Find() 坐标不正确: %v, %v", result.Latitude, result.Longitude)
+	}
+	if result.Language != "zh-CN" {
+		t.Errorf("Find() 默认语言应该为 zh-CN，实际为 %s", result.Language)
+	}
+
+	languageTests := []struct {
+		languages    []string
+		wantLanguage string
+		wantCountry  string
+		wantRegion   string
+	}{
+		{languages: []string{"en-US"}, wantLanguage: "en", wantCountry: "China", wantRegion: "Beijing"},
+		{languages: []string{"fr", "zh"}, wantLanguage: "zh-CN", wantCountry: "中国", wantRegion: "Beijing"},
+		{languages: []string{"fr"}, wantLanguage: "zh-CN", wantCountry: "中国", wantRegion: "Beijing"},
+	}
+	for _, tt := range languageTests {
+		result, _ := db.Find("1.2.3.4", tt.languages...)
+		if result.Language != tt.wantLanguage || result.Country != tt.wantCountry || result.Region != tt.wantRegion {
+			t.Errorf("Find(%v) = %s %s %s", tt.languages, result.Language, result.Country, result.Region)
+		}
+	}
+
+	tests := []struct {
+		ip      string
+		want    []string
+		wantErr bool
+	}{
+		{ip: "1.2.3.4", want: []string{"中国", "Beijing", ""}},
+		{ip: "1.2.3.255", want: []string{"中国", "Beijing", ""}},
+		{ip: "1.2.4.1", wantErr: true},
+		{ip: "::1", wantErr: true},
+		{ip: "invalid", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := db.Find(tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Find() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got.Info, tt.want) {
+				t.Errorf("Find() = %v, want %v", got.Info, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitMMDB_Invalid(t *testing.T) {
+	if _, err := ipInfo.NewMMDB([]byte("not a mmdb file")); err == nil {
+		t.Error("缺少元数据时应该返回错误")
+	}
+	if _, err := ipInfo.InitMMDB(filepath.Join(t.TempDir(), "nonexistent.mmdb")); err == nil {
+		t.Error("文件不存在时应该返回错误")
+	}
+
+	// 节点数量超出文件大小
+	buf := []byte("\xAB\xCD\xEFMaxMind.com")
+	buf = append(buf, mmdbEncodeMap(map[string][]byte{
+		"node_count":  mmdbEncodeUint32(1000),
+		"record_size": mmdbEncodeUint32(24),
+		"ip_version":  mmdbEncodeUint32(4),
+	})...)
+	if _, err := ipInfo.NewMMDB(buf); err == nil {
+		t.Error("数据区越界时应该返回错误")
+	}
+}
@@ -0,0 +1,178 @@
+package ipInfo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/soulteary/ip-helper/model/fn"
+)
+
+// Provider IP 信息查询后端
+//
+// Find 的 languages 为按偏好排列的语言列表，后端不支持时使用默认语言
+type Provider interface {
+	Name() string
+	Languages() []string
+	Find(ip string, languages ...string) (Result, error)
+}
+
+// ErrNotFound 数据库中没有对应的地址段，查询后端应该包装这个错误，以便与数据损坏等错误区分
+var ErrNotFound = errors.New("未找到 IP 地址信息")
+
+// ProviderFactory 根据数据源（通常是文件路径）创建查询后端
+type ProviderFactory func(source string) (Provider, error)
+
+var providerFactories = map[string]ProviderFactory{
+	"ipip": func(source string) (Provider, error) {
+		db, err := InitIPDB(source)
+		if err != nil {
+			return nil, err
+		}
+		return &db, nil
+	},
+	"mmdb": func(source string) (Provider, error) {
+		return InitMMDB(source)
+	},
+	"csv": func(source string) (Provider, error) {
+		return InitCSV(source)
+	},
+}
+
+// fileLoader 根据已读入内存的文件内容创建查询后端，source 为文件路径
+type fileLoader func(source string, buffer []byte) (Provider, error)
+
+// fileLoaders 内置的文件数据库，热更新时只读取一次文件，版本号和数据来自同一份内容
+var fileLoaders = map[string]fileLoader{
+	"ipip": func(source string, buffer []byte) (Provider, error) {
+		db, err := NewIPDB(buffer)
+		if err != nil {
+			return nil, err
+		}
+		return &db, nil
+	},
+	"mmdb": func(source string, buffer []byte) (Provider, error) {
+		return NewMMDB(buffer)
+	},
+	"csv": func(source string, buffer []byte) (Provider, error) {
+		return NewCSV(source, buffer)
+	},
+}
+
+// RegisterProvider 注册查询后端类型，同名类型会被覆盖
+func RegisterProvider(kind string, factory ProviderFactory) {
+	providerFactories[strings.ToLower(kind)] = factory
+	delete(fileLoaders, strings.ToLower(kind))
+}
+
+// ProviderKinds 返回已注册的查询后端类型
+func ProviderKinds() []string {
+	kinds := make([]string, 0, len(providerFactories))
+	for kind := range providerFactories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewProvider 按类型创建查询后端
+func NewProvider(kind string, source string) (Provider, error) {
+	factory, ok := providerFactories[strings.ToLower(kind)]
+	if !ok {
+		return nil, fmt.Errorf("未知的 IP 数据库类型: %s", kind)
+	}
+	provider, err := factory(source)
+	if err != nil {
+		return nil, fmt.Errorf("加载 %s 数据库 %s 失败: %v", kind, source, err)
+	}
+	return provider, nil
+}
+
+// LoadProviders 解析形如 `类型:路径` 的配置，按顺序（即优先级）组装查询链
+//
+// 数据源是普通文件时支持热更新，其他数据源（例如自定义类型使用的地址）直接交给注册的查询后端创建
+func LoadProviders(specs []string) (*Chain, error) {
+	providers := []Provider{}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		kind, source, found := strings.Cut(spec, ":")
+		if !found {
+			return nil, fmt.Errorf("IP 数据库配置格式错误，应为 `类型:路径`: %s", spec)
+		}
+		if stat, err := os.Stat(source); err != nil || !stat.Mode().IsRegular() {
+			provider, err := NewProvider(kind, source)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("IP 数据库已加载: %s (%s)\n", provider.Name(), source)
+			providers = append(providers, provider)
+			continue
+		}
+		provider, err := NewReloadableProvider(kind, source)
+		if err != nil {
+			return nil, err
+		}
+		logDatabase("IP 数据库已加载", provider.Database())
+		providers = append(providers, provider)
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("没有可用的 IP 数据库")
+	}
+	return NewChain(providers...), nil
+}
+
+// Chain 按优先级依次查询多个后端，前一个查询失败或无结果时回退到下一个
+type Chain struct {
+	providers []Provider
+}
+
+func NewChain(providers ...Provider) *Chain {
+	return &Chain{providers: providers}
+}
+
+func (c *Chain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *Chain) Providers() []Provider {
+	return c.providers
+}
+
+func (c *Chain) Languages() []string {
+	languages := []string{}
+	for _, provider := range c.providers {
+		languages = append(languages, provider.Languages()...)
+	}
+	return fn.RemoveDuplicates(languages)
+}
+
+func (c *Chain) Find(ip string, languages ...string) (Result, error) {
+	result, err := Result{}, fmt.Errorf("没有可用的 IP 数据库")
+	for _, provider := range c.providers {
+		result, err = provider.Find(ip, languages...)
+		if err == nil && len(result.Info) > 0 {
+			return result, nil
+		}
+	}
+	return result, err
+}
+
+// Lookup 查询 IP 信息，查询失败时返回提示信息
+func Lookup(provider Provider, ip string, languages ...string) Result {
+	result, err := provider.Find(ip, languages...)
+	if err != nil {
+		result = Result{Info: []string{ErrNotFound.Error()}}
+	}
+	result.Info = fn.RemoveDuplicates(result.Info)
+	return result
+}
@@ -0,0 +1,180 @@
+package ipInfo_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+type errorProvider struct{}
+
+func (errorProvider) Name() string { return "error" }
+
+func (errorProvider) Languages() []string { return nil }
+
+func (errorProvider) Find(ip string, languages ...string) (ipInfo.Result, error) {
+	return ipInfo.Result{}, fmt.Errorf("查询失败")
+}
+
+func TestChain_Find(t *testing.T) {
+	primary, err := ipInfo.NewMemoryProvider("primary", map[string][]string{
+		"1.1.1.0/24": {"主数据库"},
+	})
+	if err != nil {
+		t.Fatalf("创建内存数据库失败: %v", err)
+	}
+	fallback, err := ipInfo.NewMemoryProvider("fallback", map[string][]string{
+		"1.0.0.0/8": {"备用数据库"},
+	})
+	if err != nil {
+		t.Fatalf("创建内存数据库失败: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		providers []ipInfo.Provider
+		ip        string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "Primary hit",
+			providers: []ipInfo.Provider{primary, fallback},
+			ip:        "1.1.1.1",
+			want:      []string{"主数据库"},
+		},
+		{
+			name:      "Fallback hit",
+			providers: []ipInfo.Provider{primary, fallback},
+			ip:        "1.2.3.4",
+			want:      []string{"备用数据库"},
+		},
+		{
+			name:      "Priority order",
+			providers: []ipInfo.Provider{fallback, primary},
+			ip:        "1.1.1.1",
+			want:      []string{"备用数据库"},
+		},
+		{
+			name:      "Skip failing provider",
+			providers: []ipInfo.Provider{errorProvider{}, primary},
+			ip:        "1.1.1.1",
+			want:      []string{"主数据库"},
+		},
+		{
+			name:      "All miss",
+			providers: []ipInfo.Provider{primary, fallback},
+			ip:        "8.8.8.8",
+			wantErr:   true,
+		},
+		{
+			name:    "Empty chain",
+			ip:      "1.1.1.1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ipInfo.NewChain(tt.providers...).Find(tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Find() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got.Info, tt.want) {
+				t.Errorf("Find() = %v, want %v", got.Info, tt.want)
+			}
+		})
+	}
+
+	chain := ipInfo.NewChain(primary, fallback)
+	if chain.Name() != "primary,fallback" {
+		t.Errorf("Name() = %v, want primary,fallback", chain.Name())
+	}
+}
+
+func TestLookup(t *testing.T) {
+	provider, _ := ipInfo.NewMemoryProvider("memory", map[string][]string{
+		"1.1.1.1": {"中国", "中国", "北京"},
+	})
+
+	got := ipInfo.Lookup(provider, "1.1.1.1")
+	if !reflect.DeepEqual(got.Info, []string{"中国", "北京"}) {
+		t.Errorf("Lookup() = %v, 结果应该去重", got.Info)
+	}
+	if got.Country != "中国" || got.Region != "中国" || got.City != "北京" || got.Source != "memory" {
+		t.Errorf("Lookup() = %+v, 结构化字段不正确", got)
+	}
+
+	got = ipInfo.Lookup(provider, "2.2.2.2")
+	if !reflect.DeepEqual(got.Info, []string{"未找到 IP 地址信息"}) {
+		t.Errorf("Lookup() = %v, 未命中时应该返回提示信息", got.Info)
+	}
+}
+
+func TestProviderRegistry(t *testing.T) {
+	ipInfo.RegisterProvider("fake", func(source string) (ipInfo.Provider, error) {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		return ipInfo.NewMemoryProvider("fake", map[string][]string{strings.TrimSpace(string(content)): {"自定义"}})
+	})
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("10.0.0.0/8"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "b.txt"), []byte("1.1.1.1"), 0644)
+
+	kinds := strings.Join(ipInfo.ProviderKinds(), ",")
+	for _, kind := range []string{"csv", "fake", "ipip", "mmdb"} {
+		if !strings.Contains(kinds, kind) {
+			t.Errorf("ProviderKinds() = %v, 缺少 %s", kinds, kind)
+		}
+	}
+
+	chain, err := ipInfo.LoadProviders([]string{"fake:" + filepath.Join(tempDir, "a.txt"), " ", "FAKE:" + filepath.Join(tempDir, "b.txt")})
+	if err != nil {
+		t.Fatalf("LoadProviders() error = %v", err)
+	}
+	if len(chain.Providers()) != 2 {
+		t.Errorf("LoadProviders() 应该加载 2 个数据库，实际为 %d", len(chain.Providers()))
+	}
+	if got := ipInfo.Lookup(chain, "1.1.1.1"); !reflect.DeepEqual(got.Info, []string{"自定义"}) {
+		t.Errorf("Lookup() = %v", got.Info)
+	}
+
+	// 数据源不是文件时直接交给注册的查询后端，不支持热更新
+	ipInfo.RegisterProvider("static", func(source string) (ipInfo.Provider, error) {
+		return ipInfo.NewMemoryProvider("static", map[string][]string{source: {"静态数据"}})
+	})
+	chain, err = ipInfo.LoadProviders([]string{"static:203.0.113.0/24", "csv:" + filepath.Join(tempDir, "ranges.csv")})
+	if err == nil {
+		t.Error("LoadProviders() 应该拒绝不存在的 CSV 文件")
+	}
+	os.WriteFile(filepath.Join(tempDir, "ranges.csv"), []byte("1.1.1.0/24,CSV\n"), 0644)
+	chain, err = ipInfo.LoadProviders([]string{"static:203.0.113.0/24", "csv:" + filepath.Join(tempDir, "ranges.csv")})
+	if err != nil {
+		t.Fatalf("LoadProviders() error = %v", err)
+	}
+	if got := ipInfo.Lookup(chain, "203.0.113.1"); !reflect.DeepEqual(got.Info, []string{"静态数据"}) {
+		t.Errorf("Lookup() = %v", got.Info)
+	}
+	if databases := chain.Databases(); len(databases) != 1 || databases[0].Kind != "csv" {
+		t.Errorf("Databases() = %v, 只有文件数据库支持热更新", databases)
+	}
+
+	errorCases := [][]string{
+		{},
+		{"fake"},
+		{"unknown:" + filepath.Join(tempDir, "a.txt")},
+		{"csv:/nonexistent.csv"},
+	}
+	for _, specs := range errorCases {
+		if _, err := ipInfo.LoadProviders(specs); err == nil {
+			t.Errorf("LoadProviders(%v) 应该返回错误", specs)
+		}
+	}
+}
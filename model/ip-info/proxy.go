@@ -0,0 +1,77 @@
+package ipInfo
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// DEFAULT_TRUSTED_PROXIES 默认只信任本机的反向代理
+var DEFAULT_TRUSTED_PROXIES = []string{"127.0.0.0/8", "::1"}
+
+// TrustedProxies 受信任的反向代理地址段，只有来自这些地址的转发请求头才会被采信
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// ParseTrustedProxies 解析 IP 地址或 CIDR 列表
+func ParseTrustedProxies(items []string) (*TrustedProxies, error) {
+	trusted := &TrustedProxies{}
+	for _, item := range items {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		prefix, err := parsePrefix(item)
+		if err != nil {
+			return nil, err
+		}
+		trusted.prefixes = append(trusted.prefixes, prefix)
+	}
+	return trusted, nil
+}
+
+// CIDRs 返回规范化后的地址段，用于配置 gin 的 SetTrustedProxies
+func (t *TrustedProxies) CIDRs() []string {
+	if t == nil {
+		return nil
+	}
+	cidrs := make([]string, 0, len(t.prefixes))
+	for _, prefix := range t.prefixes {
+		cidrs = append(cidrs, prefix.String())
+	}
+	return cidrs
+}
+
+// Contains 判断地址是否属于受信任的代理
+func (t *TrustedProxies) Contains(ip string) bool {
+	if t == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range t.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveForwardedFor 从右向左遍历转发链，返回第一个不受信任的地址
+//
+// 链路上的地址都受信任时返回最左侧的地址，存在无法解析的地址时整条链视为无效
+func (t *TrustedProxies) ResolveForwardedFor(hops []string) (string, bool) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			return "", false
+		}
+		if i == 0 || !t.Contains(hop) {
+			return addr.Unmap().String(), true
+		}
+	}
+	return "", false
+}
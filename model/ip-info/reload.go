@@ -0,0 +1,252 @@
+package ipInfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DatabaseInfo 当前提供服务的数据库信息
+type DatabaseInfo struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Source    string    `json:"source"`
+	Version   string    `json:"version"`
+	BuildTime time.Time `json:"build_time"`
+	LoadedAt  time.Time `json:"loaded_at"`
+	Reloads   int64     `json:"reloads"`
+}
+
+type loadedProvider struct {
+	provider Provider
+	info     DatabaseInfo
+	modTime  time.Time
+	size     int64
+}
+
+// ReloadableProvider 支持热更新的文件数据库
+//
+// 更新时先完整加载并校验新文件，再原子替换，正在进行的查询会继续使用旧实例完成
+type ReloadableProvider struct {
+	kind    string
+	source  string
+	mu      sync.Mutex
+	reloads atomic.Int64
+	current atomic.Pointer[loadedProvider]
+}
+
+func NewReloadableProvider(kind string, source string) (*ReloadableProvider, error) {
+	p := &ReloadableProvider{kind: kind, source: source}
+	loaded, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	p.current.Store(loaded)
+	return p, nil
+}
+
+func (p *ReloadableProvider) Name() string {
+	return p.current.Load().provider.Name()
+}
+
+func (p *ReloadableProvider) Languages() []string {
+	return p.current.Load().provider.Languages()
+}
+
+func (p *ReloadableProvider) Find(ip string, languages ...string) (Result, error) {
+	return p.current.Load().provider.Find(ip, languages...)
+}
+
+func (p *ReloadableProvider) Database() DatabaseInfo {
+	info := p.current.Load().info
+	info.Reloads = p.reloads.Load()
+	return info
+}
+
+// Changed 判断数据库文件的修改时间或大小是否发生变化
+func (p *ReloadableProvider) Changed() bool {
+	stat, err := os.Stat(p.source)
+	if err != nil {
+		return false
+	}
+	current := p.current.Load()
+	return !stat.ModTime().Equal(current.modTime) || stat.Size() != current.size
+}
+
+// Reload 重新加载数据库，force 为 false 时仅在文件变化后加载，返回是否发生了替换
+func (p *ReloadableProvider) Reload(force bool) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !force && !p.Changed() {
+		return false, nil
+	}
+	loaded, err := p.load()
+	if err != nil {
+		return false, err
+	}
+	current := p.current.Load()
+	if loaded.info.Version == current.info.Version {
+		// 内容未变化时只更新文件状态，避免重复替换
+		p.current.Store(&loadedProvider{provider: current.provider, info: current.info, modTime: loaded.modTime, size: loaded.size})
+		return false, nil
+	}
+	p.current.Store(loaded)
+	p.reloads.Add(1)
+	logDatabase("IP 数据库已更新", loaded.info)
+	return true, nil
+}
+
+// load 读取一次数据库文件，版本号和查询后端都基于读到的同一份内容，避免文件在两次读取之间被替换
+func (p *ReloadableProvider) load() (*loadedProvider, error) {
+	content, stat, err := readFile(p.source)
+	if err != nil {
+		return nil, fmt.Errorf("加载 %s 数据库 %s 失败: %v", p.kind, p.source, err)
+	}
+	sum := sha256.Sum256(content)
+	version := hex.EncodeToString(sum[:])[:12]
+	provider, err := loadContent(p.kind, p.source, content)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateProvider(provider); err != nil {
+		return nil, fmt.Errorf("校验 %s 数据库 %s 失败: %v", p.kind, p.source, err)
+	}
+
+	buildTime := stat.ModTime()
+	if builder, ok := provider.(interface{ BuildTime() time.Time }); ok && !builder.BuildTime().IsZero() {
+		buildTime = builder.BuildTime()
+	}
+	return &loadedProvider{
+		provider: provider,
+		modTime:  stat.ModTime(),
+		size:     stat.Size(),
+		info: DatabaseInfo{
+			Name:      provider.Name(),
+			Kind:      p.kind,
+			Source:    p.source,
+			Version:   version,
+			BuildTime: buildTime,
+			LoadedAt:  time.Now(),
+		},
+	}, nil
+}
+
+// readFile 读取文件内容，文件状态来自同一个文件句柄，与读到的内容对应
+func readFile(path string) ([]byte, os.FileInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, stat, nil
+}
+
+// loadContent 使用读到的文件内容创建查询后端，自定义类型没有对应的 fileLoader，
+// 只能由注册的查询后端自行读取文件，文件在两次读取之间被替换时，版本号会在下次检查时更正
+func loadContent(kind string, source string, content []byte) (Provider, error) {
+	loader, ok := fileLoaders[strings.ToLower(kind)]
+	if !ok {
+		return NewProvider(kind, source)
+	}
+	provider, err := loader(source, content)
+	if err != nil {
+		return nil, fmt.Errorf("加载 %s 数据库 %s 失败: %v", kind, source, err)
+	}
+	return provider, nil
+}
+
+// validateProbes 所有数据库都应该能查到的公共地址，文件数据库还会额外查询自身包含的地址
+var validateProbes = []string{"127.0.0.1", "8.8.8.8", "1.1.1.1", "114.114.114.114", "223.5.5.5"}
+
+// validateProvider 使用试查询确认数据可以正常解析
+//
+// 查询出现 ErrNotFound 以外的错误，或者所有试查询都没有结果时，认为数据库已损坏
+func validateProvider(provider Provider) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("数据库查询异常: %v", r)
+		}
+	}()
+	probes := validateProbes
+	if sampler, ok := provider.(interface{ samples() []string }); ok {
+		probes = append(sampler.samples(), probes...)
+	}
+	for _, ip := range probes {
+		result, err := provider.Find(ip)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("查询 %s 失败: %v", ip, err)
+		}
+		if err == nil && len(result.Info) > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("试查询 %v 都没有结果", probes)
+}
+
+func logDatabase(message string, info DatabaseInfo) {
+	log.Printf("%s: %s (%s)，构建时间: %s，版本: %s\n", message, info.Name, info.Source, info.BuildTime.Format(time.RFC3339), info.Version)
+}
+
+// Databases 返回查询链中可热更新的数据库信息
+func (c *Chain) Databases() []DatabaseInfo {
+	databases := []DatabaseInfo{}
+	for _, provider := range c.providers {
+		if reloadable, ok := provider.(*ReloadableProvider); ok {
+			databases = append(databases, reloadable.Database())
+		}
+	}
+	return databases
+}
+
+// Reload 重新加载查询链中的数据库，单个数据库加载失败时继续使用旧实例
+func (c *Chain) Reload(force bool) {
+	for _, provider := range c.providers {
+		reloadable, ok := provider.(*ReloadableProvider)
+		if !ok {
+			continue
+		}
+		if _, err := reloadable.Reload(force); err != nil {
+			log.Printf("IP 数据库更新失败，继续使用旧版本: %v\n", err)
+		}
+	}
+}
+
+// Watch 定时检查数据库文件变化，收到信号时强制重新加载，直到 ctx 结束
+func (c *Chain) Watch(ctx context.Context, interval time.Duration, signals <-chan os.Signal) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			c.Reload(false)
+		case <-signals:
+			log.Println("收到重新加载信号，正在更新 IP 数据库")
+			c.Reload(true)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package ipInfo_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+func writeCSV(t *testing.T, path string, content string, modTime time.Time) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	os.Chtimes(path, modTime, modTime)
+}
+
+func TestReloadableProvider(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "ranges.csv")
+	now := time.Now()
+	writeCSV(t, csvPath, "1.0.0.0/24,旧数据\n", now.Add(-time.Hour))
+
+	provider, err := ipInfo.NewReloadableProvider("csv", csvPath)
+	if err != nil {
+		t.Fatalf("NewReloadableProvider() error = %v", err)
+	}
+	oldVersion := provider.Database().Version
+	if oldVersion == "" || provider.Database().Kind != "csv" {
+		t.Errorf("Database() = %+v", provider.Database())
+	}
+
+	// 文件未变化时不重新加载
+	if reloaded, err := provider.Reload(false); reloaded || err != nil {
+		t.Errorf("Reload(false) = %v, %v, 文件未变化时不应该重新加载", reloaded, err)
+	}
+
+	// 无效文件不会替换当前数据库
+	writeCSV(t, csvPath, "invalid\n", now.Add(-time.Minute))
+	if !provider.Changed() {
+		t.Error("Changed() 应该检测到文件变化")
+	}
+	if _, err := provider.Reload(false); err == nil {
+		t.Error("Reload() 应该拒绝无效文件")
+	}
+	if result, _ := provider.Find("1.0.0.1"); !reflect.DeepEqual(result.Info, []string{"旧数据"}) {
+		t.Errorf("加载失败后应该继续使用旧数据，得到 %v", result.Info)
+	}
+
+	// 有效文件替换当前数据库
+	writeCSV(t, csvPath, "1.0.0.0/24,新数据\n", now)
+	if reloaded, err := provider.Reload(false); !reloaded || err != nil {
+		t.Fatalf("Reload(false) = %v, %v", reloaded, err)
+	}
+	if result, _ := provider.Find("1.0.0.1"); !reflect.DeepEqual(result.Info, []string{"新数据"}) {
+		t.Errorf("重新加载后应该使用新数据，得到 %v", result.Info)
+	}
+	database := provider.Database()
+	if database.Version == oldVersion || database.Reloads != 1 {
+		t.Errorf("Database() = %+v", database)
+	}
+
+	// 内容相同时强制加载也不会替换
+	if reloaded, _ := provider.Reload(true); reloaded {
+		t.Error("内容未变化时不应该替换数据库")
+	}
+}
+
+func TestReloadableProvider_Validate(t *testing.T) {
+	dir := t.TempDir()
+	record := mmdbEncodeMap(map[string][]byte{
+		"country": mmdbEncodeMap(map[string][]byte{
+			"names": mmdbEncodeMap(map[string][]byte{"en": mmdbEncodeString("United States")}),
+		}),
+	})
+
+	mmdbPath := filepath.Join(dir, "city.mmdb")
+	os.WriteFile(mmdbPath, buildMMDB([4]byte{8, 8, 8, 0}, 24, record), 0644)
+	provider, err := ipInfo.NewReloadableProvider("mmdb", mmdbPath)
+	if err != nil {
+		t.Fatalf("NewReloadableProvider() error = %v", err)
+	}
+
+	// 元数据可以解析，但数据区被截断
+	os.WriteFile(mmdbPath, buildMMDB([4]byte{8, 8, 8, 0}, 24, record[:len(record)-4]), 0644)
+	if _, err := provider.Reload(true); err == nil {
+		t.Error("Reload() 应该拒绝数据区损坏的文件")
+	}
+	if result, _ := provider.Find("8.8.8.8"); !reflect.DeepEqual(result.Info, []string{"United States", "", ""}) {
+		t.Errorf("校验失败后应该继续使用旧数据，得到 %v", result.Info)
+	}
+
+	// 试查询都没有结果
+	csvPath := filepath.Join(dir, "ranges.csv")
+	writeCSV(t, csvPath, "# 没有地址段\n", time.Now())
+	if _, err := ipInfo.NewReloadableProvider("csv", csvPath); err == nil {
+		t.Error("NewReloadableProvider() 应该拒绝没有数据的文件")
+	}
+
+	// 文件数据库使用自身包含的地址试查询
+	writeCSV(t, csvPath, "192.0.2.0/24,测试网络\n", time.Now())
+	if _, err := ipInfo.NewReloadableProvider("csv", csvPath); err != nil {
+		t.Errorf("NewReloadableProvider() error = %v", err)
+	}
+}
+
+func TestReloadableProvider_ConcurrentLookup(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "ranges.csv")
+	writeCSV(t, csvPath, "1.0.0.0/24,A\n", time.Now().Add(-time.Hour))
+	provider, err := ipInfo.NewReloadableProvider("csv", csvPath)
+	if err != nil {
+		t.Fatalf("NewReloadableProvider() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				result, err := provider.Find("1.0.0.1")
+				if err != nil || len(result.Info) != 1 {
+					t.Errorf("并发查询失败: %v, %v", result.Info, err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		writeCSV(t, csvPath, "1.0.0.0/24,"+string(rune('A'+i%2))+"\n", time.Now().Add(time.Duration(i)*time.Second))
+		provider.Reload(true)
+	}
+	wg.Wait()
+}
+
+func TestChain_Watch(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "ranges.csv")
+	writeCSV(t, csvPath, "1.0.0.0/24,旧数据\n", time.Now().Add(-time.Hour))
+	chain, err := ipInfo.LoadProviders([]string{"csv:" + csvPath})
+	if err != nil {
+		t.Fatalf("LoadProviders() error = %v", err)
+	}
+	if len(chain.Databases()) != 1 {
+		t.Fatalf("Databases() = %v", chain.Databases())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	go chain.Watch(ctx, 0, signals)
+
+	// 修改时间不变时只能通过信号触发
+	writeCSV(t, csvPath, "1.0.0.0/24,新数据\n", time.Now().Add(-time.Hour))
+	signals <- syscall.SIGHUP
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if result, _ := chain.Find("1.0.0.1"); reflect.DeepEqual(result.Info, []string{"新数据"}) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("收到信号后应该重新加载数据库")
+}
@@ -0,0 +1,46 @@
+package ipInfo
+
+import (
+	"strconv"
+)
+
+// Result 结构化的 IP 查询结果
+type Result struct {
+	Country     string   `json:"country" yaml:"country" xml:"country"`
+	CountryCode string   `json:"country_code,omitempty" yaml:"country_code,omitempty" xml:"country_code,omitempty"`
+	Region      string   `json:"region" yaml:"region" xml:"region"`
+	City        string   `json:"city" yaml:"city" xml:"city"`
+	ISP         string   `json:"isp,omitempty" yaml:"isp,omitempty" xml:"isp,omitempty"`
+	Owner       string   `json:"owner,omitempty" yaml:"owner,omitempty" xml:"owner,omitempty"`
+	Timezone    string   `json:"timezone,omitempty" yaml:"timezone,omitempty" xml:"timezone,omitempty"`
+	Latitude    *float64 `json:"latitude,omitempty" yaml:"latitude,omitempty" xml:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty" yaml:"longitude,omitempty" xml:"longitude,omitempty"`
+	Source      string   `json:"source,omitempty" yaml:"source,omitempty" xml:"source,omitempty"`
+	Language    string   `json:"language,omitempty" yaml:"language,omitempty" xml:"language,omitempty"`
+
+	// Info 为数据库返回的原始字段列表，用于兼容旧版本的 `info` 数组
+	Info []string `json:"-" yaml:"-" xml:"-"`
+}
+
+// resultFromInfo 按 `国家,地区,城市,运营商` 的顺序解析字段列表
+func resultFromInfo(source string, info []string) Result {
+	result := Result{Source: source, Info: info}
+	fields := []*string{&result.Country, &result.Region, &result.City, &result.ISP}
+	for i, field := range fields {
+		if i < len(info) {
+			*field = info[i]
+		}
+	}
+	return result
+}
+
+func parseCoordinate(s string) *float64 {
+	if s == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,111 @@
+package leaktest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+)
+
+// Resolver 在测试期间向权威 DNS 发起查询的解析器
+type Resolver struct {
+	IP        string
+	Queries   int
+	FirstSeen time.Time
+}
+
+type session struct {
+	created   time.Time
+	resolvers []*Resolver
+}
+
+// Store 保存泄露测试会话，以及每个会话中出现过的解析器
+type Store struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	sessions map[string]*session
+}
+
+func NewStore(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, sessions: map[string]*session{}}
+}
+
+// prune 清除过期的会话，数量仍然达到上限时清除最早创建的会话
+func (s *Store) prune(now time.Time) {
+	var oldest string
+	for token, session := range s.sessions {
+		if now.Sub(session.created) > s.ttl {
+			delete(s.sessions, token)
+			continue
+		}
+		if oldest == "" || session.created.Before(s.sessions[oldest].created) {
+			oldest = token
+		}
+	}
+	if len(s.sessions) >= define.LEAKTEST_MAX_SESSIONS && oldest != "" {
+		delete(s.sessions, oldest)
+	}
+}
+
+// NewSession 创建会话并返回随机令牌，令牌可以直接作为域名的一级标签
+func (s *Store) NewSession() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成会话令牌失败: %v", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.prune(now)
+	s.sessions[token] = &session{created: now}
+	return token, nil
+}
+
+// Record 记录解析器的查询，会话不存在或已过期时返回 false
+func (s *Store) Record(token string, ip string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[token]
+	if !ok || time.Since(session.created) > s.ttl {
+		return false
+	}
+	for _, resolver := range session.resolvers {
+		if resolver.IP == ip {
+			resolver.Queries++
+			return true
+		}
+	}
+	if len(session.resolvers) < define.LEAKTEST_MAX_RESOLVERS {
+		session.resolvers = append(session.resolvers, &Resolver{IP: ip, Queries: 1, FirstSeen: time.Now()})
+	}
+	return true
+}
+
+// Resolvers 按首次出现的顺序返回会话中的解析器
+func (s *Store) Resolvers(token string) ([]Resolver, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[token]
+	if !ok || time.Since(session.created) > s.ttl {
+		return nil, false
+	}
+	resolvers := make([]Resolver, 0, len(session.resolvers))
+	for _, resolver := range session.resolvers {
+		resolvers = append(resolvers, *resolver)
+	}
+	return resolvers, true
+}
+
+// Names 返回浏览器需要解析的域名，格式为 `<token>.<n>.<zone>`
+func Names(token string, zone string) []string {
+	names := make([]string, 0, define.LEAKTEST_NAMES)
+	for i := 1; i <= define.LEAKTEST_NAMES; i++ {
+		names = append(names, fmt.Sprintf("%s.%d.%s", token, i, zone))
+	}
+	return names
+}
@@ -0,0 +1,57 @@
+package leaktest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/leaktest"
+)
+
+func TestStore(t *testing.T) {
+	store := leaktest.NewStore(time.Minute)
+	token, err := store.NewSession()
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	if len(token) != 32 || strings.ToLower(token) != token {
+		t.Errorf("令牌应该是 32 位小写十六进制字符串，实际为 %s", token)
+	}
+
+	if store.Record("unknown", "192.0.2.1") {
+		t.Error("不存在的会话不应该记录解析器")
+	}
+	if resolvers, ok := store.Resolvers(token); !ok || len(resolvers) != 0 {
+		t.Errorf("新会话应该没有解析器，实际为 %v %v", resolvers, ok)
+	}
+
+	store.Record(token, "192.0.2.1")
+	store.Record(token, "198.51.100.1")
+	store.Record(token, "192.0.2.1")
+	resolvers, ok := store.Resolvers(token)
+	if !ok || len(resolvers) != 2 {
+		t.Fatalf("应该记录两个解析器，实际为 %v", resolvers)
+	}
+	if resolvers[0].IP != "192.0.2.1" || resolvers[0].Queries != 2 || resolvers[1].IP != "198.51.100.1" || resolvers[1].Queries != 1 {
+		t.Errorf("解析器应该按首次出现的顺序记录查询次数，实际为 %+v", resolvers)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	store := leaktest.NewStore(10 * time.Millisecond)
+	token, _ := store.NewSession()
+	time.Sleep(20 * time.Millisecond)
+	if store.Record(token, "192.0.2.1") {
+		t.Error("过期的会话不应该记录解析器")
+	}
+	if _, ok := store.Resolvers(token); ok {
+		t.Error("过期的会话不应该返回结果")
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := leaktest.Names("abc", "leak.example.com")
+	if len(names) != 6 || names[0] != "abc.1.leak.example.com" || names[5] != "abc.6.leak.example.com" {
+		t.Errorf("测试域名错误: %v", names)
+	}
+}
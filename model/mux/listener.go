@@ -0,0 +1,45 @@
+package mux
+
+import (
+	"net"
+	"sync"
+)
+
+// Listener 将分发得到的连接提供给 HTTP 服务，本身不监听任何地址
+type Listener struct {
+	addr   net.Addr
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func NewListener(addr net.Addr) *Listener {
+	return &Listener{addr: addr, conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+// Push 将连接交给 Accept 的调用方，监听器已关闭时直接关闭连接
+func (l *Listener) Push(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.closed:
+		conn.Close()
+	}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *Listener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,188 @@
+package mux
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/ftp"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/supervisor"
+	"github.com/soulteary/ip-helper/model/telnet"
+)
+
+// HTTP 请求行开头的方法名，`PRI` 为 HTTP/2 明文连接的前言
+var httpMethods = []string{"GET", "HEAD", "POST", "PUT", "DELETE", "CONNECT", "OPTIONS", "TRACE", "PATCH", "PRI"}
+
+// FTP 客户端登录前可能发送的第一条命令
+var ftpCommands = map[string]bool{
+	"USER": true,
+	"PASS": true,
+	"AUTH": true,
+	"FEAT": true,
+	"SYST": true,
+	"OPTS": true,
+	"CLNT": true,
+	"HOST": true,
+	"NOOP": true,
+	"QUIT": true,
+}
+
+// Serve 在同一个端口上同时提供 HTTP 以及已开启的 TELNET 和 FTP 服务
+//
+// 连接建立后在 `MultiplexTimeout` 内读取客户端发送的第一批数据：
+//   - 以 HTTP 方法开头的交给 serveHTTP 处理
+//   - 其他数据（包括 TELNET 的选项协商）按 TELNET 会话处理
+//   - 超时仍没有数据的客户端可能在等待服务端先发言，先发送 FTP 欢迎信息（未开启 FTP 时发送 TELNET 的查询结果），
+//     再根据客户端的第一批数据决定交给 HTTP、继续 FTP 会话还是进入 TELNET 交互模式
+//
+// 浏览器预先建立、稍后才发送请求的连接同样会先收到欢迎信息，HTTP 响应之前多出的内容可能导致请求失败，
+// 所以端口复用默认关闭；TELNET 和 FTP 都未开启时不发送欢迎信息，所有连接都交给 HTTP 处理
+func Serve(ctx context.Context, config *define.Config, ipdb ipInfo.Provider, listener net.Listener, serveHTTP func(ctx context.Context, listener net.Listener) error) error {
+	passive, _ := ftp.ParsePassive(config.FTPPassiveAddr, config.FTPPassivePorts)
+	httpListener := NewListener(listener.Addr())
+	httpErr := make(chan error, 1)
+	go func() {
+		httpErr <- serveHTTP(ctx, httpListener)
+	}()
+
+	err := supervisor.ServeConn(ctx, "MUX", listener, func(conn net.Conn) {
+		dispatch(config, passive, ipdb, conn, httpListener)
+	})
+	if err != nil {
+		httpListener.Close()
+		return err
+	}
+	// HTTP 服务在 ctx 结束后自行关闭监听器，提前关闭会让它把正常退出当作 Accept 失败
+	err = <-httpErr
+	httpListener.Close()
+	return err
+}
+
+func dispatch(config *define.Config, passive ftp.Passive, ipdb ipInfo.Provider, conn net.Conn, httpListener *Listener) {
+	c := newConn(conn)
+	data, err := c.sniff(config.MultiplexTimeout)
+	if err != nil {
+		if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			conn.Close()
+			return
+		}
+		if !config.EnableFTP && !config.EnableTelnet {
+			httpListener.Push(c)
+			return
+		}
+		// 客户端在等待欢迎信息，两种欢迎信息都包含查询结果，对另一种协议的用户也可读
+		greet := telnet.Greet
+		if config.EnableFTP {
+			greet = ftp.Greet
+		}
+		if !greet(ipdb, c) {
+			conn.Close()
+			return
+		}
+		data, err = c.sniff(define.FTP_IDLE_TIMEOUT)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		switch {
+		case isHTTP(data):
+			httpListener.Push(c)
+		case config.EnableFTP && (isFTP(data) || !config.EnableTelnet):
+			ftp.Resume(ipdb, passive, c)
+		default:
+			telnet.Resume(ipdb, c)
+		}
+		return
+	}
+
+	switch {
+	case isHTTP(data):
+		httpListener.Push(c)
+	case config.EnableTelnet:
+		telnet.HandleConnection(ipdb, c)
+	case config.EnableFTP:
+		// FTP 客户端不会先发送数据，这里只可能是其他协议，按 FTP 会话处理以返回错误信息
+		ftp.Handler(passive)(ipdb, c)
+	default:
+		httpListener.Push(c)
+	}
+}
+
+// isHTTP 判断数据是否以 HTTP 方法和空格开头
+func isHTTP(data []byte) bool {
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(data, []byte(method+" ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// maybeHTTP 判断数据是否可能是尚未接收完整的 HTTP 请求行
+func maybeHTTP(data []byte) bool {
+	for _, method := range httpMethods {
+		if len(data) <= len(method) && strings.HasPrefix(method+" ", string(data)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFTP 判断客户端的第一条命令是否为 FTP 命令
+func isFTP(data []byte) bool {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	command, _, _ := strings.Cut(strings.TrimRight(string(line), "\r"), " ")
+	return ftpCommands[strings.ToUpper(command)]
+}
+
+// Conn 回放分发前读取的数据，之后继续从原连接读取
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func newConn(conn net.Conn) *Conn {
+	return &Conn{Conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// Proxied 转发底层连接的 PROXY protocol 状态，供 FTP 判断数据连接的来源
+func (c *Conn) Proxied() bool {
+	proxied, ok := c.Conn.(interface{ Proxied() bool })
+	return ok && proxied.Proxied()
+}
+
+func (c *Conn) buffered() []byte {
+	data, _ := c.reader.Peek(c.reader.Buffered())
+	return data
+}
+
+// sniff 在超时时间内读取客户端最先发送的数据，数据不足以判断是否为 HTTP 时继续等待
+func (c *Conn) sniff(timeout time.Duration) ([]byte, error) {
+	c.SetReadDeadline(time.Now().Add(timeout))
+	defer c.SetReadDeadline(time.Time{})
+
+	if _, err := c.reader.Peek(1); err != nil {
+		return nil, err
+	}
+	data := c.buffered()
+	for maybeHTTP(data) {
+		if _, err := c.reader.Peek(len(data) + 1); err != nil {
+			// 已经收到数据，超时后按非 HTTP 客户端处理
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return c.buffered(), nil
+			}
+			return nil, err
+		}
+		data = c.buffered()
+	}
+	return data, nil
+}
@@ -0,0 +1,176 @@
+package mux_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/mux"
+	"github.com/soulteary/ip-helper/model/testutil"
+)
+
+func serveHTTP(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "http ok")
+	})}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	if err := server.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func dial(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn := testutil.Dial(t, addr)
+	return conn, bufio.NewReader(conn)
+}
+
+// readUntil 读取数据直到包含指定内容
+func readUntil(t *testing.T, reader *bufio.Reader, want string) string {
+	t.Helper()
+	output := []byte{}
+	for !strings.Contains(string(output), want) {
+		b, err := reader.ReadByte()
+		if err != nil {
+			t.Fatalf("读取 %q 失败: %v，已读取: %q", want, err, output)
+		}
+		output = append(output, b)
+	}
+	return string(output)
+}
+
+// startServe 启动端口复用服务，测试结束时关闭并确认 Serve 正常返回
+func startServe(t *testing.T, config *define.Config) string {
+	t.Helper()
+	ipdb := testutil.MemoryProvider(t)
+	config.MultiplexTimeout = 50 * time.Millisecond
+	return testutil.Serve(t, func(ctx context.Context, listener net.Listener) error {
+		return mux.Serve(ctx, config, ipdb, listener, serveHTTP)
+	})
+}
+
+func TestServe(t *testing.T) {
+	addr := startServe(t, &define.Config{EnableTelnet: true, EnableFTP: true})
+
+	t.Run("HTTP", func(t *testing.T) {
+		conn, reader := dial(t, addr)
+		defer conn.Close()
+		fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+		response, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("读取响应失败: %v", err)
+		}
+		if !strings.HasPrefix(string(response), "HTTP/1.1 200") || !strings.HasSuffix(string(response), "http ok") {
+			t.Errorf("HTTP 请求应该交给 HTTP 服务处理，实际为 %q", response)
+		}
+	})
+
+	t.Run("HTTP request line in pieces", func(t *testing.T) {
+		conn, reader := dial(t, addr)
+		defer conn.Close()
+		fmt.Fprint(conn, "GE")
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(conn, "T / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+		readUntil(t, reader, "http ok")
+	})
+
+	t.Run("TELNET negotiation", func(t *testing.T) {
+		conn, reader := dial(t, addr)
+		defer conn.Close()
+		conn.Write([]byte{255, 253, 3})
+		line := readUntil(t, reader, "\r\n")
+		if !strings.HasPrefix(line, "{") || !strings.Contains(line, "本机地址") {
+			t.Errorf("TELNET 客户端应该首先收到 JSON 查询结果，实际为 %q", line)
+		}
+	})
+
+	t.Run("Silent FTP client", func(t *testing.T) {
+		conn, reader := dial(t, addr)
+		defer conn.Close()
+		greeting := readUntil(t, reader, "\r\n")
+		if !strings.HasPrefix(greeting, "220 {") {
+			t.Fatalf("等待欢迎信息的客户端应该收到 FTP 欢迎信息，实际为 %q", greeting)
+		}
+		fmt.Fprint(conn, "USER anonymous\r\n")
+		if reply := readUntil(t, reader, "\r\n"); !strings.HasPrefix(reply, "331 ") {
+			t.Errorf("USER 应该返回 331，实际为 %q", reply)
+		}
+		fmt.Fprint(conn, "QUIT\r\n")
+		if reply := readUntil(t, reader, "\r\n"); !strings.HasPrefix(reply, "221 ") {
+			t.Errorf("QUIT 应该返回 221，实际为 %q", reply)
+		}
+	})
+
+	t.Run("Silent TELNET client", func(t *testing.T) {
+		conn, reader := dial(t, addr)
+		defer conn.Close()
+		readUntil(t, reader, "\r\n")
+		fmt.Fprint(conn, "me\r\n")
+		output := readUntil(t, reader, "本机地址")
+		if !strings.Contains(output, "输入 help 查看可用命令") {
+			t.Errorf("非 FTP 命令应该进入 TELNET 交互模式，实际为 %q", output)
+		}
+		fmt.Fprint(conn, "quit\r\n")
+		readUntil(t, reader, "再见")
+	})
+
+	t.Run("Delayed HTTP request after greeting", func(t *testing.T) {
+		conn, reader := dial(t, addr)
+		defer conn.Close()
+		readUntil(t, reader, "\r\n")
+		fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+		if response := readUntil(t, reader, "http ok"); !strings.Contains(response, "HTTP/1.1 200") {
+			t.Errorf("欢迎信息之后的 HTTP 请求应该交给 HTTP 服务处理，实际为 %q", response)
+		}
+	})
+}
+
+func TestServeDisabledProtocols(t *testing.T) {
+	t.Run("WEB only", func(t *testing.T) {
+		addr := startServe(t, &define.Config{})
+
+		conn, reader := dial(t, addr)
+		defer conn.Close()
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+		response, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("读取响应失败: %v", err)
+		}
+		if !strings.HasPrefix(string(response), "HTTP/1.1 200") {
+			t.Errorf("未开启 TELNET 和 FTP 时不应该发送欢迎信息，实际为 %q", response)
+		}
+
+		conn, reader = dial(t, addr)
+		defer conn.Close()
+		fmt.Fprint(conn, "me\r\n\r\n")
+		if line := readUntil(t, reader, "\r\n"); !strings.HasPrefix(line, "HTTP/1.1 400") {
+			t.Errorf("未开启 TELNET 时不应该进入 TELNET 会话，实际为 %q", line)
+		}
+	})
+
+	t.Run("TELNET only", func(t *testing.T) {
+		addr := startServe(t, &define.Config{EnableTelnet: true})
+
+		conn, reader := dial(t, addr)
+		defer conn.Close()
+		if greeting := readUntil(t, reader, "\r\n"); !strings.HasPrefix(greeting, "{") {
+			t.Fatalf("未开启 FTP 时应该发送 TELNET 的查询结果，实际为 %q", greeting)
+		}
+		fmt.Fprint(conn, "USER anonymous\r\n")
+		if output := readUntil(t, reader, "> "); strings.Contains(output, "331 ") {
+			t.Errorf("未开启 FTP 时不应该进入 FTP 会话，实际为 %q", output)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package page
+
+// Error 请求无效时的错误页面，使用 html/template 渲染，可用的数据为 Code、Message 和 Input
+const Error = `
+<!DOCTYPE html>
+<html lang="zh-Hans">
+  <head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>{{.Code}} - IP Query</title>
+    <base href="/">
+    <style>
+      * {
+        margin: 0;
+        padding: 0;
+        box-sizing: border-box;
+        font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, "Helvetica Neue", Arial, sans-serif;
+      }
+
+      body {
+        background-color: #f5f5f5;
+        padding: 20px;
+        display: flex;
+        flex-direction: column;
+        align-items: center;
+        min-height: 100vh;
+      }
+
+      .result-container {
+        background-color: white;
+        padding: 20px;
+        border-radius: 8px;
+        box-shadow: 0 2px 8px rgba(0, 0, 0, 0.1);
+        width: 100%;
+        max-width: 600px;
+        margin-top: 30px;
+      }
+
+      h1 {
+        font-size: 24px;
+        color: #333;
+        margin-bottom: 15px;
+      }
+
+      .result-value {
+        color: #333;
+        line-height: 1.5;
+        margin-bottom: 15px;
+        word-break: break-all;
+      }
+
+      .result-input {
+        color: #666;
+        font-family: monospace;
+      }
+
+      a {
+        color: #333;
+      }
+    </style>
+  </head>
+  <body>
+    <div class="result-container">
+      <h1>{{.Code}}</h1>
+      <div class="result-value">{{.Message}}</div>
+      {{if .Input}}<div class="result-value result-input">{{.Input}}</div>{{end}}
+      <a href="/">返回首页</a>
+    </div>
+  </body>
+</html>
+`
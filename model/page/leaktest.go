@@ -0,0 +1,112 @@
+package page
+
+// LeakTest DNS 泄露测试页面，浏览器解析测试域名后展示权威 DNS 记录到的解析器
+const LeakTest = `
+<!DOCTYPE html>
+<html lang="zh-Hans">
+  <head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>DNS Leak Test</title>
+    <base href="/">
+    <style>
+      * {
+        margin: 0;
+        padding: 0;
+        box-sizing: border-box;
+        font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, "Helvetica Neue", Arial, sans-serif;
+      }
+
+      body {
+        background-color: #f5f5f5;
+        padding: 20px;
+        display: flex;
+        flex-direction: column;
+        align-items: center;
+        min-height: 100vh;
+      }
+
+      .result-container {
+        background-color: white;
+        padding: 20px;
+        border-radius: 8px;
+        box-shadow: 0 2px 8px rgba(0, 0, 0, 0.1);
+        width: 100%;
+        max-width: 600px;
+      }
+
+      .result-title {
+        font-size: 16px;
+        color: #333;
+        margin-bottom: 15px;
+        user-select: none;
+      }
+
+      .result-row {
+        display: flex;
+        margin-bottom: 15px;
+        line-height: 1.5;
+      }
+
+      .result-label {
+        width: 160px;
+        color: #666;
+        font-family: monospace;
+      }
+
+      .result-value {
+        flex: 1;
+        color: #333;
+      }
+    </style>
+  </head>
+  <body>
+    <div class="result-container">
+      <div class="result-title">DNS 泄露测试</div>
+      <div id="status" class="result-row">正在测试...</div>
+      <div id="resolvers"></div>
+    </div>
+    <script>
+      (function () {
+        var search = location.search;
+        var status = document.getElementById("status");
+        var list = document.getElementById("resolvers");
+
+        function show(resolvers) {
+          status.textContent = "共发现 " + resolvers.length + " 个解析器";
+          resolvers.forEach(function (resolver) {
+            var row = document.createElement("div");
+            row.className = "result-row";
+            var label = document.createElement("div");
+            label.className = "result-label";
+            label.textContent = resolver.ip;
+            var value = document.createElement("div");
+            value.className = "result-value";
+            value.textContent = resolver.info.join(" ");
+            row.appendChild(label);
+            row.appendChild(value);
+            list.appendChild(row);
+          });
+        }
+
+        fetch("leaktest" + search, { method: "POST", cache: "no-store" })
+          .then(function (response) { return response.json(); })
+          .then(function (session) {
+            // 加载失败不影响测试，只需要浏览器解析这些域名
+            session.names.forEach(function (name) {
+              new Image().src = location.protocol + "//" + name + "/";
+            });
+            setTimeout(function () {
+              fetch("leaktest/" + session.token + search, { cache: "no-store" })
+                .then(function (response) { return response.json(); })
+                .then(function (result) { show(result.resolvers); });
+            }, 3000);
+          })
+          .catch(function () {
+            status.textContent = "测试失败，请刷新页面重试";
+          });
+      })();
+    </script>
+  </body>
+</html>
+`
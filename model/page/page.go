@@ -1,5 +1,6 @@
 package page
 
+// Template 查询结果页面，使用 html/template 渲染，可用的数据见 response.Page
 const Template = `
 <!DOCTYPE html>
 <html lang="zh-Hans">
@@ -8,7 +9,7 @@ const Template = `
     <meta name="viewport" content="width=device-width, initial-scale=1"> 
     <title>IP Query</title>
     <meta name="description" content="IP Query Tools, open-source project https://github.com/soulteary/ip-helper">
-    <link rel="canonical" href="%DOMAIN%%DOCUMENT_PATH%"/>
+    <link rel="canonical" href="{{.Domain}}{{.Path}}"/>
     <base href="/">
     <style>
       * {
@@ -160,7 +161,7 @@ const Template = `
       <div class="main-content">
         <div class="search-container">
           <form action="/" method="post">
-            <input type="text" name="ip" class="search-input" placeholder="请输入要查询的 IP 地址" value="%IP_ADDR%" />
+            <input type="text" name="ip" class="search-input" placeholder="请输入要查询的 IP 地址" value="{{.IP}}" />
             <button class="search-button" type="submit">查询</button>  
           </form>
         </div>
@@ -168,11 +169,11 @@ const Template = `
         <div class="result-container">
           <div class="result-row">
             <div class="result-label">IP</div>
-            <div class="result-value">%IP_ADDR%</div>
+            <div class="result-value">{{.IP}}</div>
           </div>
           <div class="result-row">
             <div class="result-label">地址</div>
-            <div class="result-value">%DATA_1_INFO%</div>
+            <div class="result-value">{{.Info}}</div>
           </div>
           <!-- <div class="result-row">
             <div class="result-label">运营商</div>
@@ -188,7 +189,7 @@ const Template = `
           </div>
           <div class="result-row">
             <div class="result-label">URL</div>
-            <div class="result-value">%DOMAIN%/%IP_ADDR%</div>
+            <div class="result-value">{{.Domain}}/{{.IP}}</div>
           </div>
         </div>
 
@@ -200,7 +201,7 @@ const Template = `
             </div>
             <div class="command-code">
               <span class="command-prompt">#</span>
-              <input id="command-curl" type="text" value="curl %ONLY_DOMAIN_WITH_PORT%" />
+              <input id="command-curl" type="text" value="curl {{.DomainWithPort}}" />
             </div>
           </div>
           <div class="command-row">
@@ -209,7 +210,7 @@ const Template = `
             </div>
             <div class="command-code">
               <span class="command-prompt">></span>
-              <input id="command-telenet" type="text" value="telnet %ONLY_DOMAIN%" />
+              <input id="command-telenet" type="text" value="telnet {{.OnlyDomain}}" />
             </div>
           </div>
           <div class="command-row">
@@ -218,7 +219,7 @@ const Template = `
             </div>
             <div class="command-code">
               <span class="command-prompt">></span>
-              <input id="command-ftp" type="text" value="ftp %ONLY_DOMAIN%" />
+              <input id="command-ftp" type="text" value="ftp {{.OnlyDomain}}" />
             </div>
           </div>
 
@@ -229,7 +230,7 @@ const Template = `
             </div>
             <div class="command-code">
               <span class="command-prompt">#</span>
-              <input id="command-curl-ip-only" type="text" value="curl %ONLY_DOMAIN_WITH_PORT%/ip" />
+              <input id="command-curl-ip-only" type="text" value="curl {{.DomainWithPort}}/ip" />
             </div>
           </div>
         </div>
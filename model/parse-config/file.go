@@ -0,0 +1,187 @@
+package configParser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/soulteary/ip-helper/model/define"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig 配置文件的结构，同时用于输出生效的配置
+type fileConfig struct {
+	Debug                bool     `yaml:"debug" toml:"debug"`
+	Port                 string   `yaml:"port" toml:"port"`
+	Domain               string   `yaml:"domain" toml:"domain"`
+	Token                string   `yaml:"token" toml:"token"`
+	TrustedProxies       []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	ClientIPHeaders      []string `yaml:"client_ip_headers" toml:"client_ip_headers"`
+	DownloadTools        []string `yaml:"download_tools" toml:"download_tools"`
+	TemplateDir          string   `yaml:"template_dir" toml:"template_dir"`
+	AssetsDir            string   `yaml:"assets_dir" toml:"assets_dir"`
+	BatchMaxItems        int      `yaml:"batch_max_items" toml:"batch_max_items"`
+	DB                   string   `yaml:"db" toml:"db"`
+	Providers            []string `yaml:"providers" toml:"providers"`
+	ReloadInterval       string   `yaml:"reload_interval" toml:"reload_interval"`
+	EnableWeb            bool     `yaml:"enable_web" toml:"enable_web"`
+	EnableTelnet         bool     `yaml:"enable_telnet" toml:"enable_telnet"`
+	EnableFTP            bool     `yaml:"enable_ftp" toml:"enable_ftp"`
+	EnableDNS            bool     `yaml:"enable_dns" toml:"enable_dns"`
+	EnableSTUN           bool     `yaml:"enable_stun" toml:"enable_stun"`
+	EnableWhois          bool     `yaml:"enable_whois" toml:"enable_whois"`
+	EnableSMTP           bool     `yaml:"enable_smtp" toml:"enable_smtp"`
+	EnablePOP3           bool     `yaml:"enable_pop3" toml:"enable_pop3"`
+	EnableIMAP           bool     `yaml:"enable_imap" toml:"enable_imap"`
+	EnableFinger         bool     `yaml:"enable_finger" toml:"enable_finger"`
+	EnableSSH            bool     `yaml:"enable_ssh" toml:"enable_ssh"`
+	TelnetAddr           string   `yaml:"telnet_addr" toml:"telnet_addr"`
+	FTPAddr              string   `yaml:"ftp_addr" toml:"ftp_addr"`
+	FTPPassiveAddr       string   `yaml:"ftp_passive_addr" toml:"ftp_passive_addr"`
+	FTPPassivePorts      string   `yaml:"ftp_passive_ports" toml:"ftp_passive_ports"`
+	DNSAddr              string   `yaml:"dns_addr" toml:"dns_addr"`
+	DNSZone              string   `yaml:"dns_zone" toml:"dns_zone"`
+	LeakTestZone         string   `yaml:"leaktest_zone" toml:"leaktest_zone"`
+	STUNAddr             string   `yaml:"stun_addr" toml:"stun_addr"`
+	STUNSoftware         string   `yaml:"stun_software" toml:"stun_software"`
+	WhoisAddr            string   `yaml:"whois_addr" toml:"whois_addr"`
+	SMTPAddr             string   `yaml:"smtp_addr" toml:"smtp_addr"`
+	POP3Addr             string   `yaml:"pop3_addr" toml:"pop3_addr"`
+	IMAPAddr             string   `yaml:"imap_addr" toml:"imap_addr"`
+	FingerAddr           string   `yaml:"finger_addr" toml:"finger_addr"`
+	SSHAddr              string   `yaml:"ssh_addr" toml:"ssh_addr"`
+	SSHHostKey           string   `yaml:"ssh_host_key" toml:"ssh_host_key"`
+	ShutdownTimeout      string   `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	ProxyProtocol        bool     `yaml:"proxy_protocol" toml:"proxy_protocol"`
+	ProxyProtocolTrusted []string `yaml:"proxy_protocol_trusted" toml:"proxy_protocol_trusted"`
+	ProxyProtocolTimeout string   `yaml:"proxy_protocol_timeout" toml:"proxy_protocol_timeout"`
+	Multiplex            bool     `yaml:"multiplex" toml:"multiplex"`
+	MultiplexTimeout     string   `yaml:"multiplex_timeout" toml:"multiplex_timeout"`
+}
+
+const REDACTED = "******"
+
+func knownKeys() map[string]bool {
+	keys := map[string]bool{}
+	t := reflect.TypeOf(fileConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		keys[t.Field(i).Tag.Get("yaml")] = true
+	}
+	return keys
+}
+
+// LoadFile 读取 YAML 或 TOML 配置文件，返回以配置项名称为键的字符串值
+func LoadFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	default:
+		return nil, fmt.Errorf("不支持的配置文件格式: %s，仅支持 .yaml、.yml 和 .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+	}
+
+	known := knownKeys()
+	unknown := []string{}
+	values := map[string]string{}
+	for key, value := range raw {
+		if !known[key] {
+			unknown = append(unknown, key)
+			continue
+		}
+		switch v := value.(type) {
+		case nil:
+			continue
+		case []any:
+			items := []string{}
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("配置项 `%s` 的值不能是对象", key)
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("配置文件 %s 中包含未知的配置项: %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+// Dump 以 YAML 格式输出生效的配置，令牌等敏感信息会被隐藏
+func Dump(config *define.Config) string {
+	effective := fileConfig{
+		Debug:                config.Debug,
+		Port:                 config.Port,
+		Domain:               config.Domain,
+		Token:                config.Token,
+		TrustedProxies:       config.TrustedProxies,
+		ClientIPHeaders:      config.ClientIPHeaders,
+		DownloadTools:        config.DownloadTools,
+		TemplateDir:          config.TemplateDir,
+		AssetsDir:            config.AssetsDir,
+		BatchMaxItems:        config.BatchMaxItems,
+		DB:                   config.DBPath,
+		Providers:            config.Providers,
+		ReloadInterval:       config.ReloadInterval.String(),
+		EnableWeb:            config.EnableWeb,
+		EnableTelnet:         config.EnableTelnet,
+		EnableFTP:            config.EnableFTP,
+		TelnetAddr:           config.TelnetAddr,
+		EnableDNS:            config.EnableDNS,
+		EnableSTUN:           config.EnableSTUN,
+		EnableWhois:          config.EnableWhois,
+		EnableSMTP:           config.EnableSMTP,
+		EnablePOP3:           config.EnablePOP3,
+		EnableIMAP:           config.EnableIMAP,
+		EnableFinger:         config.EnableFinger,
+		EnableSSH:            config.EnableSSH,
+		FTPAddr:              config.FTPAddr,
+		FTPPassiveAddr:       config.FTPPassiveAddr,
+		FTPPassivePorts:      config.FTPPassivePorts,
+		DNSAddr:              config.DNSAddr,
+		DNSZone:              config.DNSZone,
+		LeakTestZone:         config.LeakTestZone,
+		STUNAddr:             config.STUNAddr,
+		STUNSoftware:         config.STUNSoftware,
+		WhoisAddr:            config.WhoisAddr,
+		SMTPAddr:             config.SMTPAddr,
+		POP3Addr:             config.POP3Addr,
+		IMAPAddr:             config.IMAPAddr,
+		FingerAddr:           config.FingerAddr,
+		SSHAddr:              config.SSHAddr,
+		SSHHostKey:           config.SSHHostKey,
+		ShutdownTimeout:      config.ShutdownTimeout.String(),
+		ProxyProtocol:        config.ProxyProtocol,
+		ProxyProtocolTrusted: config.ProxyProtocolTrusted,
+		ProxyProtocolTimeout: config.ProxyProtocolTimeout.String(),
+		Multiplex:            config.Multiplex,
+		MultiplexTimeout:     config.MultiplexTimeout.String(),
+	}
+	if effective.Token != "" {
+		effective.Token = REDACTED
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	encoder.Encode(effective)
+	return buf.String()
+}
@@ -0,0 +1,171 @@
+package configParser_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	configParser "github.com/soulteary/ip-helper/model/parse-config"
+	"gopkg.in/yaml.v3"
+)
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("创建配置文件失败: %v", err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	expected := map[string]string{
+		"debug":           "true",
+		"port":            "9090",
+		"providers":       "mmdb:/a.mmdb,ipip:/b.ipdb",
+		"reload_interval": "1m",
+		"enable_ftp":      "false",
+	}
+
+	yamlPath := writeConfigFile(t, "config.yaml", `
+debug: true
+port: 9090
+providers:
+  - mmdb:/a.mmdb
+  - ipip:/b.ipdb
+reload_interval: 1m
+enable_ftp: false
+token:
+`)
+	tomlPath := writeConfigFile(t, "config.toml", `
+debug = true
+port = "9090"
+providers = ["mmdb:/a.mmdb", "ipip:/b.ipdb"]
+reload_interval = "1m"
+enable_ftp = false
+`)
+
+	for _, path := range []string{yamlPath, tomlPath} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			values, err := configParser.LoadFile(path)
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(values, expected) {
+				t.Errorf("LoadFile() = %v, want %v", values, expected)
+			}
+		})
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"Unknown keys", "config.yaml", "port: 8080\nprot: 8080\nextra: 1\n", "未知的配置项: extra, prot"},
+		{"Unknown TOML keys", "config.toml", "domian = \"a\"\n", "未知的配置项: domian"},
+		{"Object value", "config.yaml", "port:\n  value: 8080\n", "不能是对象"},
+		{"Invalid syntax", "config.toml", "port = \n", "解析配置文件"},
+		{"Unsupported format", "config.json", "{}", "不支持的配置文件格式"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := configParser.LoadFile(writeConfigFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadFile() 错误应该包含 %q，实际为 %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := configParser.LoadFile("/nonexistent.yaml"); err == nil {
+		t.Error("文件不存在时应该返回错误")
+	}
+}
+
+func TestParseConfigFilePrecedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+port: 7000
+domain: https://file.example.com
+token: file-token
+reload_interval: 2m
+enable_telnet: false
+`)
+
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+		os.Unsetenv("CONFIG_FILE")
+	}()
+
+	// 仅使用配置文件
+	os.Args = []string{"cmd", "-config", path}
+	config := configParser.Parse()
+	if config.Port != "7000" || config.Domain != "https://file.example.com" || config.Token != "file-token" {
+		t.Errorf("应该读取配置文件，实际为 %+v", config)
+	}
+	if config.ReloadInterval != 2*time.Minute || config.EnableTelnet {
+		t.Errorf("应该读取配置文件，实际为 %s %v", config.ReloadInterval, config.EnableTelnet)
+	}
+
+	// 环境变量覆盖配置文件，命令行参数覆盖环境变量
+	resetFlags()
+	os.Unsetenv("TOKEN")
+	os.Setenv("CONFIG_FILE", path)
+	os.Setenv("SERVER_PORT", "7070")
+	os.Setenv("SERVER_DOMAIN", "https://env.example.com")
+	os.Args = []string{"cmd", "-domain=https://flag.example.com"}
+	config = configParser.Parse()
+	if config.Port != "7070" {
+		t.Errorf("环境变量应该覆盖配置文件，实际为 %s", config.Port)
+	}
+	if config.Domain != "https://flag.example.com" {
+		t.Errorf("命令行参数应该覆盖环境变量，实际为 %s", config.Domain)
+	}
+	if config.Token != "file-token" {
+		t.Errorf("未覆盖的配置项应该使用配置文件，实际为 %s", config.Token)
+	}
+}
+
+func TestDump(t *testing.T) {
+	config := &define.Config{
+		Port:           "8080",
+		Domain:         "http://localhost:8080",
+		Token:          "secret-token",
+		DBPath:         "./data/ipipfree.ipdb",
+		Providers:      []string{"ipip:./data/ipipfree.ipdb"},
+		ReloadInterval: 30 * time.Second,
+		EnableWeb:      true,
+		TelnetAddr:     ":23",
+		FTPAddr:        ":21",
+	}
+
+	output := configParser.Dump(config)
+	if strings.Contains(output, "secret-token") || !strings.Contains(output, configParser.REDACTED) {
+		t.Errorf("Dump() 应该隐藏令牌，实际为:\n%s", output)
+	}
+
+	// 输出的内容可以作为配置文件再次加载
+	path := writeConfigFile(t, "dump.yaml", output)
+	values, err := configParser.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if values["reload_interval"] != "30s" || values["providers"] != "ipip:./data/ipipfree.ipdb" || values["enable_telnet"] != "false" {
+		t.Errorf("Dump() 输出内容不正确: %v", values)
+	}
+
+	var raw map[string]any
+	yaml.Unmarshal([]byte(configParser.Dump(&define.Config{})), &raw)
+	if raw["token"] != "" {
+		t.Errorf("空令牌不需要隐藏，实际为 %v", raw["token"])
+	}
+}
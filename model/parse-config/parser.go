@@ -4,19 +4,155 @@ import (
 	"flag"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
 )
 
+const (
+	DEFAULT_DB_PATH                = "./data/ipipfree.ipdb"
+	DEFAULT_RELOAD_INTERVAL        = 30 * time.Second
+	DEFAULT_SHUTDOWN_TIMEOUT       = 10 * time.Second
+	DEFAULT_PROXY_PROTOCOL_TIMEOUT = 5 * time.Second
+	DEFAULT_MULTIPLEX_TIMEOUT      = 300 * time.Millisecond
+)
+
+func splitList(s string) []string {
+	result := []string{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// parseBool 解析环境变量中的开关，无法识别时使用默认值
+func parseBool(value string, defaultValue bool) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1", "yes", "on":
+		return true
+	case "false", "0", "no", "off":
+		return false
+	}
+	return defaultValue
+}
+
+// parseDuration 解析环境变量中的时间间隔，格式错误时输出日志并使用默认值
+func parseDuration(env string, value string, defaultValue time.Duration) time.Duration {
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("%s 格式错误，使用默认值: %v\n", env, err)
+		return defaultValue
+	}
+	return duration
+}
+
+// parseInt 解析环境变量中的整数，格式错误时输出日志并使用默认值
+func parseInt(env string, value string, defaultValue int) int {
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		log.Printf("%s 格式错误，使用默认值: %v\n", env, err)
+		return defaultValue
+	}
+	return number
+}
+
+// normalizeAddr 允许只填写端口号，如 `2323` 会被转换为 `:2323`
+func normalizeAddr(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if addr != "" && !strings.Contains(addr, ":") {
+		return ":" + addr
+	}
+	return addr
+}
+
+// configFilePath 在解析命令行参数之前找到配置文件路径，命令行参数优先于环境变量
+func configFilePath(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv("CONFIG_FILE")
+}
+
 func Parse() *define.Config {
 	config := &define.Config{}
 
-	// 先读取环境变量
-	debug := strings.ToLower(os.Getenv("DEBUG"))
-	port := os.Getenv("SERVER_PORT")
-	domain := os.Getenv("SERVER_DOMAIN")
-	token := os.Getenv("TOKEN")
+	// 配置优先级：配置文件 < 环境变量 < 命令行参数
+	configFile := configFilePath(os.Args[1:])
+	fileValues := map[string]string{}
+	if configFile != "" {
+		values, err := LoadFile(configFile)
+		if err != nil {
+			log.Fatalf("加载配置文件失败: %v\n", err)
+		}
+		fileValues = values
+	}
+	lookup := func(key string, env string) string {
+		if value := os.Getenv(env); value != "" {
+			return value
+		}
+		return fileValues[key]
+	}
+
+	// 先读取环境变量和配置文件
+	debug := strings.ToLower(lookup("debug", "DEBUG"))
+	port := lookup("port", "SERVER_PORT")
+	domain := lookup("domain", "SERVER_DOMAIN")
+	token := lookup("token", "TOKEN")
+	trustedProxies := lookup("trusted_proxies", "TRUSTED_PROXIES")
+	clientIPHeaders := lookup("client_ip_headers", "CLIENT_IP_HEADERS")
+	downloadTools := lookup("download_tools", "DOWNLOAD_TOOLS")
+	templateDir := lookup("template_dir", "TEMPLATE_DIR")
+	assetsDir := lookup("assets_dir", "ASSETS_DIR")
+	batchMaxItems := lookup("batch_max_items", "BATCH_MAX_ITEMS")
+	dbPath := lookup("db", "DB_PATH")
+	providers := lookup("providers", "PROVIDERS")
+	reloadInterval := lookup("reload_interval", "RELOAD_INTERVAL")
+	telnetAddr := lookup("telnet_addr", "TELNET_ADDR")
+	ftpAddr := lookup("ftp_addr", "FTP_ADDR")
+	ftpPassiveAddr := lookup("ftp_passive_addr", "FTP_PASSIVE_ADDR")
+	ftpPassivePorts := lookup("ftp_passive_ports", "FTP_PASSIVE_PORTS")
+	dnsAddr := lookup("dns_addr", "DNS_ADDR")
+	dnsZone := lookup("dns_zone", "DNS_ZONE")
+	leakTestZone := lookup("leaktest_zone", "LEAKTEST_ZONE")
+	stunAddr := lookup("stun_addr", "STUN_ADDR")
+	stunSoftware := lookup("stun_software", "STUN_SOFTWARE")
+	whoisAddr := lookup("whois_addr", "WHOIS_ADDR")
+	smtpAddr := lookup("smtp_addr", "SMTP_ADDR")
+	pop3Addr := lookup("pop3_addr", "POP3_ADDR")
+	imapAddr := lookup("imap_addr", "IMAP_ADDR")
+	fingerAddr := lookup("finger_addr", "FINGER_ADDR")
+	sshAddr := lookup("ssh_addr", "SSH_ADDR")
+	sshHostKey := lookup("ssh_host_key", "SSH_HOST_KEY")
+	shutdownTimeout := lookup("shutdown_timeout", "SHUTDOWN_TIMEOUT")
+	proxyProtocolTrusted := lookup("proxy_protocol_trusted", "PROXY_PROTOCOL_TRUSTED")
+	proxyProtocolTimeout := lookup("proxy_protocol_timeout", "PROXY_PROTOCOL_TIMEOUT")
+	multiplexTimeout := lookup("multiplex_timeout", "MULTIPLEX_TIMEOUT")
 
 	// 设置命令行参数默认值，如果环境变量存在则使用环境变量值
 	defaultDebug := debug == "true"
@@ -29,14 +165,142 @@ func Parse() *define.Config {
 		defaultDomain = domain
 	}
 	defaultToken := token
+	defaultTrustedProxies := strings.Join(ipInfo.DEFAULT_TRUSTED_PROXIES, ",")
+	if trustedProxies != "" {
+		defaultTrustedProxies = trustedProxies
+	}
+	defaultClientIPHeaders := strings.Join(ipInfo.DEFAULT_CLIENT_IP_HEADERS, ",")
+	if clientIPHeaders != "" {
+		defaultClientIPHeaders = clientIPHeaders
+	}
+	defaultDownloadTools := strings.Join(fn.DEFAULT_DOWNLOAD_TOOLS, ",")
+	if downloadTools != "" {
+		defaultDownloadTools = downloadTools
+	}
+	defaultDBPath := DEFAULT_DB_PATH
+	if dbPath != "" {
+		defaultDBPath = dbPath
+	}
+	defaultReloadInterval := parseDuration("RELOAD_INTERVAL", reloadInterval, DEFAULT_RELOAD_INTERVAL)
+	defaultTelnetAddr := define.TELNET_PORT
+	if telnetAddr != "" {
+		defaultTelnetAddr = telnetAddr
+	}
+	defaultFTPAddr := define.FTP_PORT
+	if ftpAddr != "" {
+		defaultFTPAddr = ftpAddr
+	}
+	defaultDNSAddr := define.DNS_PORT
+	if dnsAddr != "" {
+		defaultDNSAddr = dnsAddr
+	}
+	defaultSTUNAddr := define.STUN_PORT
+	if stunAddr != "" {
+		defaultSTUNAddr = stunAddr
+	}
+	defaultSTUNSoftware := define.STUN_SOFTWARE
+	if stunSoftware != "" {
+		defaultSTUNSoftware = stunSoftware
+	}
+	defaultWhoisAddr := define.WHOIS_PORT
+	if whoisAddr != "" {
+		defaultWhoisAddr = whoisAddr
+	}
+	defaultSMTPAddr := define.SMTP_PORT
+	if smtpAddr != "" {
+		defaultSMTPAddr = smtpAddr
+	}
+	defaultPOP3Addr := define.POP3_PORT
+	if pop3Addr != "" {
+		defaultPOP3Addr = pop3Addr
+	}
+	defaultIMAPAddr := define.IMAP_PORT
+	if imapAddr != "" {
+		defaultIMAPAddr = imapAddr
+	}
+	defaultFingerAddr := define.FINGER_PORT
+	if fingerAddr != "" {
+		defaultFingerAddr = fingerAddr
+	}
+	defaultSSHAddr := define.SSH_PORT
+	if sshAddr != "" {
+		defaultSSHAddr = sshAddr
+	}
+	defaultBatchMaxItems := parseInt("BATCH_MAX_ITEMS", batchMaxItems, define.BATCH_MAX_ITEMS)
+	defaultShutdownTimeout := parseDuration("SHUTDOWN_TIMEOUT", shutdownTimeout, DEFAULT_SHUTDOWN_TIMEOUT)
+	defaultProxyProtocol := parseBool(lookup("proxy_protocol", "PROXY_PROTOCOL"), false)
+	defaultProxyProtocolTimeout := parseDuration("PROXY_PROTOCOL_TIMEOUT", proxyProtocolTimeout, DEFAULT_PROXY_PROTOCOL_TIMEOUT)
+	defaultMultiplex := parseBool(lookup("multiplex", "MULTIPLEX"), false)
+	defaultMultiplexTimeout := parseDuration("MULTIPLEX_TIMEOUT", multiplexTimeout, DEFAULT_MULTIPLEX_TIMEOUT)
+	defaultEnableWeb := parseBool(lookup("enable_web", "ENABLE_WEB"), true)
+	defaultEnableTelnet := parseBool(lookup("enable_telnet", "ENABLE_TELNET"), true)
+	defaultEnableFTP := parseBool(lookup("enable_ftp", "ENABLE_FTP"), true)
+	defaultEnableDNS := parseBool(lookup("enable_dns", "ENABLE_DNS"), false)
+	defaultEnableSTUN := parseBool(lookup("enable_stun", "ENABLE_STUN"), false)
+	defaultEnableWhois := parseBool(lookup("enable_whois", "ENABLE_WHOIS"), false)
+	defaultEnableSMTP := parseBool(lookup("enable_smtp", "ENABLE_SMTP"), false)
+	defaultEnablePOP3 := parseBool(lookup("enable_pop3", "ENABLE_POP3"), false)
+	defaultEnableIMAP := parseBool(lookup("enable_imap", "ENABLE_IMAP"), false)
+	defaultEnableFinger := parseBool(lookup("enable_finger", "ENABLE_FINGER"), false)
+	defaultEnableSSH := parseBool(lookup("enable_ssh", "ENABLE_SSH"), false)
 
 	// 解析命令行参数，会覆盖环境变量的值
+	flag.String("config", configFile, "配置文件路径，支持 YAML 和 TOML 格式")
+	flag.BoolVar(&config.PrintConfig, "print-config", false, "输出合并后的生效配置并退出")
 	flag.BoolVar(&config.Debug, "debug", defaultDebug, "调试模式")
 	flag.StringVar(&config.Port, "port", defaultPort, "服务器端口")
 	flag.StringVar(&config.Domain, "domain", defaultDomain, "服务器域名")
 	flag.StringVar(&config.Token, "token", defaultToken, "API 访问令牌")
+	trustedProxiesFlag := flag.String("trusted-proxies", defaultTrustedProxies, "受信任的反向代理 IP 或 CIDR，多个以逗号分隔，只有来自这些地址的 X-Forwarded-For 和 X-Real-IP 才会被采信")
+	downloadToolsFlag := flag.String("download-tools", defaultDownloadTools, "没有可识别的 Accept 请求头时，User-Agent 包含这些关键字的请求返回 JSON，多个以逗号分隔")
+	flag.StringVar(&config.TemplateDir, "template-dir", templateDir, "自定义页面模板和静态资源的目录，调试模式下修改后自动重新加载")
+	flag.StringVar(&config.AssetsDir, "assets-dir", assetsDir, "静态资源目录，其中的文件优先于内置的静态资源")
+	flag.IntVar(&config.BatchMaxItems, "batch-max-items", defaultBatchMaxItems, "批量查询接口单次最多查询的 IP 数量，流式接口不受限制")
+	clientIPHeadersFlag := flag.String("client-ip-headers", defaultClientIPHeaders, "按顺序读取客户端 IP 的请求头，多个以逗号分隔，例如 CF-Connecting-IP,X-Forwarded-For")
+	flag.StringVar(&config.DBPath, "db", defaultDBPath, "IPIP 数据库文件路径，设置 providers 时不生效")
+	providersFlag := flag.String("providers", providers, "IP 数据库列表，格式为 `类型:路径`，多个以逗号分隔，靠前的优先")
+	flag.DurationVar(&config.ReloadInterval, "reload-interval", defaultReloadInterval, "检查 IP 数据库文件更新的间隔，为 0 时仅在收到 SIGHUP 信号时更新")
+	flag.BoolVar(&config.EnableWeb, "enable-web", defaultEnableWeb, "启用 WEB 服务")
+	flag.BoolVar(&config.EnableTelnet, "enable-telnet", defaultEnableTelnet, "启用 TELNET 服务")
+	flag.BoolVar(&config.EnableFTP, "enable-ftp", defaultEnableFTP, "启用 FTP 服务")
+	flag.BoolVar(&config.EnableDNS, "enable-dns", defaultEnableDNS, "启用 DNS 服务")
+	flag.BoolVar(&config.EnableSTUN, "enable-stun", defaultEnableSTUN, "启用 STUN 服务")
+	flag.BoolVar(&config.EnableWhois, "enable-whois", defaultEnableWhois, "启用 WHOIS 服务")
+	flag.BoolVar(&config.EnableSMTP, "enable-smtp", defaultEnableSMTP, "启用 SMTP 服务")
+	flag.BoolVar(&config.EnablePOP3, "enable-pop3", defaultEnablePOP3, "启用 POP3 服务")
+	flag.BoolVar(&config.EnableIMAP, "enable-imap", defaultEnableIMAP, "启用 IMAP 服务")
+	flag.BoolVar(&config.EnableFinger, "enable-finger", defaultEnableFinger, "启用 FINGER 服务")
+	flag.BoolVar(&config.EnableSSH, "enable-ssh", defaultEnableSSH, "启用 SSH 服务")
+	flag.StringVar(&config.TelnetAddr, "telnet-addr", defaultTelnetAddr, "TELNET 服务监听地址")
+	flag.StringVar(&config.FTPAddr, "ftp-addr", defaultFTPAddr, "FTP 服务监听地址")
+	flag.StringVar(&config.FTPPassiveAddr, "ftp-passive-addr", ftpPassiveAddr, "FTP 被动模式告知客户端的公网地址，部署在 NAT 或负载均衡之后时需要设置，默认为控制连接的本地地址")
+	flag.StringVar(&config.FTPPassivePorts, "ftp-passive-ports", ftpPassivePorts, "FTP 被动模式数据连接的端口范围，格式为 `起始-结束`，默认由系统分配")
+	flag.StringVar(&config.DNSAddr, "dns-addr", defaultDNSAddr, "DNS 服务监听地址，同时监听 UDP 和 TCP")
+	flag.StringVar(&config.DNSZone, "dns-zone", dnsZone, "DNS 服务应答的域名，默认为 `myip.<服务域名>`")
+	flag.StringVar(&config.STUNAddr, "stun-addr", defaultSTUNAddr, "STUN 服务监听地址，同时监听 UDP 和 TCP")
+	flag.StringVar(&config.STUNSoftware, "stun-software", defaultSTUNSoftware, "STUN 响应中 SOFTWARE 属性的内容，设置为空字符串时不发送")
+	flag.StringVar(&config.WhoisAddr, "whois-addr", defaultWhoisAddr, "WHOIS 服务监听地址")
+	flag.StringVar(&config.SMTPAddr, "smtp-addr", defaultSMTPAddr, "SMTP 服务监听地址")
+	flag.StringVar(&config.POP3Addr, "pop3-addr", defaultPOP3Addr, "POP3 服务监听地址")
+	flag.StringVar(&config.IMAPAddr, "imap-addr", defaultIMAPAddr, "IMAP 服务监听地址")
+	flag.StringVar(&config.FingerAddr, "finger-addr", defaultFingerAddr, "FINGER 服务监听地址")
+	flag.StringVar(&config.SSHAddr, "ssh-addr", defaultSSHAddr, "SSH 服务监听地址")
+	flag.StringVar(&config.SSHHostKey, "ssh-host-key", sshHostKey, "SSH 主机密钥路径，文件不存在时自动生成，为空时每次启动使用临时密钥")
+	flag.StringVar(&config.LeakTestZone, "leaktest-zone", leakTestZone, "DNS 泄露测试使用的域名，默认为 `leak.<服务域名>`")
+	flag.DurationVar(&config.ShutdownTimeout, "shutdown-timeout", defaultShutdownTimeout, "退出时等待进行中的连接处理完成的最长时间")
+	flag.BoolVar(&config.ProxyProtocol, "proxy-protocol", defaultProxyProtocol, "在所有监听地址上解析 PROXY protocol v1/v2 头部")
+	proxyProtocolTrustedFlag := flag.String("proxy-protocol-trusted", proxyProtocolTrusted, "允许发送 PROXY protocol 头部的负载均衡 IP 或 CIDR，多个以逗号分隔")
+	flag.DurationVar(&config.ProxyProtocolTimeout, "proxy-protocol-timeout", defaultProxyProtocolTimeout, "读取 PROXY protocol 头部的超时时间")
+	flag.BoolVar(&config.Multiplex, "multiplex", defaultMultiplex, "在 WEB 端口上同时提供已开启的 TELNET 和 FTP 服务")
+	flag.DurationVar(&config.MultiplexTimeout, "multiplex-timeout", defaultMultiplexTimeout, "WEB 端口等待客户端发送数据的时间，超时后按 FTP 或 TELNET 客户端处理")
 	flag.Parse()
 
+	config.Providers = splitList(*providersFlag)
+	config.TrustedProxies = splitList(*trustedProxiesFlag)
+	config.ClientIPHeaders = splitList(*clientIPHeadersFlag)
+	config.DownloadTools = splitList(*downloadToolsFlag)
+	config.ProxyProtocolTrusted = splitList(*proxyProtocolTrustedFlag)
+
 	// 处理特殊的空值情况
 	if config.Port == "" {
 		config.Port = "8080"
@@ -44,6 +308,58 @@ func Parse() *define.Config {
 	if config.Domain == "" {
 		config.Domain = "http://localhost:8080"
 	}
+	if config.DBPath == "" {
+		config.DBPath = DEFAULT_DB_PATH
+	}
+	if len(config.Providers) == 0 {
+		config.Providers = []string{"ipip:" + config.DBPath}
+	}
+	if config.TelnetAddr == "" {
+		config.TelnetAddr = define.TELNET_PORT
+	}
+	if config.FTPAddr == "" {
+		config.FTPAddr = define.FTP_PORT
+	}
+	if config.DNSAddr == "" {
+		config.DNSAddr = define.DNS_PORT
+	}
+	if config.DNSZone == "" {
+		config.DNSZone = "myip." + fn.GetDomainOnly(config.Domain)
+	}
+	if config.STUNAddr == "" {
+		config.STUNAddr = define.STUN_PORT
+	}
+	if config.WhoisAddr == "" {
+		config.WhoisAddr = define.WHOIS_PORT
+	}
+	if config.SMTPAddr == "" {
+		config.SMTPAddr = define.SMTP_PORT
+	}
+	if config.POP3Addr == "" {
+		config.POP3Addr = define.POP3_PORT
+	}
+	if config.IMAPAddr == "" {
+		config.IMAPAddr = define.IMAP_PORT
+	}
+	if config.FingerAddr == "" {
+		config.FingerAddr = define.FINGER_PORT
+	}
+	if config.SSHAddr == "" {
+		config.SSHAddr = define.SSH_PORT
+	}
+	if config.LeakTestZone == "" {
+		config.LeakTestZone = "leak." + fn.GetDomainOnly(config.Domain)
+	}
+	config.TelnetAddr = normalizeAddr(config.TelnetAddr)
+	config.FTPAddr = normalizeAddr(config.FTPAddr)
+	config.DNSAddr = normalizeAddr(config.DNSAddr)
+	config.STUNAddr = normalizeAddr(config.STUNAddr)
+	config.WhoisAddr = normalizeAddr(config.WhoisAddr)
+	config.SMTPAddr = normalizeAddr(config.SMTPAddr)
+	config.POP3Addr = normalizeAddr(config.POP3Addr)
+	config.IMAPAddr = normalizeAddr(config.IMAPAddr)
+	config.FingerAddr = normalizeAddr(config.FingerAddr)
+	config.SSHAddr = normalizeAddr(config.SSHAddr)
 
 	// 输出相关日志
 	if config.Debug {
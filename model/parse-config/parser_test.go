@@ -5,9 +5,12 @@ import (
 	"flag"
 	"log"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/soulteary/ip-helper/model/fn"
 	configParser "github.com/soulteary/ip-helper/model/parse-config"
 )
 
@@ -30,6 +33,48 @@ func clearEnv() {
 	os.Unsetenv("SERVER_PORT")
 	os.Unsetenv("SERVER_DOMAIN")
 	os.Unsetenv("TOKEN")
+	os.Unsetenv("TRUSTED_PROXIES")
+	os.Unsetenv("CLIENT_IP_HEADERS")
+	os.Unsetenv("DOWNLOAD_TOOLS")
+	os.Unsetenv("TEMPLATE_DIR")
+	os.Unsetenv("FTP_PASSIVE_ADDR")
+	os.Unsetenv("FTP_PASSIVE_PORTS")
+	os.Unsetenv("ASSETS_DIR")
+	os.Unsetenv("BATCH_MAX_ITEMS")
+	os.Unsetenv("PROVIDERS")
+	os.Unsetenv("RELOAD_INTERVAL")
+	os.Unsetenv("SHUTDOWN_TIMEOUT")
+	os.Unsetenv("PROXY_PROTOCOL")
+	os.Unsetenv("PROXY_PROTOCOL_TRUSTED")
+	os.Unsetenv("PROXY_PROTOCOL_TIMEOUT")
+	os.Unsetenv("MULTIPLEX")
+	os.Unsetenv("MULTIPLEX_TIMEOUT")
+	os.Unsetenv("DB_PATH")
+	os.Unsetenv("TELNET_ADDR")
+	os.Unsetenv("FTP_ADDR")
+	os.Unsetenv("ENABLE_WEB")
+	os.Unsetenv("ENABLE_TELNET")
+	os.Unsetenv("ENABLE_FTP")
+	os.Unsetenv("ENABLE_DNS")
+	os.Unsetenv("DNS_ADDR")
+	os.Unsetenv("DNS_ZONE")
+	os.Unsetenv("LEAKTEST_ZONE")
+	os.Unsetenv("ENABLE_STUN")
+	os.Unsetenv("STUN_ADDR")
+	os.Unsetenv("STUN_SOFTWARE")
+	os.Unsetenv("ENABLE_WHOIS")
+	os.Unsetenv("WHOIS_ADDR")
+	os.Unsetenv("ENABLE_SMTP")
+	os.Unsetenv("SMTP_ADDR")
+	os.Unsetenv("ENABLE_POP3")
+	os.Unsetenv("POP3_ADDR")
+	os.Unsetenv("ENABLE_IMAP")
+	os.Unsetenv("IMAP_ADDR")
+	os.Unsetenv("ENABLE_FINGER")
+	os.Unsetenv("FINGER_ADDR")
+	os.Unsetenv("ENABLE_SSH")
+	os.Unsetenv("SSH_ADDR")
+	os.Unsetenv("SSH_HOST_KEY")
 }
 
 func captureLog(f func()) string {
@@ -285,3 +330,510 @@ func TestEmptyEnvironmentVars(t *testing.T) {
 		t.Errorf("空环境变量 TOKEN 应该为空字符串，实际为 %s", config.Token)
 	}
 }
+
+func TestParseProviders(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if !reflect.DeepEqual(config.Providers, []string{"ipip:./data/ipipfree.ipdb"}) {
+		t.Errorf("Providers 默认值不正确，实际为 %v", config.Providers)
+	}
+
+	resetFlags()
+	os.Setenv("PROVIDERS", "mmdb:./data/city.mmdb, ipip:./data/ipipfree.ipdb")
+	config = configParser.Parse()
+	if !reflect.DeepEqual(config.Providers, []string{"mmdb:./data/city.mmdb", "ipip:./data/ipipfree.ipdb"}) {
+		t.Errorf("Providers 应该读取环境变量，实际为 %v", config.Providers)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-providers=csv:./data/ranges.csv,,"}
+	config = configParser.Parse()
+	if !reflect.DeepEqual(config.Providers, []string{"csv:./data/ranges.csv"}) {
+		t.Errorf("命令行参数应该覆盖环境变量，实际为 %v", config.Providers)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-providers="}
+	config = configParser.Parse()
+	if !reflect.DeepEqual(config.Providers, []string{"ipip:./data/ipipfree.ipdb"}) {
+		t.Errorf("空 Providers 应该使用默认值，实际为 %v", config.Providers)
+	}
+}
+
+func TestParseReloadInterval(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.ReloadInterval != 30*time.Second {
+		t.Errorf("ReloadInterval 默认值应该为 30s，实际为 %s", config.ReloadInterval)
+	}
+
+	resetFlags()
+	os.Setenv("RELOAD_INTERVAL", "5m")
+	config = configParser.Parse()
+	if config.ReloadInterval != 5*time.Minute {
+		t.Errorf("ReloadInterval 应该读取环境变量，实际为 %s", config.ReloadInterval)
+	}
+
+	resetFlags()
+	os.Setenv("RELOAD_INTERVAL", "invalid")
+	output := captureLog(func() {
+		config = configParser.Parse()
+	})
+	if config.ReloadInterval != 30*time.Second || !strings.Contains(output, "RELOAD_INTERVAL") {
+		t.Errorf("无效的环境变量应该使用默认值并输出日志，实际为 %s", config.ReloadInterval)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-reload-interval=0"}
+	config = configParser.Parse()
+	if config.ReloadInterval != 0 {
+		t.Errorf("命令行参数应该覆盖环境变量，实际为 %s", config.ReloadInterval)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if !reflect.DeepEqual(config.TrustedProxies, []string{"127.0.0.0/8", "::1"}) {
+		t.Errorf("TrustedProxies 默认应该只信任本机，实际为 %v", config.TrustedProxies)
+	}
+	if !reflect.DeepEqual(config.ClientIPHeaders, []string{"X-Forwarded-For", "X-Real-IP"}) {
+		t.Errorf("ClientIPHeaders 默认值错误，实际为 %v", config.ClientIPHeaders)
+	}
+
+	resetFlags()
+	os.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 172.16.0.1")
+	config = configParser.Parse()
+	if !reflect.DeepEqual(config.TrustedProxies, []string{"10.0.0.0/8", "172.16.0.1"}) {
+		t.Errorf("TrustedProxies 应该读取环境变量，实际为 %v", config.TrustedProxies)
+	}
+
+	resetFlags()
+	os.Setenv("CLIENT_IP_HEADERS", "CF-Connecting-IP, X-Forwarded-For")
+	config = configParser.Parse()
+	if !reflect.DeepEqual(config.ClientIPHeaders, []string{"CF-Connecting-IP", "X-Forwarded-For"}) {
+		t.Errorf("ClientIPHeaders 应该读取环境变量，实际为 %v", config.ClientIPHeaders)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-trusted-proxies="}
+	config = configParser.Parse()
+	if len(config.TrustedProxies) != 0 {
+		t.Errorf("命令行参数为空时应该不信任任何代理，实际为 %v", config.TrustedProxies)
+	}
+}
+
+func TestParseProxyProtocol(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.ProxyProtocol || config.ProxyProtocolTimeout != 5*time.Second || len(config.ProxyProtocolTrusted) != 0 {
+		t.Errorf("PROXY protocol 默认应该关闭，实际为 %v %s %v", config.ProxyProtocol, config.ProxyProtocolTimeout, config.ProxyProtocolTrusted)
+	}
+
+	resetFlags()
+	os.Setenv("PROXY_PROTOCOL", "true")
+	os.Setenv("PROXY_PROTOCOL_TRUSTED", "10.0.0.0/8,192.0.2.1")
+	os.Setenv("PROXY_PROTOCOL_TIMEOUT", "2s")
+	config = configParser.Parse()
+	if !config.ProxyProtocol || config.ProxyProtocolTimeout != 2*time.Second || !reflect.DeepEqual(config.ProxyProtocolTrusted, []string{"10.0.0.0/8", "192.0.2.1"}) {
+		t.Errorf("PROXY protocol 应该读取环境变量，实际为 %v %s %v", config.ProxyProtocol, config.ProxyProtocolTimeout, config.ProxyProtocolTrusted)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-proxy-protocol=false"}
+	config = configParser.Parse()
+	if config.ProxyProtocol {
+		t.Error("命令行参数应该覆盖环境变量")
+	}
+}
+
+func TestParseShutdownTimeout(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.ShutdownTimeout != 10*time.Second {
+		t.Errorf("ShutdownTimeout 默认值应该为 10s，实际为 %s", config.ShutdownTimeout)
+	}
+
+	resetFlags()
+	os.Setenv("SHUTDOWN_TIMEOUT", "30s")
+	config = configParser.Parse()
+	if config.ShutdownTimeout != 30*time.Second {
+		t.Errorf("ShutdownTimeout 应该读取环境变量，实际为 %s", config.ShutdownTimeout)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-shutdown-timeout=1m"}
+	config = configParser.Parse()
+	if config.ShutdownTimeout != time.Minute {
+		t.Errorf("命令行参数应该覆盖环境变量，实际为 %s", config.ShutdownTimeout)
+	}
+}
+
+func TestParseListeners(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if !config.EnableWeb || !config.EnableTelnet || !config.EnableFTP {
+		t.Error("所有协议默认应该启用")
+	}
+	if config.TelnetAddr != ":23" || config.FTPAddr != ":21" {
+		t.Errorf("监听地址默认值不正确，实际为 %s %s", config.TelnetAddr, config.FTPAddr)
+	}
+	if config.DBPath != "./data/ipipfree.ipdb" {
+		t.Errorf("DBPath 默认值不正确，实际为 %s", config.DBPath)
+	}
+
+	resetFlags()
+	os.Setenv("DB_PATH", "/srv/ipip.ipdb")
+	os.Setenv("TELNET_ADDR", "2323")
+	os.Setenv("FTP_ADDR", "127.0.0.1:2121")
+	os.Setenv("ENABLE_FTP", "off")
+	os.Setenv("ENABLE_WEB", "invalid")
+	config = configParser.Parse()
+	if config.TelnetAddr != ":2323" || config.FTPAddr != "127.0.0.1:2121" {
+		t.Errorf("监听地址应该读取环境变量，实际为 %s %s", config.TelnetAddr, config.FTPAddr)
+	}
+	if !config.EnableWeb || !config.EnableTelnet || config.EnableFTP {
+		t.Errorf("协议开关应该读取环境变量，实际为 %v %v %v", config.EnableWeb, config.EnableTelnet, config.EnableFTP)
+	}
+	if !reflect.DeepEqual(config.Providers, []string{"ipip:/srv/ipip.ipdb"}) {
+		t.Errorf("未设置 Providers 时应该使用 DBPath，实际为 %v", config.Providers)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-enable-ftp=true", "-enable-telnet=false", "-telnet-addr=:2424", "-db=/tmp/a.ipdb", "-providers=mmdb:/tmp/b.mmdb"}
+	config = configParser.Parse()
+	if !config.EnableFTP || config.EnableTelnet || config.TelnetAddr != ":2424" {
+		t.Errorf("命令行参数应该覆盖环境变量，实际为 %v %v %s", config.EnableFTP, config.EnableTelnet, config.TelnetAddr)
+	}
+	if config.DBPath != "/tmp/a.ipdb" || !reflect.DeepEqual(config.Providers, []string{"mmdb:/tmp/b.mmdb"}) {
+		t.Errorf("设置 Providers 时应该优先使用，实际为 %s %v", config.DBPath, config.Providers)
+	}
+}
+
+func TestParseMultiplex(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.Multiplex || config.MultiplexTimeout != 300*time.Millisecond {
+		t.Errorf("端口复用默认应该关闭，实际为 %v %s", config.Multiplex, config.MultiplexTimeout)
+	}
+
+	resetFlags()
+	os.Setenv("MULTIPLEX", "true")
+	os.Setenv("MULTIPLEX_TIMEOUT", "1s")
+	config = configParser.Parse()
+	if !config.Multiplex || config.MultiplexTimeout != time.Second {
+		t.Errorf("端口复用应该读取环境变量，实际为 %v %s", config.Multiplex, config.MultiplexTimeout)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-multiplex=false"}
+	config = configParser.Parse()
+	if config.Multiplex {
+		t.Error("命令行参数应该覆盖环境变量")
+	}
+}
+
+func TestParseFTPPassive(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.FTPPassiveAddr != "" || config.FTPPassivePorts != "" {
+		t.Errorf("被动模式默认不应该设置地址和端口范围，实际为 %q %q", config.FTPPassiveAddr, config.FTPPassivePorts)
+	}
+
+	resetFlags()
+	os.Setenv("FTP_PASSIVE_ADDR", "203.0.113.10")
+	os.Setenv("FTP_PASSIVE_PORTS", "30000-30100")
+	config = configParser.Parse()
+	if config.FTPPassiveAddr != "203.0.113.10" || config.FTPPassivePorts != "30000-30100" {
+		t.Errorf("被动模式应该读取环境变量，实际为 %q %q", config.FTPPassiveAddr, config.FTPPassivePorts)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-ftp-passive-ports=40000-40010"}
+	config = configParser.Parse()
+	if config.FTPPassivePorts != "40000-40010" {
+		t.Error("命令行参数应该覆盖环境变量")
+	}
+}
+
+func TestParseDNS(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd", "-domain", "https://ip.example.com"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.EnableDNS || config.DNSAddr != ":53" || config.DNSZone != "myip.ip.example.com" || config.LeakTestZone != "leak.ip.example.com" {
+		t.Errorf("DNS 服务默认值错误，实际为 %v %s %s %s", config.EnableDNS, config.DNSAddr, config.DNSZone, config.LeakTestZone)
+	}
+
+	resetFlags()
+	os.Setenv("ENABLE_DNS", "true")
+	os.Setenv("DNS_ADDR", "5353")
+	os.Setenv("DNS_ZONE", "whoami.example.net")
+	config = configParser.Parse()
+	if !config.EnableDNS || config.DNSAddr != ":5353" || config.DNSZone != "whoami.example.net" {
+		t.Errorf("DNS 服务应该读取环境变量，实际为 %v %s %s", config.EnableDNS, config.DNSAddr, config.DNSZone)
+	}
+}
+
+func TestParseSTUN(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.EnableSTUN || config.STUNAddr != ":3478" || config.STUNSoftware != "ip-helper" {
+		t.Errorf("STUN 服务默认值错误，实际为 %v %s %s", config.EnableSTUN, config.STUNAddr, config.STUNSoftware)
+	}
+
+	resetFlags()
+	os.Setenv("ENABLE_STUN", "true")
+	os.Setenv("STUN_ADDR", "3479")
+	os.Args = []string{"cmd", "-stun-software="}
+	config = configParser.Parse()
+	if !config.EnableSTUN || config.STUNAddr != ":3479" || config.STUNSoftware != "" {
+		t.Errorf("STUN 服务应该读取环境变量和命令行参数，实际为 %v %s %q", config.EnableSTUN, config.STUNAddr, config.STUNSoftware)
+	}
+}
+
+func TestParseWhois(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.EnableWhois || config.WhoisAddr != ":43" {
+		t.Errorf("WHOIS 服务默认值错误，实际为 %v %s", config.EnableWhois, config.WhoisAddr)
+	}
+
+	resetFlags()
+	os.Setenv("ENABLE_WHOIS", "true")
+	os.Setenv("WHOIS_ADDR", "4343")
+	config = configParser.Parse()
+	if !config.EnableWhois || config.WhoisAddr != ":4343" {
+		t.Errorf("WHOIS 服务应该读取环境变量，实际为 %v %s", config.EnableWhois, config.WhoisAddr)
+	}
+}
+
+func TestParseBanner(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.EnableSMTP || config.EnablePOP3 || config.EnableIMAP || config.EnableFinger {
+		t.Error("SMTP、POP3、IMAP、FINGER 服务默认应该关闭")
+	}
+	if config.SMTPAddr != ":25" || config.POP3Addr != ":110" || config.IMAPAddr != ":143" || config.FingerAddr != ":79" {
+		t.Errorf("监听地址默认值错误，实际为 %s %s %s %s", config.SMTPAddr, config.POP3Addr, config.IMAPAddr, config.FingerAddr)
+	}
+
+	resetFlags()
+	os.Setenv("ENABLE_SMTP", "true")
+	os.Setenv("SMTP_ADDR", "2525")
+	os.Args = []string{"cmd", "-enable-finger", "-finger-addr=127.0.0.1:7979"}
+	config = configParser.Parse()
+	if !config.EnableSMTP || config.SMTPAddr != ":2525" || !config.EnableFinger || config.FingerAddr != "127.0.0.1:7979" {
+		t.Errorf("应该读取环境变量和命令行参数，实际为 %v %s %v %s", config.EnableSMTP, config.SMTPAddr, config.EnableFinger, config.FingerAddr)
+	}
+}
+
+func TestParseSSH(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.EnableSSH || config.SSHAddr != ":22" || config.SSHHostKey != "" {
+		t.Errorf("SSH 服务默认值错误，实际为 %v %s %q", config.EnableSSH, config.SSHAddr, config.SSHHostKey)
+	}
+
+	resetFlags()
+	os.Setenv("ENABLE_SSH", "true")
+	os.Setenv("SSH_ADDR", "2222")
+	os.Setenv("SSH_HOST_KEY", "/data/ssh_host_ed25519_key")
+	config = configParser.Parse()
+	if !config.EnableSSH || config.SSHAddr != ":2222" || config.SSHHostKey != "/data/ssh_host_ed25519_key" {
+		t.Errorf("SSH 服务应该读取环境变量，实际为 %v %s %q", config.EnableSSH, config.SSHAddr, config.SSHHostKey)
+	}
+}
+
+func TestParseDownloadTools(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if !reflect.DeepEqual(config.DownloadTools, fn.DEFAULT_DOWNLOAD_TOOLS) {
+		t.Errorf("默认应该使用内置的关键字列表，实际为 %v", config.DownloadTools)
+	}
+
+	resetFlags()
+	os.Setenv("DOWNLOAD_TOOLS", "curl, MyScript")
+	config = configParser.Parse()
+	if !reflect.DeepEqual(config.DownloadTools, []string{"curl", "MyScript"}) {
+		t.Errorf("应该读取环境变量中的关键字列表，实际为 %v", config.DownloadTools)
+	}
+}
+
+func TestParseTemplateAndAssetsDir(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.TemplateDir != "" || config.AssetsDir != "" {
+		t.Errorf("默认不应该设置模板目录和静态资源目录，实际为 %q %q", config.TemplateDir, config.AssetsDir)
+	}
+
+	resetFlags()
+	os.Setenv("TEMPLATE_DIR", "/data/templates")
+	os.Setenv("ASSETS_DIR", "/data/public")
+	config = configParser.Parse()
+	if config.TemplateDir != "/data/templates" || config.AssetsDir != "/data/public" {
+		t.Errorf("应该读取环境变量中的目录，实际为 %q %q", config.TemplateDir, config.AssetsDir)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-template-dir", "./templates"}
+	config = configParser.Parse()
+	if config.TemplateDir != "./templates" {
+		t.Errorf("命令行参数应该覆盖环境变量，实际为 %q", config.TemplateDir)
+	}
+}
+
+func TestParseBatchMaxItems(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"cmd"}
+
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+		clearEnv()
+	}()
+
+	config := configParser.Parse()
+	if config.BatchMaxItems != 1000 {
+		t.Errorf("批量查询数量上限默认值应该为 1000，实际为 %d", config.BatchMaxItems)
+	}
+
+	resetFlags()
+	os.Setenv("BATCH_MAX_ITEMS", "5000")
+	config = configParser.Parse()
+	if config.BatchMaxItems != 5000 {
+		t.Errorf("应该读取环境变量中的数量上限，实际为 %d", config.BatchMaxItems)
+	}
+
+	resetFlags()
+	os.Setenv("BATCH_MAX_ITEMS", "many")
+	config = configParser.Parse()
+	if config.BatchMaxItems != 1000 {
+		t.Errorf("格式错误时应该使用默认值，实际为 %d", config.BatchMaxItems)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-batch-max-items", "10"}
+	config = configParser.Parse()
+	if config.BatchMaxItems != 10 {
+		t.Errorf("命令行参数应该覆盖环境变量，实际为 %d", config.BatchMaxItems)
+	}
+}
@@ -0,0 +1,177 @@
+package configParser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/ftp"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+// Validate 在启动前校验配置，返回所有发现的问题
+func Validate(config *define.Config) error {
+	errs := []error{}
+
+	if !config.EnableWeb && !config.EnableTelnet && !config.EnableFTP && !config.EnableDNS && !config.EnableSTUN && !config.EnableWhois &&
+		!config.EnableSMTP && !config.EnablePOP3 && !config.EnableIMAP && !config.EnableFinger && !config.EnableSSH {
+		errs = append(errs, fmt.Errorf("至少需要启用 WEB、TELNET、FTP、DNS、STUN、WHOIS、SMTP、POP3、IMAP、FINGER、SSH 中的一种服务"))
+	}
+
+	listeners := []struct {
+		name    string
+		enabled bool
+		addr    string
+	}{
+		{"WEB", config.EnableWeb, ":" + config.Port},
+		{"TELNET", config.EnableTelnet, config.TelnetAddr},
+		{"FTP", config.EnableFTP, config.FTPAddr},
+		{"DNS", config.EnableDNS, config.DNSAddr},
+		{"STUN", config.EnableSTUN, config.STUNAddr},
+		{"WHOIS", config.EnableWhois, config.WhoisAddr},
+		{"SMTP", config.EnableSMTP, config.SMTPAddr},
+		{"POP3", config.EnablePOP3, config.POP3Addr},
+		{"IMAP", config.EnableIMAP, config.IMAPAddr},
+		{"FINGER", config.EnableFinger, config.FingerAddr},
+		{"SSH", config.EnableSSH, config.SSHAddr},
+	}
+	type binding struct{ name, host, port string }
+	bindings := []binding{}
+	for _, listener := range listeners {
+		if !listener.enabled {
+			continue
+		}
+		host, port, err := splitAddr(listener.addr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s 服务监听地址 `%s` 无效: %v", listener.name, listener.addr, err))
+			continue
+		}
+		// 监听所有地址时，与任意地址上的同一端口冲突
+		for _, other := range bindings {
+			if other.port == port && (other.host == "" || host == "" || other.host == host) {
+				errs = append(errs, fmt.Errorf("%s 服务与 %s 服务的监听地址冲突: %s", listener.name, other.name, listener.addr))
+				break
+			}
+		}
+		bindings = append(bindings, binding{listener.name, host, port})
+	}
+
+	if len(config.Providers) == 0 {
+		errs = append(errs, fmt.Errorf("没有配置 IP 数据库"))
+	}
+	for _, spec := range config.Providers {
+		_, source, found := strings.Cut(spec, ":")
+		if !found || source == "" {
+			errs = append(errs, fmt.Errorf("IP 数据库配置 `%s` 格式错误，应为 `类型:路径`", spec))
+			continue
+		}
+		if _, err := os.Stat(source); err != nil {
+			errs = append(errs, fmt.Errorf("IP 数据库文件 `%s` 无法读取: %v", source, err))
+		}
+	}
+
+	if _, err := ipInfo.ParseTrustedProxies(config.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("受信任代理配置错误: %v", err))
+	}
+
+	if config.ProxyProtocol {
+		if len(config.ProxyProtocolTrusted) == 0 {
+			errs = append(errs, fmt.Errorf("启用 PROXY protocol 时需要设置允许发送头部的地址"))
+		} else if _, err := ipInfo.ParseTrustedProxies(config.ProxyProtocolTrusted); err != nil {
+			errs = append(errs, fmt.Errorf("PROXY protocol 可信地址配置错误: %v", err))
+		}
+		if config.ProxyProtocolTimeout <= 0 {
+			errs = append(errs, fmt.Errorf("PROXY protocol 头部超时时间必须大于 0: %s", config.ProxyProtocolTimeout))
+		}
+	}
+
+	if config.EnableWeb && config.Multiplex && config.MultiplexTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("WEB 端口协议识别的等待时间必须大于 0: %s", config.MultiplexTimeout))
+	}
+
+	if config.EnableFTP {
+		if _, err := ftp.ParsePassive(config.FTPPassiveAddr, config.FTPPassivePorts); err != nil {
+			errs = append(errs, fmt.Errorf("FTP 被动模式配置错误: %v", err))
+		}
+	}
+
+	if config.EnableDNS {
+		if !validZone(config.DNSZone) {
+			errs = append(errs, fmt.Errorf("DNS 服务域名 `%s` 无效", config.DNSZone))
+		}
+		// 会话令牌与序号占用两级标签，整个域名不能超过 253 个字符
+		if !validZone(config.LeakTestZone) || len(strings.TrimSuffix(config.LeakTestZone, "."))+40 > 253 {
+			errs = append(errs, fmt.Errorf("DNS 泄露测试域名 `%s` 无效", config.LeakTestZone))
+		}
+	}
+
+	if config.EnableSTUN && len(config.STUNSoftware) > 763 {
+		errs = append(errs, fmt.Errorf("STUN 服务的 SOFTWARE 属性不能超过 763 字节"))
+	}
+
+	for _, dir := range []struct{ name, path string }{
+		{"模板目录", config.TemplateDir},
+		{"静态资源目录", config.AssetsDir},
+	} {
+		if dir.path == "" {
+			continue
+		}
+		if info, err := os.Stat(dir.path); err != nil {
+			errs = append(errs, fmt.Errorf("%s `%s` 无法读取: %v", dir.name, dir.path, err))
+		} else if !info.IsDir() {
+			errs = append(errs, fmt.Errorf("%s `%s` 不是目录", dir.name, dir.path))
+		}
+	}
+
+	if config.EnableWeb && config.BatchMaxItems < 1 {
+		errs = append(errs, fmt.Errorf("批量查询的数量上限必须大于 0: %d", config.BatchMaxItems))
+	}
+
+	if config.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("数据库更新检查间隔不能为负数: %s", config.ReloadInterval))
+	}
+	if config.ShutdownTimeout < 0 {
+		errs = append(errs, fmt.Errorf("退出等待时间不能为负数: %s", config.ShutdownTimeout))
+	}
+
+	return errors.Join(errs...)
+}
+
+// splitAddr 拆分监听地址，并将 `0.0.0.0` 与 `::` 视为监听所有地址
+func splitAddr(addr string) (string, string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", err
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil || number < 1 || number > 65535 {
+		return "", "", fmt.Errorf("端口必须是 1-65535 之间的数字")
+	}
+	if host == "0.0.0.0" || host == "::" {
+		host = ""
+	}
+	if host != "" && net.ParseIP(host) == nil {
+		if _, err := net.LookupHost(host); err != nil {
+			return "", "", fmt.Errorf("无法解析主机名 %s", host)
+		}
+	}
+	return host, port, nil
+}
+
+// validZone 检查域名的格式，每级标签不能为空且不超过 63 个字符
+func validZone(zone string) bool {
+	zone = strings.TrimSuffix(zone, ".")
+	if zone == "" || len(zone) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(zone, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,210 @@
+package configParser_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	configParser "github.com/soulteary/ip-helper/model/parse-config"
+)
+
+func TestValidate(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.ipdb")
+	if err := os.WriteFile(dbPath, []byte("mock"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	validConfig := func() *define.Config {
+		return &define.Config{
+			Port:          "8080",
+			Providers:     []string{"ipip:" + dbPath},
+			EnableWeb:     true,
+			EnableTelnet:  true,
+			EnableFTP:     true,
+			TelnetAddr:    ":2323",
+			FTPAddr:       "127.0.0.1:2121",
+			BatchMaxItems: 1000,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*define.Config)
+		wantErr string
+	}{
+		{
+			name:   "Valid config",
+			modify: func(c *define.Config) {},
+		},
+		{
+			name: "All protocols disabled",
+			modify: func(c *define.Config) {
+				c.EnableWeb, c.EnableTelnet, c.EnableFTP = false, false, false
+			},
+			wantErr: "至少需要启用",
+		},
+		{
+			name:    "Invalid web port",
+			modify:  func(c *define.Config) { c.Port = "abc" },
+			wantErr: "WEB 服务监听地址",
+		},
+		{
+			name:    "Port out of range",
+			modify:  func(c *define.Config) { c.TelnetAddr = ":70000" },
+			wantErr: "TELNET 服务监听地址",
+		},
+		{
+			name:    "Port conflict with wildcard",
+			modify:  func(c *define.Config) { c.FTPAddr = "127.0.0.1:8080" },
+			wantErr: "FTP 服务与 WEB 服务的监听地址冲突",
+		},
+		{
+			name: "Same port on different hosts",
+			modify: func(c *define.Config) {
+				c.TelnetAddr = "127.0.0.2:2121"
+			},
+		},
+		{
+			name: "Disabled protocol is not checked",
+			modify: func(c *define.Config) {
+				c.EnableFTP = false
+				c.FTPAddr = "invalid"
+			},
+		},
+		{
+			name:    "Missing database",
+			modify:  func(c *define.Config) { c.Providers = []string{"ipip:/nonexistent.ipdb"} },
+			wantErr: "/nonexistent.ipdb",
+		},
+		{
+			name:    "Invalid provider spec",
+			modify:  func(c *define.Config) { c.Providers = []string{"ipip"} },
+			wantErr: "格式错误",
+		},
+		{
+			name:    "Negative reload interval",
+			modify:  func(c *define.Config) { c.ReloadInterval = -1 },
+			wantErr: "不能为负数",
+		},
+		{
+			name:    "Invalid trusted proxy",
+			modify:  func(c *define.Config) { c.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"} },
+			wantErr: "proxy.local",
+		},
+		{
+			name:    "PROXY protocol without trusted sources",
+			modify:  func(c *define.Config) { c.ProxyProtocol = true; c.ProxyProtocolTimeout = time.Second },
+			wantErr: "PROXY protocol",
+		},
+		{
+			name: "PROXY protocol with trusted sources",
+			modify: func(c *define.Config) {
+				c.ProxyProtocol = true
+				c.ProxyProtocolTrusted = []string{"10.0.0.0/8"}
+				c.ProxyProtocolTimeout = time.Second
+			},
+		},
+		{
+			name: "DNS conflicts with FTP",
+			modify: func(c *define.Config) {
+				c.EnableDNS = true
+				c.DNSAddr = ":2121"
+				c.DNSZone = "myip.example.com"
+				c.LeakTestZone = "leak.example.com"
+			},
+			wantErr: "DNS 服务与 FTP 服务",
+		},
+		{
+			name: "Invalid DNS zone",
+			modify: func(c *define.Config) {
+				c.EnableDNS = true
+				c.DNSAddr = ":5353"
+				c.DNSZone = "myip..example.com"
+				c.LeakTestZone = "leak.example.com"
+			},
+			wantErr: "DNS 服务域名",
+		},
+		{
+			name: "Invalid leak test zone",
+			modify: func(c *define.Config) {
+				c.EnableDNS = true
+				c.DNSAddr = ":5353"
+				c.DNSZone = "myip.example.com"
+				c.LeakTestZone = strings.Repeat("a", 64) + ".example.com"
+			},
+			wantErr: "DNS 泄露测试域名",
+		},
+		{
+			name:   "FTP passive settings",
+			modify: func(c *define.Config) { c.FTPPassiveAddr, c.FTPPassivePorts = "203.0.113.10", "30000-30100" },
+		},
+		{
+			name:    "Invalid FTP passive ports",
+			modify:  func(c *define.Config) { c.FTPPassivePorts = "30100-30000" },
+			wantErr: "FTP 被动模式",
+		},
+		{
+			name:    "Multiplex without timeout",
+			modify:  func(c *define.Config) { c.Multiplex = true },
+			wantErr: "协议识别",
+		},
+		{
+			name:   "Template directory",
+			modify: func(c *define.Config) { c.TemplateDir = filepath.Dir(dbPath) },
+		},
+		{
+			name:    "Missing template directory",
+			modify:  func(c *define.Config) { c.TemplateDir = "/nonexistent-templates" },
+			wantErr: "模板目录",
+		},
+		{
+			name:    "Template directory is a file",
+			modify:  func(c *define.Config) { c.TemplateDir = dbPath },
+			wantErr: "不是目录",
+		},
+		{
+			name:    "Missing assets directory",
+			modify:  func(c *define.Config) { c.AssetsDir = "/nonexistent-assets" },
+			wantErr: "静态资源目录",
+		},
+		{
+			name:    "Invalid batch limit",
+			modify:  func(c *define.Config) { c.BatchMaxItems = 0 },
+			wantErr: "批量查询",
+		},
+		{
+			name:    "Negative shutdown timeout",
+			modify:  func(c *define.Config) { c.ShutdownTimeout = -1 },
+			wantErr: "退出等待时间",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			tt.modify(config)
+			err := configParser.Validate(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() 不应该返回错误，实际为 %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() 错误应该包含 %q，实际为 %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// 同时返回多个问题
+	config := validConfig()
+	config.Port = "abc"
+	config.Providers = nil
+	err := configParser.Validate(config)
+	if err == nil || strings.Count(err.Error(), "\n") != 1 {
+		t.Errorf("Validate() 应该返回全部问题，实际为 %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package proxyProtocol
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+// Listener 为来自可信地址的连接解析 PROXY protocol 头部
+//
+// 其他地址的连接保持原样，避免客户端伪造来源地址
+type Listener struct {
+	net.Listener
+	trusted *ipInfo.TrustedProxies
+	timeout time.Duration
+}
+
+func NewListener(listener net.Listener, trusted *ipInfo.TrustedProxies, timeout time.Duration) *Listener {
+	return &Listener{Listener: listener, trusted: trusted, timeout: timeout}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.trusted.Contains(fn.GetBaseIP(conn.RemoteAddr().String())) {
+		return conn, nil
+	}
+	// 头部在首次读取或获取地址时解析，避免阻塞 Accept
+	return &Conn{Conn: conn, reader: bufio.NewReader(conn), timeout: l.timeout}, nil
+}
+
+// Conn 读取 PROXY protocol 头部后，RemoteAddr 返回客户端的原始地址
+type Conn struct {
+	net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+
+	mu           sync.Mutex
+	readDeadline time.Time
+}
+
+func (c *Conn) readHeader() {
+	c.once.Do(func() {
+		c.mu.Lock()
+		deadline := time.Now().Add(c.timeout)
+		if !c.readDeadline.IsZero() && c.readDeadline.Before(deadline) {
+			deadline = c.readDeadline
+		}
+		c.Conn.SetReadDeadline(deadline)
+		c.mu.Unlock()
+
+		addr, err := ReadHeader(c.reader)
+		if err != nil {
+			log.Printf("PROXY protocol 头部解析失败 (%s): %v\n", c.Conn.RemoteAddr(), err)
+			c.err = err
+			c.Conn.Close()
+			return
+		}
+		c.remoteAddr = addr
+
+		// 恢复调用方在解析头部前设置的超时时间
+		c.mu.Lock()
+		c.Conn.SetReadDeadline(c.readDeadline)
+		c.mu.Unlock()
+	})
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// Proxied 返回客户端地址是否来自 PROXY protocol 头部
+func (c *Conn) Proxied() bool {
+	c.readHeader()
+	return c.remoteAddr != nil
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return c.Conn.SetReadDeadline(t)
+}
@@ -0,0 +1,102 @@
+package proxyProtocol_test
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	proxyProtocol "github.com/soulteary/ip-helper/model/proxy-protocol"
+)
+
+func acceptOne(t *testing.T, trustedCIDRs []string, timeout time.Duration, send string) (net.Conn, net.Conn) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	trusted, _ := ipInfo.ParseTrustedProxies(trustedCIDRs)
+	wrapped := proxyProtocol.NewListener(listener, trusted, timeout)
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if send != "" {
+		client.Write([]byte(send))
+	}
+
+	conn, err := wrapped.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, client
+}
+
+func TestListenerTrustedSource(t *testing.T) {
+	conn, _ := acceptOne(t, []string{"127.0.0.1"}, time.Second, "PROXY TCP4 203.0.113.7 10.0.0.1 51234 23\r\nhello\n")
+	if got := conn.RemoteAddr().String(); got != "203.0.113.7:51234" {
+		t.Errorf("RemoteAddr() = %v, want %v", got, "203.0.113.7:51234")
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "hello\n" {
+		t.Errorf("Read() = %q, %v, want %q", line, err, "hello\n")
+	}
+}
+
+func TestListenerProxied(t *testing.T) {
+	conn, _ := acceptOne(t, []string{"127.0.0.1"}, time.Second, "PROXY TCP4 203.0.113.7 10.0.0.1 51234 23\r\n")
+	if proxied := conn.(*proxyProtocol.Conn).Proxied(); !proxied {
+		t.Error("Proxied() should be true when the header carries a client address")
+	}
+	conn, _ = acceptOne(t, []string{"127.0.0.1"}, time.Second, "PROXY UNKNOWN\r\n")
+	if proxied := conn.(*proxyProtocol.Conn).Proxied(); proxied {
+		t.Error("Proxied() should be false for UNKNOWN headers")
+	}
+}
+
+func TestListenerUntrustedSource(t *testing.T) {
+	conn, _ := acceptOne(t, []string{"192.0.2.1"}, time.Second, "PROXY TCP4 203.0.113.7 10.0.0.1 51234 23\r\n")
+	if got := conn.RemoteAddr().String(); got == "203.0.113.7:51234" {
+		t.Error("RemoteAddr() should ignore headers from untrusted sources")
+	}
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	if line != "PROXY TCP4 203.0.113.7 10.0.0.1 51234 23\r\n" {
+		t.Errorf("Read() = %q, header should be passed through", line)
+	}
+}
+
+func TestListenerHeaderTimeout(t *testing.T) {
+	conn, _ := acceptOne(t, []string{"127.0.0.0/8"}, 50*time.Millisecond, "")
+
+	start := time.Now()
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("Read() should fail when the header is missing")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Read() should time out after the header timeout, took %s", elapsed)
+	}
+	if got := conn.RemoteAddr().String(); got == "" {
+		t.Error("RemoteAddr() should fall back to the connection address")
+	}
+}
+
+func TestListenerRestoresDeadline(t *testing.T) {
+	conn, client := acceptOne(t, []string{"127.0.0.1"}, time.Second, "PROXY UNKNOWN\r\n")
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	conn.RemoteAddr()
+
+	go func() {
+		time.Sleep(1500 * time.Millisecond)
+		client.Write([]byte("x"))
+	}()
+	// 读取应该使用调用方设置的超时时间，而不是头部的超时时间
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		t.Errorf("Read() error = %v", err)
+	}
+}
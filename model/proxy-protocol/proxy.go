@@ -0,0 +1,131 @@
+package proxyProtocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// V1_MAX_LENGTH 文本格式头部的最大长度，包含结尾的 CRLF
+const V1_MAX_LENGTH = 107
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// ReadHeader 读取连接开头的 PROXY protocol v1 或 v2 头部，返回客户端的原始地址
+//
+// 头部为 v1 的 UNKNOWN 或 v2 的 LOCAL 命令时（如负载均衡的健康检查）返回 nil，应继续使用连接的地址
+func ReadHeader(reader *bufio.Reader) (net.Addr, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case v1Prefix[0]:
+		return readV1(reader)
+	case v2Signature[0]:
+		return readV2(reader)
+	}
+	return nil, fmt.Errorf("缺少 PROXY protocol 头部")
+}
+
+func readV1(reader *bufio.Reader) (net.Addr, error) {
+	line := make([]byte, 0, V1_MAX_LENGTH)
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= V1_MAX_LENGTH {
+			return nil, fmt.Errorf("PROXY protocol v1 头部过长")
+		}
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+	}
+	if !bytes.HasPrefix(line, v1Prefix) {
+		return nil, fmt.Errorf("PROXY protocol v1 头部格式错误")
+	}
+
+	fields := strings.Split(string(line[len(v1Prefix):len(line)-2]), " ")
+	if fields[0] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 5 || (fields[0] != "TCP4" && fields[0] != "TCP6") {
+		return nil, fmt.Errorf("PROXY protocol v1 头部格式错误: %q", line)
+	}
+	addr, err := netip.ParseAddr(fields[1])
+	if err != nil || addr.Zone() != "" || addr.Is4() != (fields[0] == "TCP4") {
+		return nil, fmt.Errorf("PROXY protocol v1 源地址无效: %s", fields[1])
+	}
+	if _, err := netip.ParseAddr(fields[2]); err != nil {
+		return nil, fmt.Errorf("PROXY protocol v1 目标地址无效: %s", fields[2])
+	}
+	port, err := strconv.ParseUint(fields[3], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("PROXY protocol v1 源端口无效: %s", fields[3])
+	}
+	if _, err := strconv.ParseUint(fields[4], 10, 16); err != nil {
+		return nil, fmt.Errorf("PROXY protocol v1 目标端口无效: %s", fields[4])
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(port))), nil
+}
+
+func readV2(reader *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:12], v2Signature) {
+		return nil, fmt.Errorf("PROXY protocol v2 签名错误")
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("不支持的 PROXY protocol 版本: %d", header[12]>>4)
+	}
+	command, family, transport := header[12]&0x0f, header[13]>>4, header[13]&0x0f
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+
+	switch command {
+	case 0x0:
+		// LOCAL 命令由负载均衡自身发起，不携带客户端地址
+		return nil, nil
+	case 0x1:
+	default:
+		return nil, fmt.Errorf("不支持的 PROXY protocol v2 命令: %d", command)
+	}
+
+	var addr netip.Addr
+	var port uint16
+	switch family {
+	case 0x1:
+		if len(payload) < 12 {
+			return nil, fmt.Errorf("PROXY protocol v2 地址长度错误")
+		}
+		addr = netip.AddrFrom4([4]byte(payload[0:4]))
+		port = binary.BigEndian.Uint16(payload[8:10])
+	case 0x2:
+		if len(payload) < 36 {
+			return nil, fmt.Errorf("PROXY protocol v2 地址长度错误")
+		}
+		addr = netip.AddrFrom16([16]byte(payload[0:16]))
+		port = binary.BigEndian.Uint16(payload[32:34])
+	default:
+		// UNSPEC 与 UNIX 地址无法用于查询
+		return nil, nil
+	}
+
+	if transport == 0x2 {
+		return net.UDPAddrFromAddrPort(netip.AddrPortFrom(addr, port)), nil
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, port)), nil
+}
@@ -0,0 +1,83 @@
+package proxyProtocol_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	proxyProtocol "github.com/soulteary/ip-helper/model/proxy-protocol"
+)
+
+// buildV2 构造 PROXY protocol v2 头部
+func buildV2(command byte, family byte, addresses []byte) []byte {
+	header := []byte("\r\n\r\n\x00\r\nQUIT\n")
+	header = append(header, 0x20|command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(addresses)))
+	return append(header, addresses...)
+}
+
+func TestReadHeader(t *testing.T) {
+	ipv4 := []byte{203, 0, 113, 7, 10, 0, 0, 1}
+	ipv4 = binary.BigEndian.AppendUint16(ipv4, 51234)
+	ipv4 = binary.BigEndian.AppendUint16(ipv4, 23)
+
+	ipv6 := append(make([]byte, 0, 36), []byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}...)
+	ipv6 = append(ipv6, make([]byte, 16)...)
+	ipv6 = binary.BigEndian.AppendUint16(ipv6, 4711)
+	ipv6 = binary.BigEndian.AppendUint16(ipv6, 21)
+	// TLV 扩展字段应该被跳过
+	ipv6WithTLV := append(append([]byte{}, ipv6...), 0x04, 0x00, 0x01, 0x00)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string
+		wantErr bool
+	}{
+		{"v1 TCP4", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 23\r\n"), "203.0.113.7:51234", false},
+		{"v1 TCP6", []byte("PROXY TCP6 2001:db8::1 ::1 4711 21\r\n"), "[2001:db8::1]:4711", false},
+		{"v1 UNKNOWN", []byte("PROXY UNKNOWN\r\n"), "", false},
+		{"v1 family mismatch", []byte("PROXY TCP4 2001:db8::1 ::1 4711 21\r\n"), "", true},
+		{"v1 invalid port", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 70000 23\r\n"), "", true},
+		{"v1 too long", []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), "", true},
+		{"v2 TCP4", buildV2(0x1, 0x11, ipv4), "203.0.113.7:51234", false},
+		{"v2 TCP6 with TLV", buildV2(0x1, 0x21, ipv6WithTLV), "[2001:db8::1]:4711", false},
+		{"v2 UDP4", buildV2(0x1, 0x12, ipv4), "203.0.113.7:51234", false},
+		{"v2 LOCAL", buildV2(0x0, 0x00, nil), "", false},
+		{"v2 UNSPEC", buildV2(0x1, 0x00, nil), "", false},
+		{"v2 short address", buildV2(0x1, 0x11, ipv4[:6]), "", true},
+		{"v2 unknown command", buildV2(0x2, 0x11, ipv4), "", true},
+		{"Missing header", []byte("GET / HTTP/1.1\r\n"), "", true},
+		{"Truncated header", []byte("PROXY TCP4 203.0.113.7"), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewReader(append(tt.input, "payload"...)))
+			addr, err := proxyProtocol.ReadHeader(reader)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ReadHeader() should fail, got %v", addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadHeader() error = %v", err)
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tt.want {
+				t.Errorf("ReadHeader() = %v, want %v", got, tt.want)
+			}
+			// 头部之后的数据应该保持不变
+			rest, _ := reader.ReadString(0)
+			if rest != "payload" {
+				t.Errorf("remaining data = %q, want %q", rest, "payload")
+			}
+		})
+	}
+}
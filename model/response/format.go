@@ -0,0 +1,94 @@
+package response
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+// document YAML 与 XML 格式的响应结构，与 JSON 响应的字段相同
+type document struct {
+	XMLName xml.Name      `yaml:"-" xml:"response"`
+	Version int           `yaml:"version" xml:"version,attr"`
+	IP      string        `yaml:"ip" xml:"ip"`
+	Info    []string      `yaml:"info" xml:"info>item"`
+	Result  ipInfo.Result `yaml:"result" xml:"result"`
+}
+
+func newDocument(ipaddr string, result ipInfo.Result) document {
+	info := result.Info
+	if info == nil {
+		info = []string{}
+	}
+	return document{Version: JSON_SCHEMA_VERSION, IP: ipaddr, Info: info, Result: result}
+}
+
+func RenderYAML(ipaddr string, result ipInfo.Result) []byte {
+	output, _ := yaml.Marshal(newDocument(ipaddr, result))
+	return output
+}
+
+func RenderXML(ipaddr string, result ipInfo.Result) []byte {
+	output, _ := xml.MarshalIndent(newDocument(ipaddr, result), "", "  ")
+	return append([]byte(xml.Header), append(output, '\n')...)
+}
+
+// fields 按固定顺序返回结构化的查询结果，用于 CSV 和 shell 格式
+func fields(ipaddr string, result ipInfo.Result) [][2]string {
+	coordinate := func(value *float64) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*value, 'f', -1, 64)
+	}
+	return [][2]string{
+		{"ip", ipaddr},
+		{"country", result.Country},
+		{"country_code", result.CountryCode},
+		{"region", result.Region},
+		{"city", result.City},
+		{"isp", result.ISP},
+		{"owner", result.Owner},
+		{"timezone", result.Timezone},
+		{"latitude", coordinate(result.Latitude)},
+		{"longitude", coordinate(result.Longitude)},
+		{"source", result.Source},
+		{"language", result.Language},
+	}
+}
+
+// RenderCSV 输出带表头的单行 CSV
+func RenderCSV(ipaddr string, result ipInfo.Result) []byte {
+	header, row := []string{}, []string{}
+	for _, field := range fields(ipaddr, result) {
+		header = append(header, field[0])
+		row = append(row, field[1])
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.WriteAll([][]string{header, row})
+	return buf.Bytes()
+}
+
+// RenderShell 输出可以直接 eval 的 export 语句，变量名以 IP_HELPER_ 开头
+func RenderShell(ipaddr string, result ipInfo.Result) []byte {
+	var buf bytes.Buffer
+	for _, field := range fields(ipaddr, result) {
+		// 单引号内的内容不会被 shell 解析，单引号本身需要转义
+		value := strings.ReplaceAll(field[1], "'", `'\''`)
+		buf.WriteString("export IP_HELPER_" + strings.ToUpper(field[0]) + "='" + value + "'\n")
+	}
+	return buf.Bytes()
+}
+
+// RenderJSONP 使用回调函数包裹 JSON 响应，callback 需要事先校验
+func RenderJSONP(callback string, ipaddr string, result ipInfo.Result) []byte {
+	// 开头的注释可以避免 Rosetta Flash 一类利用回调名称的攻击
+	return []byte("/**/" + callback + "(" + string(RenderJSON(ipaddr, result)) + ");")
+}
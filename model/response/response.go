@@ -3,23 +3,54 @@ package response
 import (
 	"bytes"
 	"encoding/json"
+	"html/template"
 	"strings"
 
 	"github.com/soulteary/ip-helper/model/define"
 	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
 )
 
-func RenderJSON(ipaddr string, dbInfo []string) []byte {
-	response, _ := json.Marshal(map[string]any{"ip": ipaddr, "info": dbInfo})
+// JSON_SCHEMA_VERSION 为 JSON 响应的结构版本，`info` 数组保留用于兼容旧版本
+const JSON_SCHEMA_VERSION = 2
+
+func RenderJSON(ipaddr string, result ipInfo.Result) []byte {
+	response, _ := json.Marshal(map[string]any{
+		"version": JSON_SCHEMA_VERSION,
+		"ip":      ipaddr,
+		"info":    result.Info,
+		"result":  result,
+	})
 	return response
 }
 
-func RenderHTML(config *define.Config, urlPath string, globalTemplate []byte, ipaddr string, dbInfo []string) []byte {
-	template := bytes.ReplaceAll(globalTemplate, []byte("%IP_ADDR%"), []byte(ipaddr))
-	template = bytes.ReplaceAll(template, []byte("%DOMAIN%"), []byte(config.Domain))
-	template = bytes.ReplaceAll(template, []byte("%DATA_1_INFO%"), []byte(strings.Join(fn.RemoveDuplicates(dbInfo), " ")))
-	template = bytes.ReplaceAll(template, []byte("%DOCUMENT_PATH%"), []byte(urlPath))
-	template = bytes.ReplaceAll(template, []byte("%ONLY_DOMAIN%"), []byte(fn.GetDomainOnly(config.Domain)))
-	template = bytes.ReplaceAll(template, []byte("%ONLY_DOMAIN_WITH_PORT%"), []byte(fn.GetDomainWithPort(config.Domain)))
-	return template
+// RenderText 以纯文本输出，第一行为 IP 地址，第二行为地址信息
+func RenderText(ipaddr string, result ipInfo.Result) []byte {
+	return []byte(ipaddr + "\n" + strings.Join(result.Info, " ") + "\n")
+}
+
+// Page HTML 模板可以使用的数据，由 html/template 按上下文转义
+type Page struct {
+	IP             string
+	Domain         string
+	Info           string
+	Path           string
+	OnlyDomain     string
+	DomainWithPort string
+}
+
+func RenderHTML(config *define.Config, urlPath string, tmpl *template.Template, ipaddr string, dbInfo []string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, Page{
+		IP:             ipaddr,
+		Domain:         config.Domain,
+		Info:           strings.Join(fn.RemoveDuplicates(dbInfo), " "),
+		Path:           urlPath,
+		OnlyDomain:     fn.GetDomainOnly(config.Domain),
+		DomainWithPort: fn.GetDomainWithPort(config.Domain),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
@@ -2,9 +2,12 @@ package response_test
 
 import (
 	"encoding/json"
+	"html/template"
+	"strings"
 	"testing"
 
 	"github.com/soulteary/ip-helper/model/define"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
 	"github.com/soulteary/ip-helper/model/response"
 )
 
@@ -46,7 +49,7 @@ func TestRenderJSON(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := response.RenderJSON(tt.ipaddr, tt.dbInfo)
+			result := response.RenderJSON(tt.ipaddr, ipInfo.Result{Info: tt.dbInfo})
 
 			// Parse the JSON result back into a map for comparison
 			var got map[string]any
@@ -79,12 +82,62 @@ func TestRenderJSON(t *testing.T) {
 	}
 }
 
+func TestRenderJSONResult(t *testing.T) {
+	latitude, longitude := 39.9042, 116.4074
+	result := ipInfo.Result{
+		Country:     "中国",
+		CountryCode: "CN",
+		Region:      "北京",
+		City:        "北京",
+		Latitude:    &latitude,
+		Longitude:   &longitude,
+		Source:      "ipip",
+		Info:        []string{"中国", "北京"},
+	}
+
+	var got struct {
+		Version int           `json:"version"`
+		IP      string        `json:"ip"`
+		Info    []string      `json:"info"`
+		Result  ipInfo.Result `json:"result"`
+	}
+	if err := json.Unmarshal(response.RenderJSON("1.2.3.4", result), &got); err != nil {
+		t.Fatalf("Failed to unmarshal result JSON: %v", err)
+	}
+
+	if got.Version != response.JSON_SCHEMA_VERSION {
+		t.Errorf("Version mismatch - got: %d, want: %d", got.Version, response.JSON_SCHEMA_VERSION)
+	}
+	if got.IP != "1.2.3.4" || len(got.Info) != 2 {
+		t.Errorf("Legacy fields mismatch - got: %+v", got)
+	}
+	if got.Result.Country != "中国" || got.Result.CountryCode != "CN" || got.Result.City != "北京" || got.Result.Source != "ipip" {
+		t.Errorf("Result mismatch - got: %+v", got.Result)
+	}
+	if got.Result.Latitude == nil || *got.Result.Latitude != latitude || got.Result.Longitude == nil || *got.Result.Longitude != longitude {
+		t.Errorf("Coordinates mismatch - got: %v, %v", got.Result.Latitude, got.Result.Longitude)
+	}
+
+	// 未知字段不应输出
+	raw := string(response.RenderJSON("1.2.3.4", ipInfo.Result{Info: []string{}}))
+	if strings.Contains(raw, "latitude") || strings.Contains(raw, "timezone") {
+		t.Errorf("Empty optional fields should be omitted - got: %s", raw)
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	got := string(response.RenderText("1.2.3.4", ipInfo.Result{Info: []string{"中国", "北京"}}))
+	if got != "1.2.3.4\n中国 北京\n" {
+		t.Errorf("RenderText() = %q", got)
+	}
+}
+
 func TestRenderHTML(t *testing.T) {
 	tests := []struct {
 		name            string
 		config          *define.Config
 		urlPath         string
-		globalTemplate  []byte
+		globalTemplate  string
 		ipaddr          string
 		dbInfo          []string
 		expectedContent string
@@ -95,7 +148,7 @@ func TestRenderHTML(t *testing.T) {
 				Domain: "example.com:8080",
 			},
 			urlPath:         "/test/path",
-			globalTemplate:  []byte("IP: %IP_ADDR% Domain: %DOMAIN% Info: %DATA_1_INFO% Path: %DOCUMENT_PATH% Domain Only: %ONLY_DOMAIN% Domain With Port: %ONLY_DOMAIN_WITH_PORT%"),
+			globalTemplate:  "IP: {{.IP}} Domain: {{.Domain}} Info: {{.Info}} Path: {{.Path}} Domain Only: {{.OnlyDomain}} Domain With Port: {{.DomainWithPort}}",
 			ipaddr:          "127.0.0.1",
 			dbInfo:          []string{"info1", "info2"},
 			expectedContent: "IP: 127.0.0.1 Domain: example.com:8080 Info: info1 info2 Path: /test/path Domain Only: example.com Domain With Port: example.com:8080",
@@ -106,19 +159,91 @@ func TestRenderHTML(t *testing.T) {
 				Domain: "",
 			},
 			urlPath:         "",
-			globalTemplate:  []byte("%IP_ADDR%%DOMAIN%%DATA_1_INFO%%DOCUMENT_PATH%%ONLY_DOMAIN%%ONLY_DOMAIN_WITH_PORT%"),
+			globalTemplate:  "{{.IP}}{{.Domain}}{{.Info}}{{.Path}}{{.OnlyDomain}}{{.DomainWithPort}}",
 			ipaddr:          "",
 			dbInfo:          []string{},
 			expectedContent: "",
 		},
+		{
+			name: "escaped by context",
+			config: &define.Config{
+				Domain: "javascript:alert(1)",
+			},
+			urlPath:         "/ip/<script>",
+			globalTemplate:  `<a href="{{.Domain}}">{{.IP}}</a><input value="{{.Info}}"/><link href="/x{{.Path}}"/>`,
+			ipaddr:          "<script>alert(1)</script>",
+			dbInfo:          []string{`"><img src=x onerror=alert(1)>`},
+			expectedContent: `<a href="#ZgotmplZ">&lt;script&gt;alert(1)&lt;/script&gt;</a><input value="&#34;&gt;&lt;img src=x onerror=alert(1)&gt;"/><link href="/x/ip/%3cscript%3e"/>`,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := response.RenderHTML(tt.config, tt.urlPath, tt.globalTemplate, tt.ipaddr, tt.dbInfo)
+			tmpl := template.Must(template.New("test").Parse(tt.globalTemplate))
+			result, err := response.RenderHTML(tt.config, tt.urlPath, tmpl, tt.ipaddr, tt.dbInfo)
+			if err != nil {
+				t.Fatalf("RenderHTML() error = %v", err)
+			}
 			if string(result) != tt.expectedContent {
 				t.Errorf("RenderHTML() = %s, want %s", string(result), tt.expectedContent)
 			}
 		})
 	}
 }
+
+func sampleResult() ipInfo.Result {
+	latitude, longitude := 39.9042, 116.4074
+	return ipInfo.Result{
+		Country:   "中国",
+		Region:    "北京",
+		City:      "北京",
+		ISP:       "It's ISP",
+		Latitude:  &latitude,
+		Longitude: &longitude,
+		Source:    "memory",
+		Info:      []string{"中国", "北京", "北京"},
+	}
+}
+
+func TestRenderYAML(t *testing.T) {
+	output := string(response.RenderYAML("1.2.3.4", sampleResult()))
+	for _, want := range []string{"version: 2\n", "ip: 1.2.3.4\n", "info:\n    - 中国\n", "result:\n", "    country: 中国\n", "    latitude: 39.9042\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("YAML output should contain %q, got %q", want, output)
+		}
+	}
+}
+
+func TestRenderXML(t *testing.T) {
+	output := string(response.RenderXML("1.2.3.4", sampleResult()))
+	for _, want := range []string{`<?xml version="1.0" encoding="UTF-8"?>`, `<response version="2">`, "<ip>1.2.3.4</ip>", "<item>中国</item>", "<country>中国</country>", "<isp>It&#39;s ISP</isp>"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("XML output should contain %q, got %q", want, output)
+		}
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	output := string(response.RenderCSV("1.2.3.4", sampleResult()))
+	expected := "ip,country,country_code,region,city,isp,owner,timezone,latitude,longitude,source,language\n" +
+		"1.2.3.4,中国,,北京,北京,It's ISP,,,39.9042,116.4074,memory,\n"
+	if output != expected {
+		t.Errorf("Unexpected CSV output: %q", output)
+	}
+}
+
+func TestRenderShell(t *testing.T) {
+	output := string(response.RenderShell("1.2.3.4", sampleResult()))
+	for _, want := range []string{"export IP_HELPER_IP='1.2.3.4'\n", "export IP_HELPER_COUNTRY='中国'\n", `export IP_HELPER_ISP='It'\''s ISP'` + "\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Shell output should contain %q, got %q", want, output)
+		}
+	}
+}
+
+func TestRenderJSONP(t *testing.T) {
+	output := string(response.RenderJSONP("app.show", "1.2.3.4", sampleResult()))
+	if !strings.HasPrefix(output, "/**/app.show({") || !strings.HasSuffix(output, "});") {
+		t.Errorf("Unexpected JSONP output: %q", output)
+	}
+}
@@ -0,0 +1,46 @@
+package sshServer
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// LoadHostKey 读取主机密钥，path 为空时生成仅在本次运行中有效的临时密钥，
+// 文件不存在时生成新的 Ed25519 密钥并保存，之后重启都使用同一个密钥
+func LoadHostKey(path string) (ssh.Signer, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			signer, err := ssh.ParsePrivateKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("解析 SSH 主机密钥失败: %v", err)
+			}
+			return signer, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("读取 SSH 主机密钥失败: %v", err)
+		}
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成 SSH 主机密钥失败: %v", err)
+	}
+	if path != "" {
+		block, err := ssh.MarshalPrivateKey(key, "ip-helper")
+		if err != nil {
+			return nil, fmt.Errorf("生成 SSH 主机密钥失败: %v", err)
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			return nil, fmt.Errorf("保存 SSH 主机密钥失败: %v", err)
+		}
+	}
+	return ssh.NewSignerFromKey(key)
+}
@@ -0,0 +1,142 @@
+package sshServer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/response"
+	"github.com/soulteary/ip-helper/model/supervisor"
+)
+
+// NewConfig 创建服务端配置，接受任意用户名和认证方式
+func NewConfig(hostKey ssh.Signer) *ssh.ServerConfig {
+	config := &ssh.ServerConfig{
+		NoClientAuth:  true,
+		ServerVersion: define.SSH_SERVER_VERSION,
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+		PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+		KeyboardInteractiveCallback: func(ssh.ConnMetadata, ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+	return config
+}
+
+// Serve 在已绑定的监听器上提供服务，ctx 结束后等待进行中的连接处理完成
+func Serve(ctx context.Context, ipdb ipInfo.Provider, config *ssh.ServerConfig, listener net.Listener) error {
+	return supervisor.ServeConn(ctx, "SSH", listener, func(conn net.Conn) {
+		HandleConnection(ipdb, config, conn)
+	})
+}
+
+// HandleConnection 完成握手后在会话中输出查询结果并退出，
+// 执行的命令为 `json`、`text` 或 IP 地址时分别切换输出格式或查询指定的地址
+func HandleConnection(ipdb ipInfo.Provider, config *ssh.ServerConfig, conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(define.SSH_TIMEOUT))
+
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	defer serverConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	clientIP := fn.GetBaseIP(conn.RemoteAddr().String())
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "只支持 session 类型的通道")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		// 每个连接只处理一个会话，输出结果后断开
+		handleSession(ipdb, clientIP, channel, channelRequests)
+		return
+	}
+}
+
+// handleSession 等待客户端请求 shell 或执行命令，分配了终端时默认使用文本格式
+func handleSession(ipdb ipInfo.Provider, clientIP string, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	terminal := false
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			terminal = true
+			req.Reply(true, nil)
+		case "env":
+			req.Reply(true, nil)
+		case "shell", "exec":
+			command := ""
+			if req.Type == "exec" {
+				var payload struct{ Command string }
+				if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+					req.Reply(false, nil)
+					continue
+				}
+				command = payload.Command
+			}
+			req.Reply(true, nil)
+
+			output, status := render(ipdb, clientIP, command, terminal)
+			if _, err := channel.Write(output); err != nil {
+				log.Printf("SSH 服务发送消息时发生错误: %v\n", err)
+			}
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// render 根据命令生成输出和退出状态
+func render(ipdb ipInfo.Provider, clientIP string, command string, terminal bool) ([]byte, uint32) {
+	ip, text := clientIP, terminal
+	for _, field := range strings.Fields(command) {
+		switch strings.ToLower(field) {
+		case "json":
+			text = false
+		case "text":
+			text = true
+		default:
+			if !fn.IsValidIPAddress(field) {
+				return lineEnding([]byte("用法: ssh <服务器> [json|text] [IP 地址]\n"), terminal), 1
+			}
+			ip = field
+		}
+	}
+
+	var output []byte
+	if text {
+		output = response.RenderText(ip, ipInfo.Lookup(ipdb, ip))
+	} else {
+		output = append(response.RenderJSON(ip, ipInfo.Lookup(ipdb, ip)), '\n')
+	}
+	return lineEnding(output, terminal), 0
+}
+
+// lineEnding 分配了终端时使用 CRLF 换行
+func lineEnding(output []byte, terminal bool) []byte {
+	if !terminal {
+		return output
+	}
+	return bytes.ReplaceAll(output, []byte("\n"), []byte("\r\n"))
+}
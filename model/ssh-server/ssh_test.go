@@ -0,0 +1,169 @@
+package sshServer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	sshServer "github.com/soulteary/ip-helper/model/ssh-server"
+	"github.com/soulteary/ip-helper/model/testutil"
+)
+
+// startServer 在随机端口上运行服务，返回监听地址
+func startServer(t *testing.T) string {
+	t.Helper()
+	memory := testutil.MemoryProvider(t)
+	hostKey, err := sshServer.LoadHostKey("")
+	if err != nil {
+		t.Fatalf("生成主机密钥失败: %v", err)
+	}
+	return testutil.Serve(t, func(ctx context.Context, listener net.Listener) error {
+		return sshServer.Serve(ctx, memory, sshServer.NewConfig(hostKey), listener)
+	})
+}
+
+func dial(t *testing.T, addr string, auth ...ssh.AuthMethod) *ssh.Client {
+	t.Helper()
+	conn, chans, reqs, err := ssh.NewClientConn(testutil.Dial(t, addr), addr, &ssh.ClientConfig{
+		User:            "anyone",
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	client := ssh.NewClient(conn, chans, reqs)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestExec(t *testing.T) {
+	addr := startServer(t)
+
+	tests := []struct {
+		name    string
+		command string
+		check   func(t *testing.T, output []byte)
+	}{
+		{
+			name:    "JSON",
+			command: "",
+			check: func(t *testing.T, output []byte) {
+				var result struct {
+					IP   string   `json:"ip"`
+					Info []string `json:"info"`
+				}
+				if err := json.Unmarshal(output, &result); err != nil {
+					t.Fatalf("输出应该为 JSON，实际为 %q", output)
+				}
+				if result.IP != "127.0.0.1" || len(result.Info) == 0 || result.Info[0] != "本机地址" {
+					t.Errorf("查询结果错误: %+v", result)
+				}
+			},
+		},
+		{
+			name:    "Text",
+			command: "text",
+			check: func(t *testing.T, output []byte) {
+				if string(output) != "127.0.0.1\n本机地址\n" {
+					t.Errorf("文本输出错误，实际为 %q", output)
+				}
+			},
+		},
+		{
+			name:    "Lookup",
+			command: "text 192.0.2.1",
+			check: func(t *testing.T, output []byte) {
+				if !strings.Contains(string(output), "测试网络") {
+					t.Errorf("应该查询指定的 IP，实际为 %q", output)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := dial(t, addr, ssh.Password("secret")).NewSession()
+			if err != nil {
+				t.Fatalf("创建会话失败: %v", err)
+			}
+			defer session.Close()
+			output, err := session.Output(tt.command)
+			if err != nil {
+				t.Fatalf("执行命令失败: %v", err)
+			}
+			tt.check(t, output)
+		})
+	}
+
+	t.Run("Invalid command", func(t *testing.T) {
+		session, err := dial(t, addr).NewSession()
+		if err != nil {
+			t.Fatalf("创建会话失败: %v", err)
+		}
+		defer session.Close()
+		output, err := session.Output("rm -rf /")
+		if exitErr, ok := err.(*ssh.ExitError); !ok || exitErr.ExitStatus() != 1 {
+			t.Errorf("无效的命令应该以状态 1 退出，实际为 %v", err)
+		}
+		if !strings.Contains(string(output), "用法") {
+			t.Errorf("应该输出用法，实际为 %q", output)
+		}
+	})
+}
+
+func TestShell(t *testing.T) {
+	session, err := dial(t, startServer(t)).NewSession()
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	defer session.Close()
+	if err := session.RequestPty("xterm", 24, 80, ssh.TerminalModes{}); err != nil {
+		t.Fatalf("请求终端失败: %v", err)
+	}
+	var output bytes.Buffer
+	session.Stdout = &output
+	if err := session.Shell(); err != nil {
+		t.Fatalf("启动 shell 失败: %v", err)
+	}
+	if err := session.Wait(); err != nil {
+		t.Fatalf("会话应该正常退出: %v", err)
+	}
+	// 分配了终端时使用文本格式和 CRLF 换行
+	if output.String() != "127.0.0.1\r\n本机地址\r\n" {
+		t.Errorf("终端输出错误，实际为 %q", output.String())
+	}
+}
+
+func TestLoadHostKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ssh_host_ed25519_key")
+	first, err := sshServer.LoadHostKey(path)
+	if err != nil {
+		t.Fatalf("生成主机密钥失败: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("应该以 0600 权限保存主机密钥: %v", err)
+	}
+	second, err := sshServer.LoadHostKey(path)
+	if err != nil {
+		t.Fatalf("读取主机密钥失败: %v", err)
+	}
+	if ssh.FingerprintSHA256(first.PublicKey()) != ssh.FingerprintSHA256(second.PublicKey()) {
+		t.Error("再次加载应该得到同一个主机密钥")
+	}
+
+	if err := os.WriteFile(path, []byte("invalid"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sshServer.LoadHostKey(path); err == nil {
+		t.Error("无效的主机密钥应该返回错误")
+	}
+}
@@ -0,0 +1,144 @@
+package stun
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"net/netip"
+)
+
+// MAGIC_COOKIE 用于区分 RFC 5389 与 RFC 3489 的客户端
+const MAGIC_COOKIE = 0x2112a442
+
+// 消息类型，参考 RFC 5389 第 6 节
+const (
+	BINDING_REQUEST  = 0x0001
+	BINDING_SUCCESS  = 0x0101
+	BINDING_ERROR    = 0x0111
+	METHOD_BINDING   = 0x0001
+	CLASS_MASK       = 0x0110
+	CLASS_REQUEST    = 0x0000
+	CLASS_INDICATION = 0x0010
+	CLASS_ERROR      = 0x0110
+)
+
+// 属性类型，小于 0x8000 的属性必须被理解
+const (
+	ATTR_MAPPED_ADDRESS     = 0x0001
+	ATTR_ERROR_CODE         = 0x0009
+	ATTR_UNKNOWN_ATTRIBUTES = 0x000a
+	ATTR_XOR_MAPPED_ADDRESS = 0x0020
+	ATTR_SOFTWARE           = 0x8022
+	ATTR_FINGERPRINT        = 0x8028
+)
+
+const (
+	headerLength   = 20
+	fingerprintXOR = 0x5354554e
+	// SOFTWARE 属性的最大长度
+	maxSoftwareLength = 763
+)
+
+var errFormat = errors.New("STUN 消息格式错误")
+
+type attribute struct {
+	atype uint16
+	value []byte
+}
+
+type message struct {
+	mtype uint16
+	// RFC 3489 的客户端没有 magic cookie，事务 ID 为 16 字节
+	classic bool
+	// magic cookie 与事务 ID
+	transaction []byte
+	attributes  []attribute
+}
+
+func (m *message) has(atype uint16) bool {
+	for _, attr := range m.attributes {
+		if attr.atype == atype {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMessage 解析 STUN 消息，无法识别为 STUN 的数据返回错误，不做应答
+func parseMessage(msg []byte) (*message, error) {
+	if len(msg) < headerLength || msg[0]&0xc0 != 0 {
+		return nil, errFormat
+	}
+	length := int(binary.BigEndian.Uint16(msg[2:]))
+	if length%4 != 0 || headerLength+length != len(msg) {
+		return nil, errFormat
+	}
+	m := &message{
+		mtype:       binary.BigEndian.Uint16(msg),
+		classic:     binary.BigEndian.Uint32(msg[4:]) != MAGIC_COOKIE,
+		transaction: msg[4:headerLength],
+	}
+
+	for offset := headerLength; offset < len(msg); {
+		if offset+4 > len(msg) {
+			return nil, errFormat
+		}
+		atype, size := binary.BigEndian.Uint16(msg[offset:]), int(binary.BigEndian.Uint16(msg[offset+2:]))
+		padded := (size + 3) &^ 3
+		if offset+4+padded > len(msg) {
+			return nil, errFormat
+		}
+		m.attributes = append(m.attributes, attribute{atype, msg[offset+4 : offset+4+size]})
+		offset += 4 + padded
+	}
+	return m, nil
+}
+
+// addressValue 编码 MAPPED-ADDRESS，xor 为 true 时按 XOR-MAPPED-ADDRESS 的规则与 magic cookie 和事务 ID 异或
+func addressValue(addr netip.AddrPort, transaction []byte, xor bool) []byte {
+	ip := addr.Addr().Unmap()
+	value := []byte{0, 0x01}
+	if ip.Is6() {
+		value[1] = 0x02
+	}
+	port := addr.Port()
+	raw := ip.AsSlice()
+	if xor {
+		port ^= MAGIC_COOKIE >> 16
+		for i := range raw {
+			raw[i] ^= transaction[i]
+		}
+	}
+	value = binary.BigEndian.AppendUint16(value, port)
+	return append(value, raw...)
+}
+
+// errorValue 编码 ERROR-CODE 属性
+func errorValue(code int, reason string) []byte {
+	return append([]byte{0, 0, byte(code / 100), byte(code % 100)}, reason...)
+}
+
+// encodeMessage 构造消息，fingerprint 为 true 时在末尾添加 FINGERPRINT 属性
+func encodeMessage(mtype uint16, transaction []byte, attributes []attribute, fingerprint bool) []byte {
+	buf := make([]byte, 4, 128)
+	binary.BigEndian.PutUint16(buf, mtype)
+	buf = append(buf, transaction...)
+	for _, attr := range attributes {
+		buf = binary.BigEndian.AppendUint16(buf, attr.atype)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(attr.value)))
+		buf = append(buf, attr.value...)
+		for len(buf)%4 != 0 {
+			buf = append(buf, 0)
+		}
+	}
+	if fingerprint {
+		// 长度需要包含 FINGERPRINT 属性本身
+		binary.BigEndian.PutUint16(buf[2:], uint16(len(buf)-headerLength+8))
+		crc := crc32.ChecksumIEEE(buf) ^ fingerprintXOR
+		buf = binary.BigEndian.AppendUint16(buf, ATTR_FINGERPRINT)
+		buf = binary.BigEndian.AppendUint16(buf, 4)
+		buf = binary.BigEndian.AppendUint32(buf, crc)
+	}
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(buf)-headerLength))
+	return buf
+}
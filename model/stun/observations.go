@@ -0,0 +1,71 @@
+package stun
+
+import (
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+)
+
+// Observations 保存最近通过 STUN 观察到的映射地址，用于与 HTTP 请求的地址对比
+type Observations struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	seen  map[netip.AddrPort]time.Time
+	limit int
+}
+
+func NewObservations(ttl time.Duration) *Observations {
+	return &Observations{ttl: ttl, seen: map[netip.AddrPort]time.Time{}, limit: define.STUN_MAX_OBSERVATIONS}
+}
+
+// prune 清除过期的记录，数量仍然达到上限时清除最早的记录
+func (o *Observations) prune(now time.Time) {
+	var oldest netip.AddrPort
+	for addr, seen := range o.seen {
+		if now.Sub(seen) > o.ttl {
+			delete(o.seen, addr)
+			continue
+		}
+		if !oldest.IsValid() || seen.Before(o.seen[oldest]) {
+			oldest = addr
+		}
+	}
+	if len(o.seen) >= o.limit && oldest.IsValid() {
+		delete(o.seen, oldest)
+	}
+}
+
+func (o *Observations) Record(addr netip.AddrPort) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	if _, ok := o.seen[addr]; !ok && len(o.seen) >= o.limit {
+		o.prune(now)
+	}
+	o.seen[addr] = now
+}
+
+// Seen 判断映射地址是否在有效期内被观察到
+func (o *Observations) Seen(addr netip.AddrPort) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	seen, ok := o.seen[addr]
+	return ok && time.Since(seen) <= o.ttl
+}
+
+// ByIP 返回指定 IP 在有效期内的所有映射地址，按端口排序
+func (o *Observations) ByIP(ip netip.Addr) []netip.AddrPort {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	result := []netip.AddrPort{}
+	for addr, seen := range o.seen {
+		if addr.Addr() == ip && time.Since(seen) <= o.ttl {
+			result = append(result, addr)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Port() < result[j].Port() })
+	return result
+}
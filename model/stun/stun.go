@@ -0,0 +1,122 @@
+package stun
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/supervisor"
+)
+
+// Handler 应答 Binding 请求，返回客户端经过 NAT 映射后的地址
+type Handler struct {
+	software     string
+	observations *Observations
+}
+
+// NewHandler 创建处理器，software 为空时不发送 SOFTWARE 属性，observations 为 nil 时不记录映射地址
+func NewHandler(software string, observations *Observations) *Handler {
+	if len(software) > maxSoftwareLength {
+		software = software[:maxSoftwareLength]
+	}
+	return &Handler{software: software, observations: observations}
+}
+
+// Answer 处理一条消息，无法识别的消息以及请求以外的消息返回 nil，不做应答
+func (h *Handler) Answer(msg []byte, remote netip.AddrPort) []byte {
+	request, err := parseMessage(msg)
+	if err != nil || request.mtype&CLASS_MASK != CLASS_REQUEST {
+		return nil
+	}
+	fingerprint := !request.classic && request.has(ATTR_FINGERPRINT)
+
+	attributes := []attribute{}
+	if h.software != "" && !request.classic {
+		attributes = append(attributes, attribute{ATTR_SOFTWARE, []byte(h.software)})
+	}
+	if request.mtype != BINDING_REQUEST {
+		attributes = append(attributes, attribute{ATTR_ERROR_CODE, errorValue(400, "Bad Request")})
+		return encodeMessage(request.mtype&^CLASS_MASK|CLASS_ERROR, request.transaction, attributes, fingerprint)
+	}
+
+	// 不支持认证等必须理解的属性，按 RFC 5389 返回 420 错误
+	unknown := []byte{}
+	for _, attr := range request.attributes {
+		if attr.atype < 0x8000 {
+			unknown = binary.BigEndian.AppendUint16(unknown, attr.atype)
+		}
+	}
+	if len(unknown) > 0 && !request.classic {
+		attributes = append(attributes,
+			attribute{ATTR_ERROR_CODE, errorValue(420, "Unknown Attribute")},
+			attribute{ATTR_UNKNOWN_ATTRIBUTES, unknown},
+		)
+		return encodeMessage(BINDING_ERROR, request.transaction, attributes, fingerprint)
+	}
+
+	remote = netip.AddrPortFrom(remote.Addr().Unmap(), remote.Port())
+	if h.observations != nil {
+		h.observations.Record(remote)
+	}
+	if request.classic {
+		attributes = append(attributes, attribute{ATTR_MAPPED_ADDRESS, addressValue(remote, request.transaction, false)})
+	} else {
+		attributes = append(attributes, attribute{ATTR_XOR_MAPPED_ADDRESS, addressValue(remote, request.transaction, true)})
+	}
+	return encodeMessage(BINDING_SUCCESS, request.transaction, attributes, fingerprint)
+}
+
+func addrPort(addr net.Addr) netip.AddrPort {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.AddrPort()
+	case *net.TCPAddr:
+		return a.AddrPort()
+	}
+	addrPort, _ := netip.ParseAddrPort(addr.String())
+	return addrPort
+}
+
+// ServeUDP 在 UDP 上提供服务，ctx 结束后等待进行中的请求处理完成
+func ServeUDP(ctx context.Context, handler *Handler, conn net.PacketConn) error {
+	return supervisor.ServePacket(ctx, "STUN", conn, func(msg []byte, addr net.Addr) []byte {
+		return handler.Answer(msg, addrPort(addr))
+	})
+}
+
+// ServeTCP 在 TCP 上提供服务，同一连接上可以连续发送多条请求
+func ServeTCP(ctx context.Context, handler *Handler, listener net.Listener) error {
+	return supervisor.ServeConn(ctx, "STUN", listener, func(conn net.Conn) {
+		HandleConnection(handler, conn)
+	})
+}
+
+// HandleConnection 处理 TCP 连接，根据消息头部中的长度拆分消息
+func HandleConnection(handler *Handler, conn net.Conn) {
+	defer conn.Close()
+	header := make([]byte, headerLength)
+	for {
+		conn.SetReadDeadline(time.Now().Add(define.STUN_TCP_IDLE_TIMEOUT))
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		msg := make([]byte, headerLength+int(binary.BigEndian.Uint16(header[2:])))
+		copy(msg, header)
+		if _, err := io.ReadFull(conn, msg[headerLength:]); err != nil {
+			return
+		}
+		response := handler.Answer(msg, addrPort(conn.RemoteAddr()))
+		if response == nil {
+			continue
+		}
+		if _, err := conn.Write(response); err != nil {
+			log.Printf("STUN 服务发送消息时发生错误: %v\n", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package stun_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/stun"
+)
+
+var transaction = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+func buildRequest(mtype uint16, classic bool, attributes ...[]byte) []byte {
+	msg := binary.BigEndian.AppendUint16(nil, mtype)
+	msg = append(msg, 0, 0)
+	if classic {
+		msg = append(msg, 0xde, 0xad, 0xbe, 0xef)
+	} else {
+		msg = binary.BigEndian.AppendUint32(msg, stun.MAGIC_COOKIE)
+	}
+	msg = append(msg, transaction...)
+	for _, attr := range attributes {
+		msg = append(msg, attr...)
+	}
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)-20))
+	return msg
+}
+
+func attr(atype uint16, value []byte) []byte {
+	buf := binary.BigEndian.AppendUint16(nil, atype)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(value)))
+	buf = append(buf, value...)
+	for len(buf)%4 != 0 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+// parseAttributes 校验响应头部并返回属性
+func parseAttributes(t *testing.T, msg []byte, wantType uint16) map[uint16][]byte {
+	t.Helper()
+	if len(msg) < 20 || binary.BigEndian.Uint16(msg) != wantType || int(binary.BigEndian.Uint16(msg[2:])) != len(msg)-20 {
+		t.Fatalf("响应头部错误: %x", msg)
+	}
+	if !bytes.Equal(msg[8:20], transaction) {
+		t.Fatalf("响应的事务 ID 应该与请求相同: %x", msg[8:20])
+	}
+	attributes := map[uint16][]byte{}
+	for offset := 20; offset < len(msg); {
+		atype, length := binary.BigEndian.Uint16(msg[offset:]), int(binary.BigEndian.Uint16(msg[offset+2:]))
+		attributes[atype] = msg[offset+4 : offset+4+length]
+		offset += 4 + (length+3)&^3
+	}
+	return attributes
+}
+
+func decodeXORAddress(value []byte, cookie []byte) netip.AddrPort {
+	port := binary.BigEndian.Uint16(value[2:]) ^ stun.MAGIC_COOKIE>>16
+	raw := append([]byte{}, value[4:]...)
+	for i := range raw {
+		raw[i] ^= cookie[i]
+	}
+	addr, _ := netip.AddrFromSlice(raw)
+	return netip.AddrPortFrom(addr, port)
+}
+
+func TestAnswer(t *testing.T) {
+	cookie := append(binary.BigEndian.AppendUint32(nil, stun.MAGIC_COOKIE), transaction...)
+	observations := stun.NewObservations(time.Minute)
+	handler := stun.NewHandler("ip-helper", observations)
+
+	t.Run("IPv4", func(t *testing.T) {
+		remote := netip.MustParseAddrPort("192.0.2.1:54321")
+		attributes := parseAttributes(t, handler.Answer(buildRequest(stun.BINDING_REQUEST, false), remote), stun.BINDING_SUCCESS)
+		if got := decodeXORAddress(attributes[stun.ATTR_XOR_MAPPED_ADDRESS], cookie); got != remote {
+			t.Errorf("XOR-MAPPED-ADDRESS 应该为 %s，实际为 %s", remote, got)
+		}
+		if string(attributes[stun.ATTR_SOFTWARE]) != "ip-helper" {
+			t.Errorf("SOFTWARE 应该为 ip-helper，实际为 %q", attributes[stun.ATTR_SOFTWARE])
+		}
+		if _, ok := attributes[stun.ATTR_FINGERPRINT]; ok {
+			t.Error("请求没有 FINGERPRINT 时响应不应该包含 FINGERPRINT")
+		}
+		if !observations.Seen(remote) {
+			t.Error("应该记录观察到的映射地址")
+		}
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		remote := netip.MustParseAddrPort("[2001:db8::1]:3478")
+		attributes := parseAttributes(t, handler.Answer(buildRequest(stun.BINDING_REQUEST, false), remote), stun.BINDING_SUCCESS)
+		if got := decodeXORAddress(attributes[stun.ATTR_XOR_MAPPED_ADDRESS], cookie); got != remote {
+			t.Errorf("XOR-MAPPED-ADDRESS 应该为 %s，实际为 %s", remote, got)
+		}
+	})
+
+	t.Run("Fingerprint", func(t *testing.T) {
+		request := buildRequest(stun.BINDING_REQUEST, false, attr(stun.ATTR_FINGERPRINT, []byte{0, 0, 0, 0}))
+		response := handler.Answer(request, netip.MustParseAddrPort("192.0.2.1:1"))
+		attributes := parseAttributes(t, response, stun.BINDING_SUCCESS)
+		want := crc32.ChecksumIEEE(response[:len(response)-8]) ^ 0x5354554e
+		if got := binary.BigEndian.Uint32(attributes[stun.ATTR_FINGERPRINT]); got != want {
+			t.Errorf("FINGERPRINT 应该为 %x，实际为 %x", want, got)
+		}
+	})
+
+	t.Run("RFC 3489 client", func(t *testing.T) {
+		response := handler.Answer(buildRequest(stun.BINDING_REQUEST, true), netip.MustParseAddrPort("192.0.2.1:5060"))
+		attributes := parseAttributes(t, response, stun.BINDING_SUCCESS)
+		want := []byte{0, 1, 0x13, 0xc4, 192, 0, 2, 1}
+		if !bytes.Equal(attributes[stun.ATTR_MAPPED_ADDRESS], want) {
+			t.Errorf("MAPPED-ADDRESS 应该为 %v，实际为 %v", want, attributes[stun.ATTR_MAPPED_ADDRESS])
+		}
+	})
+
+	t.Run("Unknown attribute", func(t *testing.T) {
+		request := buildRequest(stun.BINDING_REQUEST, false, attr(0x0006, []byte("user")))
+		attributes := parseAttributes(t, handler.Answer(request, netip.MustParseAddrPort("192.0.2.1:1")), stun.BINDING_ERROR)
+		if code := attributes[stun.ATTR_ERROR_CODE]; len(code) < 4 || code[2] != 4 || code[3] != 20 {
+			t.Errorf("应该返回 420 错误，实际为 %v", code)
+		}
+		if !bytes.Equal(attributes[stun.ATTR_UNKNOWN_ATTRIBUTES], []byte{0, 6}) {
+			t.Errorf("UNKNOWN-ATTRIBUTES 应该包含 0x0006，实际为 %v", attributes[stun.ATTR_UNKNOWN_ATTRIBUTES])
+		}
+	})
+
+	t.Run("Ignored messages", func(t *testing.T) {
+		remote := netip.MustParseAddrPort("192.0.2.1:1")
+		if handler.Answer(buildRequest(stun.METHOD_BINDING|stun.CLASS_INDICATION, false), remote) != nil {
+			t.Error("不应该应答 indication")
+		}
+		if handler.Answer([]byte("GET / HTTP/1.1\r\n\r\n"), remote) != nil {
+			t.Error("不应该应答非 STUN 消息")
+		}
+	})
+
+	t.Run("Without SOFTWARE", func(t *testing.T) {
+		attributes := parseAttributes(t, stun.NewHandler("", nil).Answer(buildRequest(stun.BINDING_REQUEST, false), netip.MustParseAddrPort("192.0.2.1:1")), stun.BINDING_SUCCESS)
+		if _, ok := attributes[stun.ATTR_SOFTWARE]; ok {
+			t.Error("SOFTWARE 为空时不应该发送")
+		}
+	})
+}
+
+func TestObservations(t *testing.T) {
+	observations := stun.NewObservations(50 * time.Millisecond)
+	observations.Record(netip.MustParseAddrPort("192.0.2.1:2000"))
+	observations.Record(netip.MustParseAddrPort("192.0.2.1:1000"))
+	observations.Record(netip.MustParseAddrPort("198.51.100.1:1000"))
+
+	got := observations.ByIP(netip.MustParseAddr("192.0.2.1"))
+	if len(got) != 2 || got[0].Port() != 1000 || got[1].Port() != 2000 {
+		t.Errorf("应该按端口排序返回同一 IP 的映射地址，实际为 %v", got)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if observations.Seen(netip.MustParseAddrPort("192.0.2.1:1000")) || len(observations.ByIP(netip.MustParseAddr("192.0.2.1"))) != 0 {
+		t.Error("过期的映射地址不应该返回")
+	}
+}
+
+func TestServe(t *testing.T) {
+	handler := stun.NewHandler("ip-helper", nil)
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听 UDP 失败: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听 TCP 失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 2)
+	go func() { done <- stun.ServeUDP(ctx, handler, packetConn) }()
+	go func() { done <- stun.ServeTCP(ctx, handler, listener) }()
+
+	cookie := append(binary.BigEndian.AppendUint32(nil, stun.MAGIC_COOKIE), transaction...)
+	for _, network := range []string{"udp", "tcp"} {
+		addr := packetConn.LocalAddr().String()
+		if network == "tcp" {
+			addr = listener.Addr().String()
+		}
+		conn, err := net.Dial(network, addr)
+		if err != nil {
+			t.Fatalf("连接 %s 失败: %v", network, err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		if _, err := conn.Write(buildRequest(stun.BINDING_REQUEST, false)); err != nil {
+			t.Fatalf("发送 %s 请求失败: %v", network, err)
+		}
+
+		var response []byte
+		if network == "udp" {
+			buf := make([]byte, 512)
+			n, err := conn.Read(buf)
+			if err != nil {
+				t.Fatalf("读取 udp 响应失败: %v", err)
+			}
+			response = buf[:n]
+		} else {
+			header := make([]byte, 20)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				t.Fatalf("读取 tcp 响应失败: %v", err)
+			}
+			response = make([]byte, 20+int(binary.BigEndian.Uint16(header[2:])))
+			copy(response, header)
+			if _, err := io.ReadFull(conn, response[20:]); err != nil {
+				t.Fatalf("读取 tcp 响应失败: %v", err)
+			}
+		}
+		attributes := parseAttributes(t, response, stun.BINDING_SUCCESS)
+		if got := decodeXORAddress(attributes[stun.ATTR_XOR_MAPPED_ADDRESS], cookie); got.String() != conn.LocalAddr().String() {
+			t.Errorf("%s 映射地址应该为 %s，实际为 %s", network, conn.LocalAddr(), got)
+		}
+	}
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("服务不应该返回错误，实际为 %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("服务应该在 ctx 结束后返回")
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 进程退出状态码
+const (
+	EXIT_OK            = 0
+	EXIT_STARTUP_ERROR = 1
+	EXIT_SERVICE_ERROR = 2
+	EXIT_DRAIN_TIMEOUT = 3
+)
+
+// ServeFunc 处理请求直到 ctx 结束，返回前应该等待进行中的连接处理完成
+type ServeFunc func(ctx context.Context) error
+
+// Service 由监督器统一管理生命周期的服务
+type Service struct {
+	Name string
+	// Listen 绑定监听地址，失败时作为启动错误返回
+	Listen func() (ServeFunc, error)
+}
+
+// TCP 创建监听 TCP 地址的服务
+func TCP(name string, addr string, serve func(ctx context.Context, listener net.Listener) error) Service {
+	return Service{
+		Name: name,
+		Listen: func() (ServeFunc, error) {
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("%s 服务器已启动，监听地址: %s\n", name, listener.Addr())
+			return func(ctx context.Context) error {
+				return serve(ctx, listener)
+			}, nil
+		},
+	}
+}
+
+// UDP 创建监听 UDP 地址的服务
+func UDP(name string, addr string, serve func(ctx context.Context, conn net.PacketConn) error) Service {
+	return Service{
+		Name: name,
+		Listen: func() (ServeFunc, error) {
+			conn, err := net.ListenPacket("udp", addr)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("%s 服务器已启动，监听地址: %s/udp\n", name, conn.LocalAddr())
+			return func(ctx context.Context) error {
+				return serve(ctx, conn)
+			}, nil
+		},
+	}
+}
+
+// Run 启动所有服务并等待 ctx 结束或任一服务退出，然后在 timeout 内等待所有服务处理完进行中的连接
+func Run(ctx context.Context, timeout time.Duration, services ...Service) int {
+	serves := make([]ServeFunc, 0, len(services))
+	for _, service := range services {
+		serve, err := service.Listen()
+		if err != nil {
+			log.Printf("%s 服务器启动失败: %v\n", service.Name, err)
+			// 释放已经绑定的监听地址
+			cancelled, cancel := context.WithCancel(context.Background())
+			cancel()
+			for _, started := range serves {
+				started(cancelled)
+			}
+			return EXIT_STARTUP_ERROR
+		}
+		serves = append(serves, serve)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(serves))
+	for i, serve := range serves {
+		go func(name string, serve ServeFunc) {
+			results <- result{name: name, err: serve(runCtx)}
+		}(services[i].Name, serve)
+	}
+
+	exitCode := EXIT_OK
+	remaining := len(serves)
+	select {
+	case <-ctx.Done():
+		log.Println("收到退出信号，正在等待进行中的连接处理完成")
+	case r := <-results:
+		remaining--
+		if r.err == nil {
+			r.err = errors.New("服务意外停止")
+		}
+		log.Printf("%s 服务器异常退出: %v\n", r.name, r.err)
+		exitCode = EXIT_SERVICE_ERROR
+	}
+	cancel()
+
+	deadline := time.After(timeout)
+	for ; remaining > 0; remaining-- {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				log.Printf("%s 服务器停止时发生错误: %v\n", r.name, r.err)
+				if exitCode == EXIT_OK {
+					exitCode = EXIT_SERVICE_ERROR
+				}
+			}
+		case <-deadline:
+			log.Printf("等待连接处理超时 (%s)，强制退出\n", timeout)
+			return EXIT_DRAIN_TIMEOUT
+		}
+	}
+	log.Println("所有服务已停止")
+	return exitCode
+}
+
+// drainConn 在服务停止后让所有读取立即超时，避免 handler 重新设置的超时时间阻塞退出
+type drainConn struct {
+	net.Conn
+	draining *atomic.Bool
+}
+
+func (c *drainConn) SetDeadline(t time.Time) error {
+	if c.draining.Load() {
+		t = time.Now()
+	}
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *drainConn) SetReadDeadline(t time.Time) error {
+	if c.draining.Load() {
+		t = time.Now()
+	}
+	return c.Conn.SetReadDeadline(t)
+}
+
+// Proxied 转发底层连接的 PROXY protocol 状态
+func (c *drainConn) Proxied() bool {
+	proxied, ok := c.Conn.(interface{ Proxied() bool })
+	return ok && proxied.Proxied()
+}
+
+// ServeConn 接受连接并交给 handler 处理，ctx 结束后停止接受新连接，
+// 中断空闲连接的读取并等待所有 handler 返回
+func ServeConn(ctx context.Context, name string, listener net.Listener, handler func(conn net.Conn)) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var draining atomic.Bool
+	conns := map[net.Conn]struct{}{}
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+			return
+		}
+		listener.Close()
+		draining.Store(true)
+		mu.Lock()
+		for conn := range conns {
+			conn.SetReadDeadline(time.Now())
+		}
+		mu.Unlock()
+	}()
+
+	var serveErr error
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if errors.Is(err, net.ErrClosed) {
+				serveErr = err
+				break
+			}
+			log.Printf("%s 服务器接受连接时发生错误: %v\n", name, err)
+			continue
+		}
+
+		conn = &drainConn{Conn: conn, draining: &draining}
+		mu.Lock()
+		conns[conn] = struct{}{}
+		if draining.Load() {
+			conn.SetReadDeadline(time.Now())
+		}
+		mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				mu.Lock()
+				delete(conns, conn)
+				mu.Unlock()
+			}()
+			handler(conn)
+		}()
+	}
+
+	wg.Wait()
+	return serveErr
+}
+
+// ServePacket 接收数据包并交给 handler 处理，handler 返回非空内容时发送回复，
+// ctx 结束后关闭连接并等待所有 handler 返回
+func ServePacket(ctx context.Context, name string, conn net.PacketConn, handler func(msg []byte, addr net.Addr) []byte) error {
+	var wg sync.WaitGroup
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stopped:
+		}
+	}()
+
+	var serveErr error
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if errors.Is(err, net.ErrClosed) {
+				serveErr = err
+				break
+			}
+			log.Printf("%s 服务器读取数据包时发生错误: %v\n", name, err)
+			continue
+		}
+		msg := append([]byte{}, buf[:n]...)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if reply := handler(msg, addr); reply != nil {
+				if _, err := conn.WriteTo(reply, addr); err != nil && ctx.Err() == nil {
+					log.Printf("%s 服务发送消息时发生错误: %v\n", name, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return serveErr
+}
@@ -0,0 +1,152 @@
+package supervisor_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/supervisor"
+)
+
+// connService 创建使用 ServeConn 处理连接的测试服务
+func connService(name string, addr string, handler func(conn net.Conn)) supervisor.Service {
+	return supervisor.TCP(name, addr, func(ctx context.Context, listener net.Listener) error {
+		return supervisor.ServeConn(ctx, name, listener, handler)
+	})
+}
+
+func idleHandler(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 1)
+	for {
+		conn.SetReadDeadline(time.Now().Add(time.Minute))
+		if _, err := conn.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+func TestRunStartupError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	defer listener.Close()
+
+	code := supervisor.Run(context.Background(), time.Second,
+		connService("A", "127.0.0.1:0", idleHandler),
+		connService("B", listener.Addr().String(), idleHandler),
+	)
+	if code != supervisor.EXIT_STARTUP_ERROR {
+		t.Errorf("端口被占用时应该返回 %d，实际为 %d", supervisor.EXIT_STARTUP_ERROR, code)
+	}
+}
+
+func TestRunGracefulShutdown(t *testing.T) {
+	addr := make(chan string, 1)
+	service := supervisor.TCP("TEST", "127.0.0.1:0", func(ctx context.Context, listener net.Listener) error {
+		addr <- listener.Addr().String()
+		return supervisor.ServeConn(ctx, "TEST", listener, idleHandler)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int, 1)
+	go func() {
+		done <- supervisor.Run(ctx, 2*time.Second, service)
+	}()
+
+	conn, err := net.Dial("tcp", <-addr)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+	// 等待连接被接受
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	select {
+	case code := <-done:
+		if code != supervisor.EXIT_OK {
+			t.Errorf("正常退出应该返回 %d，实际为 %d", supervisor.EXIT_OK, code)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("空闲连接应该被中断")
+	}
+}
+
+func TestRunServiceError(t *testing.T) {
+	failing := supervisor.Service{
+		Name: "FAIL",
+		Listen: func() (supervisor.ServeFunc, error) {
+			return func(ctx context.Context) error {
+				return errors.New("broken")
+			}, nil
+		},
+	}
+	code := supervisor.Run(context.Background(), time.Second, failing, connService("OK", "127.0.0.1:0", idleHandler))
+	if code != supervisor.EXIT_SERVICE_ERROR {
+		t.Errorf("服务异常退出时应该返回 %d，实际为 %d", supervisor.EXIT_SERVICE_ERROR, code)
+	}
+}
+
+func TestRunDrainTimeout(t *testing.T) {
+	stuck := supervisor.Service{
+		Name: "STUCK",
+		Listen: func() (supervisor.ServeFunc, error) {
+			return func(ctx context.Context) error {
+				<-ctx.Done()
+				time.Sleep(time.Second)
+				return nil
+			}, nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	code := supervisor.Run(ctx, 50*time.Millisecond, stuck)
+	if code != supervisor.EXIT_DRAIN_TIMEOUT {
+		t.Errorf("等待超时应该返回 %d，实际为 %d", supervisor.EXIT_DRAIN_TIMEOUT, code)
+	}
+}
+
+func TestServePacket(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- supervisor.ServePacket(ctx, "TEST", conn, func(msg []byte, addr net.Addr) []byte {
+			if string(msg) == "ignore" {
+				return nil
+			}
+			return append([]byte("echo "), msg...)
+		})
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(2 * time.Second))
+	client.Write([]byte("ignore"))
+	client.Write([]byte("ping"))
+	buf := make([]byte, 64)
+	n, err := client.Read(buf)
+	if err != nil || string(buf[:n]) != "echo ping" {
+		t.Errorf("应该只回复需要回复的数据包，实际为 %q %v", buf[:n], err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServePacket 不应该返回错误，实际为 %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ServePacket 应该在 ctx 结束后返回")
+	}
+}
@@ -0,0 +1,263 @@
+package telnet
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/response"
+)
+
+// TELNET 协议命令，参考 RFC 854
+const (
+	IAC  = 255
+	DONT = 254
+	DO   = 253
+	WONT = 252
+	WILL = 251
+	SB   = 250
+	IP   = 244
+	SE   = 240
+
+	OPT_ECHO              = 1
+	OPT_SUPPRESS_GO_AHEAD = 3
+)
+
+const PROMPT = "> "
+
+const HELP = "可用命令:\r\n" +
+	"  me            查询当前连接的 IP\r\n" +
+	"  lookup <ip>   查询指定的 IP\r\n" +
+	"  json          使用 JSON 格式输出\r\n" +
+	"  text          使用文本格式输出\r\n" +
+	"  lang [代码]   查看支持的语言或切换查询语言\r\n" +
+	"  help          显示帮助\r\n" +
+	"  quit          断开连接"
+
+var (
+	errLineTooLong = errors.New("输入过长")
+	errInterrupt   = errors.New("客户端中断")
+)
+
+// session 一个 TELNET 连接的交互状态
+type session struct {
+	ipdb     ipInfo.Provider
+	conn     net.Conn
+	reader   *bufio.Reader
+	clientIP string
+
+	language string
+	text     bool
+	// 是否已同意客户端关闭 go-ahead
+	suppressGoAhead bool
+}
+
+func newSession(ipdb ipInfo.Provider, conn net.Conn) *session {
+	return &session{
+		ipdb:     ipdb,
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+		clientIP: fn.GetBaseIP(conn.RemoteAddr().String()),
+	}
+}
+
+func (s *session) render(ip string) []byte {
+	var languages []string
+	if s.language != "" {
+		languages = []string{s.language}
+	}
+	result := ipInfo.Lookup(s.ipdb, ip, languages...)
+	if s.text {
+		return bytes.TrimSuffix(bytes.ReplaceAll(response.RenderText(ip, result), []byte("\n"), []byte("\r\n")), []byte("\r\n"))
+	}
+	return response.RenderJSON(ip, result)
+}
+
+// negotiate 回应客户端的选项协商，只启用 SUPPRESS-GO-AHEAD，回显由客户端在本地处理
+func (s *session) negotiate(command byte, option byte) {
+	var reply []byte
+	switch command {
+	case DO:
+		if option == OPT_SUPPRESS_GO_AHEAD {
+			if !s.suppressGoAhead {
+				s.suppressGoAhead = true
+				reply = []byte{IAC, WILL, option}
+			}
+		} else {
+			reply = []byte{IAC, WONT, option}
+		}
+	case DONT:
+		if option == OPT_SUPPRESS_GO_AHEAD && s.suppressGoAhead {
+			s.suppressGoAhead = false
+			reply = []byte{IAC, WONT, option}
+		}
+	case WILL:
+		reply = []byte{IAC, DONT, option}
+	}
+	if reply != nil {
+		s.conn.Write(reply)
+	}
+}
+
+// readLine 读取一行输入并过滤 TELNET 命令
+func (s *session) readLine() (string, error) {
+	line := []byte{}
+	tooLong := false
+	for {
+		b, err := s.reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case IAC:
+			command, err := s.reader.ReadByte()
+			if err != nil {
+				return "", err
+			}
+			switch command {
+			case IAC:
+				// 转义的 0xFF 数据，不是有效的命令字符
+				continue
+			case DO, DONT, WILL, WONT:
+				option, err := s.reader.ReadByte()
+				if err != nil {
+					return "", err
+				}
+				s.negotiate(command, option)
+			case SB:
+				// 跳过子协商直到 IAC SE
+				for previous := byte(0); ; {
+					b, err := s.reader.ReadByte()
+					if err != nil {
+						return "", err
+					}
+					if previous == IAC && b == SE {
+						break
+					}
+					previous = b
+				}
+			case IP:
+				return "", errInterrupt
+			}
+		case '\r', '\n':
+			if b == '\r' {
+				if next, err := s.reader.Peek(1); err == nil && (next[0] == '\n' || next[0] == 0) {
+					s.reader.ReadByte()
+				}
+			}
+			if tooLong {
+				return "", errLineTooLong
+			}
+			return string(line), nil
+		case '\b', 127:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		default:
+			if len(line) >= define.TELNET_MAX_LINE_LENGTH {
+				tooLong = true
+				continue
+			}
+			line = append(line, b)
+		}
+	}
+}
+
+// waitForInput 等待客户端输入，超时不视为错误
+func (s *session) waitForInput(timeout time.Duration) error {
+	s.conn.SetReadDeadline(time.Now().Add(timeout))
+	_, err := s.reader.Peek(1)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return nil
+	}
+	return err
+}
+
+// run 立即输出当前连接的查询结果，之后进入交互模式直到客户端退出或空闲超时
+func (s *session) run() {
+	if !send(s.conn, s.render(s.clientIP)) {
+		return
+	}
+	if err := s.waitForInput(define.TELNET_PROMPT_DELAY); err != nil {
+		return
+	}
+	s.interact()
+}
+
+// interact 进入交互模式，处理命令直到客户端退出或空闲超时
+func (s *session) interact() {
+	s.suppressGoAhead = true
+	greeting := append([]byte{IAC, WILL, OPT_SUPPRESS_GO_AHEAD}, "输入 help 查看可用命令\r\n"+PROMPT...)
+	if !write(s.conn, greeting) {
+		return
+	}
+
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(define.TELNET_IDLE_TIMEOUT))
+		line, err := s.readLine()
+		if err == errLineTooLong {
+			if !write(s.conn, []byte("输入过长，单行最多 "+strconv.Itoa(define.TELNET_MAX_LINE_LENGTH)+" 字节\r\n"+PROMPT)) {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				send(s.conn, []byte("\r\n空闲超时，连接已关闭"))
+			}
+			return
+		}
+
+		output, ok := s.execute(strings.Fields(line))
+		if !ok {
+			send(s.conn, []byte("再见"))
+			return
+		}
+		if output != nil {
+			output = append(output, "\r\n"...)
+		}
+		if !write(s.conn, append(output, PROMPT...)) {
+			return
+		}
+	}
+}
+
+// execute 执行一条命令，返回需要输出的内容，ok 为 false 时断开连接
+func (s *session) execute(fields []string) (output []byte, ok bool) {
+	if len(fields) == 0 {
+		return nil, true
+	}
+	switch strings.ToLower(fields[0]) {
+	case "me":
+		return s.render(s.clientIP), true
+	case "lookup":
+		if len(fields) != 2 || !fn.IsValidIPAddress(fields[1]) {
+			return []byte("用法: lookup <ip>，请输入有效的 IP 地址"), true
+		}
+		return s.render(fields[1]), true
+	case "json":
+		s.text = false
+		return []byte("已切换为 JSON 格式"), true
+	case "text":
+		s.text = true
+		return []byte("已切换为文本格式"), true
+	case "lang":
+		if len(fields) == 1 {
+			return []byte("支持的语言: " + strings.Join(s.ipdb.Languages(), ", ")), true
+		}
+		s.language = fields[1]
+		return s.render(s.clientIP), true
+	case "help", "?":
+		return []byte(HELP), true
+	case "quit", "exit":
+		return nil, false
+	}
+	return []byte("未知命令，输入 help 查看可用命令"), true
+}
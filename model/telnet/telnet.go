@@ -2,52 +2,55 @@ package telnet
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"log"
 	"net"
 
-	"github.com/soulteary/ip-helper/model/fn"
+	"github.com/soulteary/ip-helper/model/banner"
 	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
-	"github.com/soulteary/ip-helper/model/response"
 )
 
-func Server(ipdb *ipInfo.IPDB, port string) error {
-	listener, err := net.Listen("tcp", port)
-	if err != nil {
-		return fmt.Errorf("TELNET 服务器启动失败: %v", err)
-	}
-	defer listener.Close()
+func Server(ipdb ipInfo.Provider, port string) error {
+	return banner.Server("TELNET", ipdb, port, HandleConnection)
+}
 
-	info := ipdb.FindByIPIP("127.0.0.1")
-	if len(info) == 0 {
-		return fmt.Errorf("IP 数据库加载失败")
-	}
+// Serve 在已绑定的监听器上提供服务，ctx 结束后等待进行中的连接处理完成
+func Serve(ctx context.Context, ipdb ipInfo.Provider, listener net.Listener) error {
+	return banner.Serve(ctx, "TELNET", ipdb, listener, HandleConnection)
+}
 
-	log.Println("TELNET 服务器已启动，监听端口:", port)
+// HandleConnection 立即输出当前连接的查询结果，只读取第一行的脚本可以直接使用，
+// 之后进入交互模式，支持 me、lookup、json、text、lang、help、quit 等命令
+func HandleConnection(ipdb ipInfo.Provider, conn net.Conn) {
+	defer conn.Close()
+	newSession(ipdb, conn).run()
+}
 
-	for {
-		conn, err := listener.Accept()
-		if err != nil {
-			log.Printf("TELNET 服务器接受连接时发生错误: %v\n", err)
-			continue
-		}
-		go HandleConnection(ipdb, conn)
-	}
+// Greet 只输出当前连接的查询结果，用于在确定客户端协议之前先行应答
+func Greet(ipdb ipInfo.Provider, conn net.Conn) bool {
+	s := newSession(ipdb, conn)
+	return send(conn, s.render(s.clientIP))
 }
 
-func HandleConnection(ipdb *ipInfo.IPDB, conn net.Conn) {
+// Resume 跳过首行的查询结果直接进入交互模式，用于已经向客户端输出过查询结果的连接
+func Resume(ipdb ipInfo.Provider, conn net.Conn) {
 	defer conn.Close()
+	newSession(ipdb, conn).interact()
+}
 
-	clientIP := fn.GetBaseIP(conn.RemoteAddr().String())
-	info := ipdb.FindByIPIP(clientIP)
-
+func send(conn net.Conn, message []byte) bool {
 	sendBuf := [][]byte{
-		response.RenderJSON(clientIP, info),
+		message,
 		[]byte("\r\n"),
 	}
-	_, err := conn.Write(bytes.Join(sendBuf, []byte("")))
+	return write(conn, bytes.Join(sendBuf, []byte("")))
+}
+
+func write(conn net.Conn, data []byte) bool {
+	_, err := conn.Write(data)
 	if err != nil {
 		fmt.Printf("TELNET 服务发送消息时发生错误: %v\n", err)
-		return
+		return false
 	}
+	return true
 }
@@ -1,7 +1,9 @@
 package telnet_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"net"
@@ -11,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/soulteary/ip-helper/model/define"
 	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
 	"github.com/soulteary/ip-helper/model/telnet"
 )
@@ -235,3 +238,191 @@ func TestInvalidIPDB(t *testing.T) {
 		t.Error("服务器应该因为无效的 IPDB 而失败，但没有")
 	}
 }
+
+// languageProvider 根据语言返回不同结果的测试数据库
+type languageProvider struct{}
+
+func (languageProvider) Name() string { return "test" }
+
+func (languageProvider) Languages() []string { return []string{"CN", "EN"} }
+
+func (languageProvider) Find(ip string, languages ...string) (ipInfo.Result, error) {
+	language := ipInfo.MatchLanguage([]string{"CN", "EN"}, languages)
+	if language == "EN" {
+		return ipInfo.Result{Country: "China", Language: language, Info: []string{"China"}}, nil
+	}
+	return ipInfo.Result{Country: "中国", Language: "CN", Info: []string{"中国"}}, nil
+}
+
+// telnetClient 读取交互模式的输出直到出现提示符
+type telnetClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newTelnetClient(t *testing.T, conn net.Conn) *telnetClient {
+	return &telnetClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *telnetClient) readLine() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("读取响应失败: %v", err)
+	}
+	return line
+}
+
+func (c *telnetClient) readPrompt() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	output := []byte{}
+	// 提示符总是在单独的一行开始
+	for string(output) != telnet.PROMPT && !bytes.HasSuffix(output, []byte("\n"+telnet.PROMPT)) {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			c.t.Fatalf("读取响应失败: %v, 已读取: %q", err, output)
+		}
+		output = append(output, b)
+	}
+	return string(output)
+}
+
+func (c *telnetClient) command(command string) string {
+	c.t.Helper()
+	fmt.Fprint(c.conn, command+"\r\n")
+	return c.readPrompt()
+}
+
+// TestLangCommand 测试 lang 命令切换语言
+func TestLangCommand(t *testing.T) {
+	server, conn := net.Pipe()
+	defer conn.Close()
+	go telnet.HandleConnection(languageProvider{}, server)
+	client := newTelnetClient(t, conn)
+
+	if line := client.readLine(); !strings.Contains(line, "中国") {
+		t.Errorf("默认应该使用中文，得到: %s", line)
+	}
+	client.readPrompt()
+
+	if output := client.command("lang en"); !strings.Contains(output, "China") || !strings.Contains(output, `"language":"EN"`) {
+		t.Errorf("lang en 应该返回英文结果，得到: %s", output)
+	}
+	if output := client.command("me"); !strings.Contains(output, "China") {
+		t.Errorf("切换语言后 me 应该继续使用英文，得到: %s", output)
+	}
+	if output := client.command("LANG"); !strings.Contains(output, "CN, EN") {
+		t.Errorf("LANG 应该列出支持的语言，得到: %s", output)
+	}
+	if output := client.command("LANG fr"); !strings.Contains(output, "中国") {
+		t.Errorf("不支持的语言应该回退到默认语言，得到: %s", output)
+	}
+	if output := client.command("HELLO"); !strings.Contains(output, "未知命令") {
+		t.Errorf("未知命令应该返回提示，得到: %s", output)
+	}
+
+	fmt.Fprint(conn, "QUIT\r\n")
+	if line := client.readLine(); !strings.Contains(line, "再见") {
+		t.Errorf("QUIT 应该返回告别信息，得到: %s", line)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := client.reader.ReadByte(); err == nil {
+		t.Error("QUIT 后连接应该关闭")
+	}
+}
+
+// TestInteractiveSession 测试交互模式的命令与 TELNET 协商
+func TestInteractiveSession(t *testing.T) {
+	server, conn := net.Pipe()
+	defer conn.Close()
+	go telnet.HandleConnection(languageProvider{}, server)
+	client := newTelnetClient(t, conn)
+
+	// 第一行只包含查询结果，协商数据和提示符在之后发送
+	if line := client.readLine(); !strings.HasPrefix(line, "{") || !strings.HasSuffix(line, "}\r\n") {
+		t.Errorf("第一行应该是 JSON 查询结果，得到: %q", line)
+	}
+	greeting := client.readPrompt()
+	if !strings.HasPrefix(greeting, string([]byte{telnet.IAC, telnet.WILL, telnet.OPT_SUPPRESS_GO_AHEAD})) || !strings.Contains(greeting, "help") {
+		t.Errorf("进入交互模式时应该协商 SUPPRESS-GO-AHEAD 并提示 help，得到: %q", greeting)
+	}
+
+	// 回显由客户端处理，其他选项一律拒绝，已启用的选项不再重复回应
+	negotiation := []byte{telnet.IAC, telnet.DO, telnet.OPT_ECHO, telnet.IAC, telnet.WILL, 31, telnet.IAC, telnet.DO, telnet.OPT_SUPPRESS_GO_AHEAD}
+	negotiation = append(negotiation, telnet.IAC, telnet.SB, 31, 0, 80, 0, 24, telnet.IAC, telnet.SE)
+	output := client.command(string(negotiation) + "m\bme")
+	want := string([]byte{telnet.IAC, telnet.WONT, telnet.OPT_ECHO, telnet.IAC, telnet.DONT, 31}) + "{"
+	if !strings.HasPrefix(output, want) {
+		t.Errorf("协商回应错误，得到: %q", output)
+	}
+
+	if output := client.command("help"); !strings.Contains(output, "lookup <ip>") {
+		t.Errorf("help 应该列出可用命令，得到: %s", output)
+	}
+	if output := client.command("lookup 8.8.8.8"); !strings.Contains(output, `"ip":"8.8.8.8"`) {
+		t.Errorf("lookup 应该查询指定的 IP，得到: %s", output)
+	}
+	if output := client.command("lookup example.com"); !strings.Contains(output, "用法") {
+		t.Errorf("lookup 应该校验 IP 地址，得到: %s", output)
+	}
+	client.command("text")
+	if output := client.command("lookup 8.8.8.8"); output != "8.8.8.8\r\n中国\r\n> " {
+		t.Errorf("text 模式应该输出文本，得到: %q", output)
+	}
+	client.command("json")
+	if output := client.command("me"); !strings.HasPrefix(output, "{") {
+		t.Errorf("json 模式应该输出 JSON，得到: %q", output)
+	}
+	if output := client.command(""); output != telnet.PROMPT {
+		t.Errorf("空行只应该输出提示符，得到: %q", output)
+	}
+	if output := client.command(strings.Repeat("a", define.TELNET_MAX_LINE_LENGTH+1)); !strings.Contains(output, "输入过长") {
+		t.Errorf("过长的输入应该被拒绝，得到: %s", output)
+	}
+
+	fmt.Fprint(conn, "quit\r\n")
+	client.readLine()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := client.reader.ReadByte(); err == nil {
+		t.Error("quit 后连接应该关闭")
+	}
+}
+
+// TestServeShutdown 测试停止服务时会中断空闲连接并等待处理完成
+func TestServeShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- telnet.Serve(ctx, languageProvider{}, listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+		t.Fatalf("读取响应失败: %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve 不应该返回错误，实际为 %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve 应该在连接处理完成后返回")
+	}
+	if _, err := net.DialTimeout("tcp", listener.Addr().String(), 100*time.Millisecond); err == nil {
+		t.Error("停止服务后不应该继续接受连接")
+	}
+}
@@ -0,0 +1,65 @@
+// Package testutil 提供各协议测试共用的测试数据库、监听和连接辅助函数
+package testutil
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+// TIMEOUT 测试连接的读写超时时间，以及等待服务退出的时间
+const TIMEOUT = 2 * time.Second
+
+// MemoryProvider 返回测试使用的内存数据库，127.0.0.0/8 为 `本机地址`，192.0.2.0/24 为 `测试网络`
+func MemoryProvider(t testing.TB) *ipInfo.MemoryProvider {
+	t.Helper()
+	memory, err := ipInfo.NewMemoryProvider("memory", map[string][]string{
+		"127.0.0.0/8":  {"本机地址"},
+		"192.0.2.0/24": {"测试网络"},
+	})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	return memory
+}
+
+// Serve 在 127.0.0.1 的随机端口上运行服务并返回监听地址，
+// 测试结束时取消 ctx，并确认服务没有返回错误
+func Serve(t testing.TB, serve func(ctx context.Context, listener net.Listener) error) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, listener) }()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("服务不应该返回错误，实际为 %v", err)
+			}
+		case <-time.After(TIMEOUT):
+			t.Error("服务应该在 ctx 结束后返回")
+		}
+	})
+	return listener.Addr().String()
+}
+
+// Dial 连接服务并设置读写超时，测试结束时关闭连接
+func Dial(t testing.TB, addr string) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(TIMEOUT))
+	return conn
+}
@@ -0,0 +1,148 @@
+package web
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	static "github.com/soulteary/gin-static"
+
+	"github.com/soulteary/ip-helper/model/define"
+)
+
+// 部分系统没有 mime.types，补充标准库未内置的常用类型，避免依赖内容探测
+func init() {
+	for ext, contentType := range map[string]string{
+		".ico":         "image/x-icon",
+		".map":         "application/json",
+		".txt":         "text/plain; charset=utf-8",
+		".webmanifest": "application/manifest+json",
+		".woff":        "font/woff",
+		".woff2":       "font/woff2",
+	} {
+		mime.AddExtensionType(ext, contentType)
+	}
+}
+
+// fingerprintPattern 匹配带有内容指纹的文件名，例如 `app.3f9a2c1b.js` 或 `app-3f9a2c1b.css`
+var fingerprintPattern = regexp.MustCompile(`[.-][0-9a-fA-F]{8,64}\.[0-9A-Za-z]+$`)
+
+type assetKey struct {
+	layer   int
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// Assets 按顺序在多个文件系统中查找静态资源，靠前的优先，只提供普通文件
+type Assets struct {
+	layers []fs.FS
+
+	mu    sync.Mutex
+	etags map[assetKey]string
+}
+
+// NewAssets 创建静态资源，为 nil 的文件系统会被忽略
+func NewAssets(layers ...fs.FS) *Assets {
+	a := &Assets{etags: map[assetKey]string{}}
+	for _, layer := range layers {
+		if layer != nil {
+			a.layers = append(a.layers, layer)
+		}
+	}
+	return a
+}
+
+// find 返回资源所在的文件系统，目录以及不合法的路径视为不存在
+func (a *Assets) find(name string) (int, string, fs.FileInfo, bool) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" || !fs.ValidPath(name) {
+		return 0, "", nil, false
+	}
+	for i, layer := range a.layers {
+		if info, err := fs.Stat(layer, name); err == nil && info.Mode().IsRegular() {
+			return i, name, info, true
+		}
+	}
+	return 0, "", nil, false
+}
+
+// Open 实现 http.FileSystem
+func (a *Assets) Open(name string) (http.File, error) {
+	i, _, _, ok := a.find(name)
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return http.FS(a.layers[i]).Open(name)
+}
+
+// Exists 实现 static.ServeFileSystem
+func (a *Assets) Exists(prefix string, name string) bool {
+	p := strings.TrimPrefix(name, prefix)
+	if len(p) == len(name) {
+		return false
+	}
+	_, _, _, ok := a.find(p)
+	return ok
+}
+
+// etag 根据文件内容计算 ETag，按文件大小和修改时间缓存结果
+func (a *Assets) etag(layer int, name string, info fs.FileInfo) (string, error) {
+	key := assetKey{layer, name, info.Size(), info.ModTime()}
+	a.mu.Lock()
+	etag, ok := a.etags[key]
+	a.mu.Unlock()
+	if ok {
+		return etag, nil
+	}
+
+	file, err := a.layers[layer].Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	etag = fmt.Sprintf(`"%x"`, hash.Sum(nil)[:16])
+
+	a.mu.Lock()
+	a.etags[key] = etag
+	a.mu.Unlock()
+	return etag, nil
+}
+
+// ServeAssets 提供静态资源，带有内容指纹的文件长期缓存，其他文件过期后通过 ETag 重新验证
+func ServeAssets(assets *Assets) gin.HandlerFunc {
+	serve := static.Serve("/", assets)
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			return
+		}
+		layer, name, info, ok := assets.find(c.Request.URL.Path)
+		if !ok {
+			return
+		}
+		if etag, err := assets.etag(layer, name, info); err == nil {
+			c.Header("ETag", etag)
+		}
+		maxAge := define.ASSETS_MAX_AGE
+		cacheControl := "public, max-age=%d"
+		if fingerprintPattern.MatchString(name) {
+			maxAge = define.ASSETS_IMMUTABLE_MAX_AGE
+			cacheControl += ", immutable"
+		}
+		c.Header("Cache-Control", fmt.Sprintf(cacheControl, int(maxAge.Seconds())))
+		serve(c)
+	}
+}
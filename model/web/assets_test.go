@@ -0,0 +1,84 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/web"
+)
+
+func TestAssets(t *testing.T) {
+	embedded := fstest.MapFS{
+		"favicon.svg":          {Data: []byte("<svg></svg>")},
+		"robots.txt":           {Data: []byte("User-agent: *")},
+		"app.3f9a2c1b.js":      {Data: []byte("console.log(1)")},
+		"fonts/icons.woff2":    {Data: []byte("wOF2")},
+		"fonts/readme.unknown": {Data: []byte("plain")},
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *\nDisallow: /"), 0644)
+
+	config := &define.Config{Domain: "http://localhost:8080", AssetsDir: dir}
+	router := newTestRouter(t, config, web.Features{Assets: embedded})
+
+	request := func(method string, target string, header map[string]string) *httptest.ResponseRecorder {
+		merged := map[string]string{"User-Agent": "Mozilla/5.0"}
+		for key, value := range header {
+			merged[key] = value
+		}
+		return doRequest(router, method, target, nil, merged)
+	}
+
+	tests := []struct {
+		path         string
+		contentType  string
+		body         string
+		cacheControl string
+	}{
+		{"/favicon.svg", "image/svg+xml", "<svg></svg>", "public, max-age=3600"},
+		{"/robots.txt", "text/plain; charset=utf-8", "User-agent: *\nDisallow: /", "public, max-age=3600"},
+		{"/app.3f9a2c1b.js", "text/javascript; charset=utf-8", "console.log(1)", "public, max-age=31536000, immutable"},
+		{"/fonts/icons.woff2", "font/woff2", "wOF2", "public, max-age=3600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := request(http.MethodGet, tt.path, nil)
+			if w.Code != 200 || w.Body.String() != tt.body {
+				t.Fatalf("Expected 200 with %q, got %d %q", tt.body, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.contentType, got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("Expected Cache-Control %q, got %q", tt.cacheControl, got)
+			}
+
+			etag := w.Header().Get("ETag")
+			if !strings.HasPrefix(etag, `"`) {
+				t.Fatalf("Expected strong ETag, got %q", etag)
+			}
+			if w := request(http.MethodGet, tt.path, map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+				t.Errorf("Expected 304 for matching ETag, got %d", w.Code)
+			}
+		})
+	}
+
+	if w := request(http.MethodGet, "/", nil); w.Code != 200 || !strings.Contains(w.Body.String(), "Test Network") {
+		t.Errorf("Expected IP page instead of directory listing, got %d", w.Code)
+	}
+	if w := request(http.MethodGet, "/fonts/", nil); w.Code != 404 {
+		t.Errorf("Expected 404 for asset directory, got %d", w.Code)
+	}
+	if w := request(http.MethodGet, "/../main.go", nil); w.Code != 404 {
+		t.Errorf("Expected 404 for path outside assets, got %d", w.Code)
+	}
+	if w := request(http.MethodPost, "/robots.txt", nil); w.Code == 200 {
+		t.Errorf("Expected assets to be served only for GET and HEAD, got %d", w.Code)
+	}
+}
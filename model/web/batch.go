@@ -0,0 +1,232 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/response"
+)
+
+// BATCH_STREAM_PATH 流式批量查询的路径，响应需要逐条发送，不能经过 gzip 压缩
+const BATCH_STREAM_PATH = "/api/batch/stream"
+
+// BatchItem 批量查询中一项输入的结果，输入无效时只返回 Error
+type BatchItem struct {
+	Index  int            `json:"index"`
+	Input  string         `json:"input"`
+	IP     string         `json:"ip,omitempty"`
+	Info   []string       `json:"info,omitempty"`
+	Result *ipInfo.Result `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// batchReader 逐项读取批量查询的输入，支持 JSON 数组，以及每行一个 IP 或 JSON 字符串的文本
+type batchReader struct {
+	json  *json.Decoder
+	lines *bufio.Scanner
+}
+
+func newBatchReader(body io.Reader, allowArray bool) (*batchReader, error) {
+	buffered := bufio.NewReader(body)
+	for {
+		next, err := buffered.Peek(1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(string(next)) == "" {
+			buffered.ReadByte()
+			continue
+		}
+		if next[0] == '[' && allowArray {
+			decoder := json.NewDecoder(buffered)
+			if _, err := decoder.Token(); err != nil {
+				return nil, fmt.Errorf("JSON 数组格式错误: %w", err)
+			}
+			return &batchReader{json: decoder}, nil
+		}
+		break
+	}
+	scanner := bufio.NewScanner(buffered)
+	scanner.Buffer(make([]byte, 0, 4096), define.BATCH_MAX_ITEM_LENGTH)
+	return &batchReader{lines: scanner}, nil
+}
+
+// Next 返回下一项输入，无法转换为字符串的项返回 ok 为 false，没有更多输入时返回 io.EOF
+func (r *batchReader) Next() (input string, ok bool, err error) {
+	if r.json != nil {
+		if !r.json.More() {
+			if _, err := r.json.Token(); err != nil {
+				return "", false, fmt.Errorf("JSON 数组格式错误: %w", err)
+			}
+			// 数组结束后只允许空白，避免忽略拼接在后面的其他内容
+			if r.json.More() {
+				return "", false, errors.New("JSON 数组之后不能有其他内容")
+			}
+			if _, err := r.json.Token(); err != io.EOF {
+				return "", false, fmt.Errorf("JSON 数组之后不能有其他内容: %w", err)
+			}
+			return "", false, io.EOF
+		}
+		var raw json.RawMessage
+		if err := r.json.Decode(&raw); err != nil {
+			return "", false, fmt.Errorf("JSON 数组格式错误: %w", err)
+		}
+		if err := json.Unmarshal(raw, &input); err != nil {
+			return string(raw), false, nil
+		}
+		return input, true, nil
+	}
+
+	for r.lines.Scan() {
+		line := strings.TrimSpace(r.lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// 兼容 NDJSON 格式的输入
+		if strings.HasPrefix(line, `"`) {
+			if err := json.Unmarshal([]byte(line), &input); err != nil {
+				return line, false, nil
+			}
+			return input, true, nil
+		}
+		return line, true, nil
+	}
+	if err := r.lines.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return "", false, fmt.Errorf("单行内容不能超过 %d 字节", define.BATCH_MAX_ITEM_LENGTH)
+		}
+		return "", false, err
+	}
+	return "", false, io.EOF
+}
+
+func lookupBatchItem(ipdb ipInfo.Provider, index int, input string, ok bool, languages []string) BatchItem {
+	item := BatchItem{Index: index, Input: input}
+	switch {
+	case !ok:
+		item.Error = "输入必须是字符串"
+	case len(input) > define.BATCH_MAX_ITEM_LENGTH:
+		item.Input = input[:define.BATCH_MAX_ITEM_LENGTH]
+		item.Error = "输入过长"
+	case !fn.IsValidIPAddress(input):
+		item.Error = "无效的 IP 地址"
+	default:
+		result := ipInfo.Lookup(ipdb, input, languages...)
+		item.IP = input
+		item.Info = result.Info
+		item.Result = &result
+	}
+	return item
+}
+
+// registerBatch 注册批量查询接口
+//
+// `/api/batch` 读取全部输入后返回结果，数量受 `BatchMaxItems` 限制；
+// `/api/batch/stream` 逐行读取输入并以 NDJSON 逐条返回结果，不需要在内存中保存全部输入和结果
+func registerBatch(r *gin.Engine, config *define.Config, ipdb ipInfo.Provider) {
+	maxItems := config.BatchMaxItems
+	if maxItems <= 0 {
+		maxItems = define.BATCH_MAX_ITEMS
+	}
+
+	r.POST("/api/batch", func(c *gin.Context) {
+		// 预留 JSON 引号、逗号和空白的长度
+		limit := int64(maxItems)*int64(define.BATCH_MAX_ITEM_LENGTH+8) + 2
+		reader, err := newBatchReader(http.MaxBytesReader(c.Writer, c.Request.Body, limit), true)
+		if err != nil {
+			batchError(c, err)
+			return
+		}
+
+		languages := RequestLanguages(c)
+		items := []BatchItem{}
+		failed := 0
+		for {
+			input, ok, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				batchError(c, err)
+				return
+			}
+			if len(items) >= maxItems {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("单次最多查询 %d 个 IP，更多的 IP 请使用 %s", maxItems, BATCH_STREAM_PATH)})
+				return
+			}
+			item := lookupBatchItem(ipdb, len(items), input, ok, languages)
+			if item.Error != "" {
+				failed++
+			}
+			items = append(items, item)
+		}
+		if len(items) == 0 {
+			c.JSON(400, gin.H{"error": "没有需要查询的 IP"})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"version": response.JSON_SCHEMA_VERSION,
+			"count":   len(items),
+			"errors":  failed,
+			"results": items,
+		})
+	})
+
+	r.POST(BATCH_STREAM_PATH, func(c *gin.Context) {
+		// HTTP/1.1 默认在开始输出后不再读取请求体，边读边写需要开启全双工
+		http.NewResponseController(c.Writer).EnableFullDuplex()
+		reader, err := newBatchReader(c.Request.Body, false)
+		if err != nil {
+			batchError(c, err)
+			return
+		}
+
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Status(200)
+		encoder := json.NewEncoder(c.Writer)
+		languages := RequestLanguages(c)
+		for index := 0; ; index++ {
+			input, ok, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			// 已经开始输出，错误信息作为最后一行返回
+			if err != nil {
+				encoder.Encode(gin.H{"error": err.Error()})
+				break
+			}
+			if err := encoder.Encode(lookupBatchItem(ipdb, index, input, ok, languages)); err != nil {
+				return
+			}
+			if (index+1)%define.BATCH_FLUSH_ITEMS == 0 {
+				c.Writer.Flush()
+			}
+		}
+		c.Writer.Flush()
+	})
+}
+
+// batchError 返回读取输入时发生的错误，请求体过大时返回 413
+func batchError(c *gin.Context, err error) {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "请求内容过大"})
+		return
+	}
+	c.JSON(400, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,158 @@
+package web_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/web"
+)
+
+func TestBatch(t *testing.T) {
+	config := &define.Config{Domain: "http://localhost:8080", BatchMaxItems: 3}
+	router := newTestRouter(t, config, web.Features{})
+
+	type batchResponse struct {
+		Count   int             `json:"count"`
+		Errors  int             `json:"errors"`
+		Results []web.BatchItem `json:"results"`
+		Error   string          `json:"error"`
+	}
+	request := func(contentType string, body string) (int, batchResponse) {
+		w := doRequest(router, http.MethodPost, "/api/batch", strings.NewReader(body), map[string]string{"Content-Type": contentType})
+		var result batchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to parse response %q: %v", w.Body.String(), err)
+		}
+		return w.Code, result
+	}
+
+	t.Run("JSON array", func(t *testing.T) {
+		code, result := request("application/json", ` ["192.0.2.8", "not-an-ip", 42]`)
+		if code != 200 || result.Count != 3 || result.Errors != 2 {
+			t.Fatalf("Expected 3 results with 2 errors, got %d %+v", code, result)
+		}
+		first := result.Results[0]
+		if first.IP != "192.0.2.8" || first.Result == nil || len(first.Info) == 0 || first.Info[0] != "Test Network" || first.Error != "" {
+			t.Errorf("Unexpected result for valid IP: %+v", first)
+		}
+		if result.Results[1].Index != 1 || result.Results[1].Input != "not-an-ip" || result.Results[1].Error == "" || result.Results[1].Result != nil {
+			t.Errorf("Expected per-item error for invalid IP, got %+v", result.Results[1])
+		}
+		if result.Results[2].Input != "42" || result.Results[2].Error == "" {
+			t.Errorf("Expected per-item error for non-string item, got %+v", result.Results[2])
+		}
+	})
+
+	t.Run("Newline delimited", func(t *testing.T) {
+		code, result := request("text/plain", "192.0.2.8\n\n# comment\r\n\"192.0.2.9\"\n")
+		if code != 200 || result.Count != 2 || result.Errors != 0 || result.Results[1].IP != "192.0.2.9" {
+			t.Errorf("Expected 2 results, got %d %+v", code, result)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if code, result := request("text/plain", "192.0.2.1\n192.0.2.2\n192.0.2.3\n192.0.2.4\n"); code != 413 || !strings.Contains(result.Error, web.BATCH_STREAM_PATH) {
+			t.Errorf("Expected 413 when exceeding the item limit, got %d %+v", code, result)
+		}
+		if code, result := request("text/plain", strings.Repeat("1", 10000)); code != 413 {
+			t.Errorf("Expected 413 for oversized body, got %d %+v", code, result)
+		}
+		if code, result := request("application/json", `["192.0.2.1"`); code != 400 || result.Error == "" {
+			t.Errorf("Expected 400 for malformed JSON, got %d %+v", code, result)
+		}
+		for _, body := range []string{`["192.0.2.1"]["192.0.2.2"]`, `["192.0.2.1"] garbage`, "[\"192.0.2.1\"]\n{}"} {
+			if code, result := request("application/json", body); code != 400 || result.Error == "" {
+				t.Errorf("Expected 400 for trailing data in %q, got %d %+v", body, code, result)
+			}
+		}
+		if code, result := request("application/json", "[\"192.0.2.1\"] \n"); code != 200 || result.Count != 1 {
+			t.Errorf("Expected trailing whitespace to be accepted, got %d %+v", code, result)
+		}
+		if code, result := request("text/plain", " \n"); code != 400 || result.Error == "" {
+			t.Errorf("Expected 400 for empty input, got %d %+v", code, result)
+		}
+	})
+}
+
+func TestBatchStream(t *testing.T) {
+	config := &define.Config{Domain: "http://localhost:8080", BatchMaxItems: 1}
+	server := httptest.NewServer(newTestRouter(t, config, web.Features{}))
+	defer server.Close()
+
+	input, writer := io.Pipe()
+	req, _ := http.NewRequest(http.MethodPost, server.URL+web.BATCH_STREAM_PATH, input)
+	req.Header.Set("Accept-Encoding", "gzip")
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Errorf("Request failed: %v", err)
+			close(responses)
+			return
+		}
+		responses <- resp
+	}()
+
+	// 输入尚未结束时就应该收到已发送部分的结果
+	lines := make([]string, define.BATCH_FLUSH_ITEMS)
+	for i := range lines {
+		lines[i] = "192.0.2.8"
+	}
+	lines[1] = "not-an-ip"
+	go writer.Write([]byte(strings.Join(lines, "\n") + "\n"))
+
+	var resp *http.Response
+	select {
+	case resp = <-responses:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected streamed response before the request body ends")
+	}
+	if resp == nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "application/x-ndjson; charset=utf-8" || resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("Unexpected headers: %v", resp.Header)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readItem := func() web.BatchItem {
+		t.Helper()
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("Failed to read line: %v", err)
+		}
+		var item web.BatchItem
+		if err := json.Unmarshal(line, &item); err != nil {
+			t.Fatalf("Failed to parse line %q: %v", line, err)
+		}
+		return item
+	}
+	if item := readItem(); item.Index != 0 || item.IP != "192.0.2.8" || item.Info[0] != "Test Network" {
+		t.Errorf("Unexpected first item: %+v", item)
+	}
+	if item := readItem(); item.Index != 1 || item.Error == "" {
+		t.Errorf("Expected per-item error, got %+v", item)
+	}
+	for i := 2; i < define.BATCH_FLUSH_ITEMS; i++ {
+		readItem()
+	}
+
+	// 数量不受 BatchMaxItems 限制，超长的行作为最后一行错误返回
+	writer.Write([]byte("192.0.2.9\n" + strings.Repeat("1", define.BATCH_MAX_ITEM_LENGTH+1) + "\n"))
+	writer.Close()
+	if item := readItem(); item.Index != define.BATCH_FLUSH_ITEMS || item.IP != "192.0.2.9" {
+		t.Errorf("Unexpected item after the item limit: %+v", item)
+	}
+	rest, _ := io.ReadAll(reader)
+	if !strings.Contains(string(rest), `"error"`) {
+		t.Errorf("Expected trailing error line, got %q", rest)
+	}
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/soulteary/ip-helper/model/define"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+// addVary 追加 Vary 请求头，保留 gzip 等中间件已经设置的字段
+func addVary(c *gin.Context, fields ...string) {
+	for _, field := range fields {
+		c.Writer.Header().Add("Vary", field)
+	}
+}
+
+// databaseVersion 返回当前提供服务的数据库的版本，数据库热更新后随之变化
+func databaseVersion(ipdb ipInfo.Provider) string {
+	lister, ok := ipdb.(databaseLister)
+	if !ok {
+		return ""
+	}
+	var version strings.Builder
+	for _, db := range lister.Databases() {
+		fmt.Fprintf(&version, "%s|%s|%s|%s\n", db.Name, db.Kind, db.Source, db.Version)
+	}
+	return version.String()
+}
+
+// etagMatch 按弱比较判断 If-None-Match 是否包含 etag
+func etagMatch(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheLookup 为查询指定 IP 的响应设置缓存，ETag 由数据库版本和响应内容计算，
+// 数据库更新或模板变化后 ETag 随之变化。请求的 If-None-Match 匹配时返回 304 并返回 true
+func cacheLookup(c *gin.Context, config *define.Config, ipdb ipInfo.Provider, body []byte) bool {
+	hash := sha256.New()
+	hash.Write([]byte(databaseVersion(ipdb)))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	// 响应可能被 gzip 压缩，只能使用弱 ETag
+	etag := fmt.Sprintf(`W/"%x"`, hash.Sum(nil)[:16])
+
+	// 启用认证时响应不应该被共享缓存保存
+	scope := "public"
+	if config.Token != "" {
+		scope = "private"
+	}
+	c.Header("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int(define.LOOKUP_MAX_AGE.Seconds())))
+	c.Header("ETag", etag)
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatch(match, etag) {
+		c.Status(304)
+		return true
+	}
+	return false
+}
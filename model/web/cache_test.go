@@ -0,0 +1,100 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/soulteary/ip-helper/model/define"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/web"
+)
+
+func TestLookupCache(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "ranges.csv")
+	if err := os.WriteFile(csvPath, []byte("192.0.2.0/24,Test Network\n198.51.100.0/24,Other Network\n"), 0644); err != nil {
+		t.Fatalf("Failed to write database: %v", err)
+	}
+	chain, err := ipInfo.LoadProviders([]string{"csv:" + csvPath})
+	if err != nil {
+		t.Fatalf("LoadProviders() error = %v", err)
+	}
+	config := &define.Config{Domain: "http://localhost:8080"}
+	router := web.NewRouter(config, chain, web.Features{})
+
+	request := func(target string, header map[string]string) *httptest.ResponseRecorder {
+		merged := map[string]string{"User-Agent": "curl/8.0"}
+		for key, value := range header {
+			merged[key] = value
+		}
+		return doRequest(router, http.MethodGet, target, nil, merged)
+	}
+
+	t.Run("Self IP is not cached", func(t *testing.T) {
+		for _, target := range []string{"/", "/ip"} {
+			w := request(target, map[string]string{"If-None-Match": "*"})
+			if w.Code != 200 {
+				t.Errorf("%s: expected 200, got %d", target, w.Code)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("%s: expected no-store, got %q", target, got)
+			}
+			if got := w.Header().Get("ETag"); got != "" {
+				t.Errorf("%s: expected no ETag, got %q", target, got)
+			}
+		}
+	})
+
+	t.Run("Lookup is cached", func(t *testing.T) {
+		w := request("/ip/192.0.2.8", map[string]string{"Accept-Encoding": "gzip"})
+		if got := w.Header().Get("Cache-Control"); got != "public, max-age=300" {
+			t.Errorf("Expected public cache, got %q", got)
+		}
+		vary := strings.Join(w.Header().Values("Vary"), ", ")
+		for _, field := range []string{"Accept-Encoding", "Accept", "Accept-Language", "User-Agent"} {
+			if !strings.Contains(vary, field) {
+				t.Errorf("Vary should contain %s, got %q", field, vary)
+			}
+		}
+
+		etag := w.Header().Get("ETag")
+		if !strings.HasPrefix(etag, `W/"`) {
+			t.Fatalf("Expected weak ETag, got %q", etag)
+		}
+		if w := request("/ip/192.0.2.8", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("Expected 304 without body, got %d %q", w.Code, w.Body.String())
+		}
+		if w := request("/ip/192.0.2.8", map[string]string{"If-None-Match": `W/"other", ` + strings.TrimPrefix(etag, "W/")}); w.Code != http.StatusNotModified {
+			t.Errorf("Expected 304 for ETag list, got %d", w.Code)
+		}
+
+		for _, other := range []string{"/ip/198.51.100.8", "/ip/192.0.2.9", "/ip/192.0.2.8?format=text"} {
+			w := request(other, map[string]string{"If-None-Match": etag})
+			if w.Code != 200 || w.Header().Get("ETag") == etag {
+				t.Errorf("%s: expected a different ETag, got %d %q", other, w.Code, w.Header().Get("ETag"))
+			}
+		}
+	})
+
+	t.Run("Database update", func(t *testing.T) {
+		etag := request("/ip/192.0.2.8", nil).Header().Get("ETag")
+		// 查询结果不变，但数据库版本变化
+		os.WriteFile(csvPath, []byte("192.0.2.0/24,Test Network\n198.51.100.0/24,Other Network\n203.0.113.0/24,New Network\n"), 0644)
+		chain.Reload(true)
+		w := request("/ip/192.0.2.8", map[string]string{"If-None-Match": etag})
+		if w.Code != 200 || w.Header().Get("ETag") == etag {
+			t.Errorf("Expected a new ETag after database reload, got %d %q", w.Code, w.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("Private with token", func(t *testing.T) {
+		router := web.NewRouter(&define.Config{Domain: "http://localhost:8080", Token: "secret"}, chain, web.Features{})
+		w := doRequest(router, http.MethodGet, "/ip/192.0.2.8?token=secret", nil, nil)
+		if got := w.Header().Get("Cache-Control"); got != "private, max-age=300" {
+			t.Errorf("Expected private cache with token, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package web
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/soulteary/ip-helper/model/fn"
+)
+
+// 查询结果支持的输出格式
+const (
+	FORMAT_HTML  = "html"
+	FORMAT_JSON  = "json"
+	FORMAT_TEXT  = "text"
+	FORMAT_YAML  = "yaml"
+	FORMAT_XML   = "xml"
+	FORMAT_CSV   = "csv"
+	FORMAT_SHELL = "shell"
+	FORMAT_JSONP = "jsonp"
+)
+
+// FORMATS `format` 参数可以使用的格式名称及别名
+var FORMATS = map[string]string{
+	"html":   FORMAT_HTML,
+	"json":   FORMAT_JSON,
+	"text":   FORMAT_TEXT,
+	"txt":    FORMAT_TEXT,
+	"yaml":   FORMAT_YAML,
+	"yml":    FORMAT_YAML,
+	"xml":    FORMAT_XML,
+	"csv":    FORMAT_CSV,
+	"shell":  FORMAT_SHELL,
+	"sh":     FORMAT_SHELL,
+	"export": FORMAT_SHELL,
+	"jsonp":  FORMAT_JSONP,
+}
+
+// mediaTypes Accept 请求头中可以识别的媒体类型
+var mediaTypes = map[string]string{
+	"text/html":              FORMAT_HTML,
+	"application/xhtml+xml":  FORMAT_HTML,
+	"application/json":       FORMAT_JSON,
+	"text/plain":             FORMAT_TEXT,
+	"application/yaml":       FORMAT_YAML,
+	"application/x-yaml":     FORMAT_YAML,
+	"text/yaml":              FORMAT_YAML,
+	"text/x-yaml":            FORMAT_YAML,
+	"application/xml":        FORMAT_XML,
+	"text/xml":               FORMAT_XML,
+	"text/csv":               FORMAT_CSV,
+	"application/javascript": FORMAT_JSONP,
+	"text/javascript":        FORMAT_JSONP,
+}
+
+// CONTENT_TYPES 各格式响应的 Content-Type
+var CONTENT_TYPES = map[string]string{
+	FORMAT_HTML:  "text/html; charset=utf-8",
+	FORMAT_JSON:  "application/json; charset=utf-8",
+	FORMAT_TEXT:  "text/plain; charset=utf-8",
+	FORMAT_YAML:  "application/yaml; charset=utf-8",
+	FORMAT_XML:   "application/xml; charset=utf-8",
+	FORMAT_CSV:   "text/csv; charset=utf-8",
+	FORMAT_SHELL: "text/plain; charset=utf-8",
+	FORMAT_JSONP: "application/javascript; charset=utf-8",
+}
+
+// 只允许由标识符和点组成的回调名称，避免注入脚本
+var callbackPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+const maxCallbackLength = 64
+
+// NegotiateFormat 按 `format` 参数、`callback` 参数、Accept 请求头的顺序确定输出格式，
+// Accept 请求头缺失或只接受任意类型时，根据 User-Agent 判断是否为命令行工具
+func NegotiateFormat(c *gin.Context, tools []string) (string, error) {
+	if value := c.Query("format"); value != "" {
+		format, ok := FORMATS[strings.ToLower(value)]
+		if !ok {
+			return "", fmt.Errorf("不支持的格式: %s，可选 html、json、text、yaml、xml、csv、shell、jsonp", value)
+		}
+		return format, nil
+	}
+	if c.Query("callback") != "" {
+		return FORMAT_JSONP, nil
+	}
+	if format := parseAccept(c.GetHeader("Accept")); format != "" {
+		return format, nil
+	}
+	if fn.IsDownloadTool(c.GetHeader("User-Agent"), tools...) {
+		return FORMAT_JSON, nil
+	}
+	return FORMAT_HTML, nil
+}
+
+// parseAccept 返回权重最高的可识别格式，权重相同时以先出现的为准，
+// 最优先的是 `*/*` 或没有可识别的格式时返回空字符串
+func parseAccept(header string) string {
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+	ranges := []mediaRange{}
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType, quality})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+
+	for _, r := range ranges {
+		if r.quality <= 0 || r.mediaType == "*/*" {
+			return ""
+		}
+		if format, ok := mediaTypes[r.mediaType]; ok {
+			return format
+		}
+	}
+	return ""
+}
+
+// ValidCallback 判断 JSONP 回调名称是否安全
+func ValidCallback(callback string) bool {
+	return len(callback) <= maxCallbackLength && callbackPattern.MatchString(callback)
+}
@@ -0,0 +1,60 @@
+package web_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/web"
+)
+
+func TestResponseFormats(t *testing.T) {
+	config := &define.Config{Domain: "http://localhost:8080", DownloadTools: []string{"curl", "MyScript"}}
+	router := newTestRouter(t, config, web.Features{})
+
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		userAgent   string
+		code        int
+		contentType string
+		contains    string
+	}{
+		{"Browser", "/", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "Mozilla/5.0", 200, "text/html", "192.0.2.1"},
+		{"HTTPie", "/", "application/json, */*;q=0.5", "HTTPie/3.2.2", 200, "application/json", `"ip":"192.0.2.1"`},
+		{"Accept quality", "/", "text/html;q=0.5, text/plain", "Mozilla/5.0", 200, "text/plain", "192.0.2.1\nTest Network\n"},
+		{"YAML", "/", "application/yaml", "", 200, "application/yaml", "ip: 192.0.2.1"},
+		{"XML", "/", "text/xml", "", 200, "application/xml", "<ip>192.0.2.1</ip>"},
+		{"CSV", "/", "text/csv", "", 200, "text/csv", "192.0.2.1,Test Network"},
+		{"Wildcard with configured tool", "/", "*/*", "MyScript/1.0", 200, "application/json", `"ip":"192.0.2.1"`},
+		{"Tool not configured", "/", "", "Wget/1.20.3", 200, "text/html", "192.0.2.1"},
+		{"Format overrides Accept", "/?format=shell", "text/html", "Mozilla/5.0", 200, "text/plain", "export IP_HELPER_IP='192.0.2.1'"},
+		{"Format alias", "/ip/192.0.2.8?format=yml", "", "", 200, "application/yaml", "ip: 192.0.2.8"},
+		{"JSONP", "/?format=jsonp&callback=app.show", "", "", 200, "application/javascript", "/**/app.show({"},
+		{"JSONP from callback", "/?callback=cb", "", "", 200, "application/javascript", "/**/cb({"},
+		{"JSONP default callback", "/?format=jsonp", "", "", 200, "application/javascript", "/**/callback({"},
+		{"Invalid callback", "/?callback=alert(1)", "", "", 400, "application/json", "error"},
+		{"Unknown format", "/?format=pdf", "", "", 400, "application/json", "pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(router, http.MethodGet, tt.target, nil, map[string]string{"Accept": tt.accept, "User-Agent": tt.userAgent})
+
+			if w.Code != tt.code {
+				t.Fatalf("Expected status %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			if !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("Expected content type %s, got %s", tt.contentType, w.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("Expected body to contain %q, got %q", tt.contains, w.Body.String())
+			}
+			if tt.code == 200 && !strings.Contains(w.Header().Get("Vary"), "Accept") {
+				t.Errorf("Expected Vary to include Accept, got %q", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
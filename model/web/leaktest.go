@@ -0,0 +1,65 @@
+package web
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/soulteary/ip-helper/model/define"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/leaktest"
+	"github.com/soulteary/ip-helper/model/page"
+)
+
+// LeakTestResolver 泄露测试中发现的解析器及其地理位置
+type LeakTestResolver struct {
+	IP        string        `json:"ip"`
+	Info      []string      `json:"info"`
+	Result    ipInfo.Result `json:"result"`
+	Queries   int           `json:"queries"`
+	FirstSeen int64         `json:"first_seen"`
+}
+
+// registerLeakTest 注册 DNS 泄露测试的页面和接口，结果与会话相关，不允许缓存
+func registerLeakTest(r *gin.Engine, config *define.Config, ipdb ipInfo.Provider, store *leaktest.Store) {
+	r.GET("/leaktest", func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.Data(200, "text/html; charset=utf-8", []byte(page.LeakTest))
+	})
+
+	r.POST("/leaktest", func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		token, err := store.NewSession()
+		if err != nil {
+			log.Printf("创建泄露测试会话失败: %v\n", err)
+			c.JSON(500, gin.H{"error": "创建测试会话失败"})
+			return
+		}
+		c.JSON(200, gin.H{
+			"token": token,
+			"names": leaktest.Names(token, config.LeakTestZone),
+		})
+	})
+
+	r.GET("/leaktest/:token", func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		token := c.Param("token")
+		resolvers, ok := store.Resolvers(token)
+		if !ok {
+			c.JSON(404, gin.H{"error": "测试会话不存在或已过期"})
+			return
+		}
+		result := make([]LeakTestResolver, 0, len(resolvers))
+		for _, resolver := range resolvers {
+			info := ipInfo.Lookup(ipdb, resolver.IP, RequestLanguages(c)...)
+			result = append(result, LeakTestResolver{
+				IP:        resolver.IP,
+				Info:      info.Info,
+				Result:    info,
+				Queries:   resolver.Queries,
+				FirstSeen: resolver.FirstSeen.Unix(),
+			})
+		}
+		c.JSON(200, gin.H{"token": token, "resolvers": result})
+	})
+}
@@ -0,0 +1,56 @@
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/leaktest"
+	"github.com/soulteary/ip-helper/model/web"
+)
+
+func TestLeakTest(t *testing.T) {
+	config := &define.Config{Domain: "http://localhost:8080", LeakTestZone: "leak.example.com"}
+	store := leaktest.NewStore(time.Minute)
+	router := newTestRouter(t, config, web.Features{LeakTest: store})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/leaktest", nil))
+	if w.Code != 200 || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("Expected uncached 200 response, got %d %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+	var session struct {
+		Token string   `json:"token"`
+		Names []string `json:"names"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil {
+		t.Fatalf("Failed to parse session: %v", err)
+	}
+	if len(session.Names) == 0 || session.Names[0] != session.Token+".1.leak.example.com" {
+		t.Errorf("Unexpected names: %v", session.Names)
+	}
+
+	store.Record(session.Token, "192.0.2.53")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/leaktest/"+session.Token, nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"ip":"192.0.2.53"`) || !strings.Contains(w.Body.String(), "Test Network") {
+		t.Errorf("Expected recorded resolver, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/leaktest/unknown", nil))
+	if w.Code != 404 {
+		t.Errorf("Expected 404 for unknown session, got %d", w.Code)
+	}
+
+	// 未启用 DNS 服务时不提供泄露测试
+	w = httptest.NewRecorder()
+	newTestRouter(t, config, web.Features{}).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/leaktest", nil))
+	if w.Code != 404 {
+		t.Errorf("Expected 404 without leak test store, got %d", w.Code)
+	}
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+type databaseLister interface {
+	Databases() []ipInfo.DatabaseInfo
+}
+
+// RenderMetrics 以 Prometheus 文本格式输出当前提供服务的数据库信息
+func RenderMetrics(ipdb ipInfo.Provider) []byte {
+	databases := []ipInfo.DatabaseInfo{}
+	if lister, ok := ipdb.(databaseLister); ok {
+		databases = lister.Databases()
+	}
+
+	var buf bytes.Buffer
+	metrics := []struct {
+		name  string
+		kind  string
+		help  string
+		value func(ipInfo.DatabaseInfo) int64
+	}{
+		{"ip_helper_database_build_timestamp_seconds", "gauge", "IP 数据库构建时间", func(db ipInfo.DatabaseInfo) int64 { return db.BuildTime.Unix() }},
+		{"ip_helper_database_loaded_timestamp_seconds", "gauge", "IP 数据库加载时间", func(db ipInfo.DatabaseInfo) int64 { return db.LoadedAt.Unix() }},
+		{"ip_helper_database_reloads_total", "counter", "IP 数据库热更新次数", func(db ipInfo.DatabaseInfo) int64 { return db.Reloads }},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, db := range databases {
+			fmt.Fprintf(&buf, "%s{name=%s,kind=%s,source=%s,version=%s} %d\n", metric.name,
+				metricLabel(db.Name), metricLabel(db.Kind), metricLabel(db.Source), metricLabel(db.Version), metric.value(db))
+		}
+	}
+	return buf.Bytes()
+}
+
+func metricLabel(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
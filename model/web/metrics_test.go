@@ -0,0 +1,41 @@
+package web_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/web"
+)
+
+func TestRenderMetrics(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "ranges.csv")
+	if err := os.WriteFile(csvPath, []byte("1.0.0.0/24,测试\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	chain, err := ipInfo.LoadProviders([]string{"csv:" + csvPath})
+	if err != nil {
+		t.Fatalf("LoadProviders() error = %v", err)
+	}
+	version := chain.Databases()[0].Version
+
+	metrics := string(web.RenderMetrics(chain))
+	for _, expected := range []string{
+		"# TYPE ip_helper_database_build_timestamp_seconds gauge",
+		"# TYPE ip_helper_database_reloads_total counter",
+		`ip_helper_database_reloads_total{name="csv:ranges.csv",kind="csv",source="` + csvPath + `",version="` + version + `"} 0`,
+	} {
+		if !strings.Contains(metrics, expected) {
+			t.Errorf("Metrics should contain %q, got:\n%s", expected, metrics)
+		}
+	}
+
+	// 不支持热更新的数据库只输出指标说明
+	memory, _ := ipInfo.NewMemoryProvider("memory", nil)
+	metrics = string(web.RenderMetrics(memory))
+	if strings.Contains(metrics, "{") {
+		t.Errorf("Metrics should not contain samples, got:\n%s", metrics)
+	}
+}
@@ -1,12 +1,6 @@
 package web
 
 import (
-	"crypto/md5"
-	"fmt"
-	"net/http"
-	"strings"
-	"time"
-
 	"github.com/gin-gonic/gin"
 	"github.com/soulteary/ip-helper/model/define"
 
@@ -30,28 +24,23 @@ func AuthMiddleware(config *define.Config) gin.HandlerFunc {
 	}
 }
 
-func IPAnalyzerMiddleware() gin.HandlerFunc {
+// IPAnalyzerMiddleware 分析请求来源，IP 来自转发请求头时通过 `X-Client-IP-Source` 响应头说明来源
+func IPAnalyzerMiddleware(trusted *ipInfo.TrustedProxies, headers []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ipInfo := ipInfo.AnalyzeRequestData(c)
+		ipInfo := ipInfo.AnalyzeRequestData(c, trusted, headers)
+		if ipInfo.RealIPHeader != "" {
+			c.Header("X-Client-IP-Source", ipInfo.RealIPHeader)
+		}
 		c.Set("ip_info", ipInfo)
 		c.Next()
 	}
 }
 
+// CacheMiddleware 默认禁止缓存响应，返回当前访问者信息的接口不能被缓存，
+// 可以缓存的接口通过 cacheLookup 设置缓存时间和 ETag
 func CacheMiddleware() gin.HandlerFunc {
-	data := []byte(time.Now().String())
-	etag := fmt.Sprintf("W/%x", md5.Sum(data))
 	return func(c *gin.Context) {
-		if strings.HasPrefix(c.Request.URL.Path, "/") {
-			c.Header("Cache-Control", "private, max-age=86400")
-			c.Header("ETag", etag)
-			if match := c.GetHeader("If-None-Match"); match != "" {
-				if strings.Contains(match, etag) {
-					c.Status(http.StatusNotModified)
-					return
-				}
-			}
-		}
+		c.Header("Cache-Control", "no-store")
 		c.Next()
 	}
 }
@@ -1,12 +1,12 @@
 package web_test
 
 import (
-	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/soulteary/ip-helper/model/define"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
 	"github.com/soulteary/ip-helper/model/web"
 )
 
@@ -91,15 +91,19 @@ func TestIPAnalyzerMiddleware(t *testing.T) {
 	_, r := gin.CreateTestContext(w)
 
 	// 设置路由
-	r.Use(web.IPAnalyzerMiddleware())
+	trusted, _ := ipInfo.ParseTrustedProxies([]string{"192.0.2.0/24"})
+	r.Use(web.IPAnalyzerMiddleware(trusted, ipInfo.DEFAULT_CLIENT_IP_HEADERS))
 	r.GET("/test", func(c *gin.Context) {
-		ipInfo, exists := c.Get("ip_info")
+		info, exists := c.Get("ip_info")
 		if !exists {
 			t.Error("IPAnalyzerMiddleware() failed to set ip_info")
 		}
-		if ipInfo == nil {
+		if info == nil {
 			t.Error("IPAnalyzerMiddleware() ip_info is nil")
 		}
+		if got := info.(ipInfo.Info).RealIP; got != "192.168.1.1" {
+			t.Errorf("IPAnalyzerMiddleware() RealIP = %v, want %v", got, "192.168.1.1")
+		}
 		c.Status(200)
 	})
 
@@ -114,100 +118,31 @@ func TestIPAnalyzerMiddleware(t *testing.T) {
 	if w.Code != 200 {
 		t.Errorf("IPAnalyzerMiddleware() status = %v, want %v", w.Code, 200)
 	}
+	if got := w.Header().Get("X-Client-IP-Source"); got != "X-Real-IP" {
+		t.Errorf("IPAnalyzerMiddleware() X-Client-IP-Source = %v, want %v", got, "X-Real-IP")
+	}
 }
 
 func TestCacheMiddleware(t *testing.T) {
-	// 创建一个中间件实例以确保使用相同的 ETag
-	middleware := web.CacheMiddleware()
-
-	tests := []struct {
-		name       string
-		path       string
-		etag       string
-		wantCache  bool
-		wantStatus int
-	}{
-		{
-			name:       "无缓存请求",
-			path:       "/test",
-			etag:       "",
-			wantCache:  true,
-			wantStatus: 200,
-		},
-		{
-			name:       "带ETag请求-不匹配",
-			path:       "/test",
-			etag:       "W/different-etag",
-			wantCache:  true,
-			wantStatus: 200,
-		},
-		{
-			name:       "非根路径请求",
-			path:       "/api/test",
-			etag:       "",
-			wantCache:  true,
-			wantStatus: 200,
-		},
-		{
-			name:      "带ETag请求-匹配",
-			path:      "/test",
-			etag:      "", // 将在测试中动态设置
-			wantCache: true,
-			// wantStatus: http.StatusNotModified,
-			wantStatus: http.StatusOK,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// 设置测试环境
-			gin.SetMode(gin.TestMode)
-			w := httptest.NewRecorder()
-			_, r := gin.CreateTestContext(w)
-
-			// 使用同一个中间件实例
-			r.Use(middleware)
-			r.GET(tt.path, func(c *gin.Context) {
-				c.Status(200)
-			})
-
-			// 创建请求
-			req := httptest.NewRequest("GET", tt.path, nil)
-
-			if tt.name == "带ETag请求-匹配" {
-				// 先执行一次请求获取 ETag
-				w1 := httptest.NewRecorder()
-				req1 := httptest.NewRequest("GET", tt.path, nil)
-				r.ServeHTTP(w1, req1)
-
-				etag := w1.Header().Get("ETag")
-				if etag == "" {
-					t.Error("Failed to get ETag from first request")
-				}
-				t.Logf("Got ETag from first request: %s", etag)
-				req.Header.Set("If-None-Match", etag)
-				t.Logf("Setting If-None-Match header to: %s", etag)
-			} else if tt.etag != "" {
-				req.Header.Set("If-None-Match", tt.etag)
-			}
-
-			// 执行请求
-			r.ServeHTTP(w, req)
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.Use(web.CacheMiddleware())
+	r.GET("/test", func(c *gin.Context) {
+		c.Status(200)
+	})
 
-			// 检查结果
-			if w.Code != tt.wantStatus {
-				t.Errorf("CacheMiddleware() status = %v, want %v", w.Code, tt.wantStatus)
-				t.Logf("Response Headers: %v", w.Header())
-				t.Logf("If-None-Match Header: %s", req.Header.Get("If-None-Match"))
-			}
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("If-None-Match", "*")
+	r.ServeHTTP(w, req)
 
-			if tt.wantCache {
-				cacheControl := w.Header().Get("Cache-Control")
-				if cacheControl != "private, max-age=86400" {
-					t.Errorf("CacheMiddleware() Cache-Control = %v, want %v",
-						cacheControl, "private, max-age=86400")
-				}
-			}
-		})
+	if w.Code != 200 {
+		t.Errorf("CacheMiddleware() status = %v, want %v", w.Code, 200)
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		t.Errorf("CacheMiddleware() Cache-Control = %v, want %v", cacheControl, "no-store")
+	}
+	if etag := w.Header().Get("ETag"); etag != "" {
+		t.Errorf("CacheMiddleware() should not set ETag, got %v", etag)
 	}
 }
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/stun"
+)
+
+// ObservedAddress 通过 HTTP 或 STUN 观察到的客户端地址
+type ObservedAddress struct {
+	IP   string   `json:"ip"`
+	Port uint16   `json:"port,omitempty"`
+	Info []string `json:"info"`
+	// STUN 服务是否在有效期内观察到这个映射地址，客户端提交的地址可能未经过本服务
+	Observed *bool `json:"observed,omitempty"`
+}
+
+// registerSTUN 注册对比 STUN 与 HTTP 观察到的地址的接口
+//
+// 客户端可以通过 `mapped` 参数提交从同一个本地端口向不同 STUN 服务获取的映射地址，
+// 未提交时使用 STUN 服务最近观察到的、与 HTTP 请求来自同一 IP 的映射地址，
+// 这些地址不一定来自同一个本地端口，不用于判断对称型 NAT
+func registerSTUN(r *gin.Engine, ipdb ipInfo.Provider, observations *stun.Observations) {
+	r.GET("/stun", func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		info, exists := c.Get("ip_info")
+		if !exists {
+			c.JSON(500, gin.H{"error": "IP info not found"})
+			return
+		}
+		clientInfo := info.(ipInfo.Info)
+		languages := RequestLanguages(c)
+
+		httpIP, err := netip.ParseAddr(clientInfo.RealIP)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "无法识别客户端地址"})
+			return
+		}
+		httpIP = httpIP.Unmap()
+		observedHTTP := ObservedAddress{IP: httpIP.String(), Info: ipInfo.Lookup(ipdb, httpIP.String(), languages...).Info}
+		// 经过反向代理时连接的端口属于代理，不能用于对比
+		if clientInfo.RealIPHeader == "" {
+			if addr, err := netip.ParseAddrPort(c.Request.RemoteAddr); err == nil {
+				observedHTTP.Port = addr.Port()
+			}
+		}
+
+		mapped := []netip.AddrPort{}
+		submitted := c.QueryArray("mapped")
+		for _, value := range submitted {
+			addr, err := netip.ParseAddrPort(value)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "映射地址格式错误，应为 `IP:端口`: " + value})
+				return
+			}
+			mapped = append(mapped, netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port()))
+		}
+		if len(submitted) == 0 {
+			mapped = observations.ByIP(httpIP)
+		}
+
+		observedSTUN := []ObservedAddress{}
+		sameIP, compared, symmetric := true, false, false
+		for i, addr := range mapped {
+			seen := observations.Seen(addr)
+			observedSTUN = append(observedSTUN, ObservedAddress{
+				IP:       addr.Addr().String(),
+				Port:     addr.Port(),
+				Info:     ipInfo.Lookup(ipdb, addr.Addr().String(), languages...).Info,
+				Observed: &seen,
+			})
+			// 双栈客户端的 IPv4 与 IPv6 出口地址必然不同，只对比同一地址族
+			if addr.Addr().Is4() == httpIP.Is4() {
+				compared = true
+				if addr.Addr() != httpIP {
+					sameIP = false
+				}
+			}
+			// 只有客户端提交的地址来自同一个本地端口，同一地址族下映射出不同的地址或端口，说明映射与目标地址相关
+			if len(submitted) > 0 {
+				for _, other := range mapped[:i] {
+					if other.Addr().Is4() == addr.Addr().Is4() && other != addr {
+						symmetric = true
+					}
+				}
+			}
+		}
+		// 出口 IP 不同可能只是经过了不同的代理，只通过 same_ip 反映，不作为对称型 NAT 的依据
+		sameIP = sameIP && compared
+
+		c.JSON(200, gin.H{
+			"http":      observedHTTP,
+			"stun":      observedSTUN,
+			"same_ip":   sameIP,
+			"symmetric": symmetric,
+		})
+	})
+}
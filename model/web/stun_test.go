@@ -0,0 +1,115 @@
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/stun"
+	"github.com/soulteary/ip-helper/model/web"
+)
+
+type stunResult struct {
+	HTTP struct {
+		IP   string `json:"ip"`
+		Port uint16 `json:"port"`
+	} `json:"http"`
+	STUN []struct {
+		IP       string   `json:"ip"`
+		Port     uint16   `json:"port"`
+		Info     []string `json:"info"`
+		Observed *bool    `json:"observed"`
+	} `json:"stun"`
+	SameIP    bool `json:"same_ip"`
+	Symmetric bool `json:"symmetric"`
+}
+
+func TestSTUN(t *testing.T) {
+	config := &define.Config{Domain: "http://localhost:8080"}
+	observations := stun.NewObservations(time.Minute)
+	observations.Record(netip.MustParseAddrPort("192.0.2.1:40000"))
+	router := newTestRouter(t, config, web.Features{STUN: observations})
+
+	request := func(target string) (*httptest.ResponseRecorder, stunResult) {
+		t.Helper()
+		w := doRequest(router, http.MethodGet, target, nil, nil)
+		var result stunResult
+		if w.Code == 200 {
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to parse result: %v", err)
+			}
+		}
+		return w, result
+	}
+
+	t.Run("Observed mapping", func(t *testing.T) {
+		w, result := request("/stun")
+		if w.Code != 200 || w.Header().Get("Cache-Control") != "no-store" {
+			t.Fatalf("Expected uncached 200 response, got %d %q", w.Code, w.Header().Get("Cache-Control"))
+		}
+		if result.HTTP.IP != "192.0.2.1" || result.HTTP.Port != 1234 {
+			t.Errorf("Unexpected HTTP address: %+v", result.HTTP)
+		}
+		if len(result.STUN) != 1 || result.STUN[0].Port != 40000 || result.STUN[0].Observed == nil || !*result.STUN[0].Observed {
+			t.Fatalf("Expected observed STUN mapping, got %+v", result.STUN)
+		}
+		if len(result.STUN[0].Info) == 0 || result.STUN[0].Info[0] != "Test Network" {
+			t.Errorf("Expected IP info for STUN mapping, got %v", result.STUN[0].Info)
+		}
+		if !result.SameIP || result.Symmetric {
+			t.Errorf("Expected same IP without symmetric NAT, got same_ip=%v symmetric=%v", result.SameIP, result.Symmetric)
+		}
+	})
+
+	t.Run("Submitted mappings", func(t *testing.T) {
+		_, result := request("/stun?mapped=192.0.2.1:40000&mapped=192.0.2.1:40001")
+		if len(result.STUN) != 2 || !*result.STUN[0].Observed || *result.STUN[1].Observed {
+			t.Fatalf("Unexpected STUN mappings: %+v", result.STUN)
+		}
+		if !result.SameIP || !result.Symmetric {
+			t.Errorf("Expected symmetric NAT for different ports, got same_ip=%v symmetric=%v", result.SameIP, result.Symmetric)
+		}
+
+		// 出口 IP 不同只通过 same_ip 反映
+		_, result = request("/stun?mapped=198.51.100.1:40000")
+		if result.SameIP || result.Symmetric {
+			t.Errorf("Expected different IP without symmetric NAT, got same_ip=%v symmetric=%v", result.SameIP, result.Symmetric)
+		}
+
+		// 双栈客户端的 IPv6 映射地址不参与对比
+		_, result = request("/stun?mapped=192.0.2.1:40000&mapped=[2001:db8::1]:40000")
+		if !result.SameIP || result.Symmetric {
+			t.Errorf("Expected dual-stack mappings to compare by family, got same_ip=%v symmetric=%v", result.SameIP, result.Symmetric)
+		}
+
+		_, result = request("/stun?mapped=[2001:db8::1]:40000")
+		if result.SameIP || result.Symmetric {
+			t.Errorf("Expected no comparable mapping, got same_ip=%v symmetric=%v", result.SameIP, result.Symmetric)
+		}
+	})
+
+	t.Run("Observed mappings from different local ports", func(t *testing.T) {
+		observations.Record(netip.MustParseAddrPort("192.0.2.1:40002"))
+		_, result := request("/stun")
+		if len(result.STUN) != 2 || !result.SameIP || result.Symmetric {
+			t.Errorf("Observed mappings should not imply symmetric NAT, got %+v", result)
+		}
+	})
+
+	t.Run("Invalid mapping", func(t *testing.T) {
+		if w, _ := request("/stun?mapped=192.0.2.1"); w.Code != 400 {
+			t.Errorf("Expected 400 for invalid mapping, got %d", w.Code)
+		}
+	})
+
+	// 未启用 STUN 服务时不提供对比接口
+	w := httptest.NewRecorder()
+	newTestRouter(t, config, web.Features{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stun", nil))
+	if w.Code != 404 {
+		t.Errorf("Expected 404 without STUN observations, got %d", w.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"html/template"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/page"
+)
+
+// 模板目录中可以覆盖的页面，未提供的页面使用内置模板
+const (
+	TEMPLATE_INDEX = "index.html"
+	TEMPLATE_ERROR = "error.html"
+)
+
+// TEMPLATE_STATIC_DIR 模板目录中静态资源所在的子目录，优先于其他静态资源提供
+const TEMPLATE_STATIC_DIR = "static"
+
+var embeddedTemplates = map[string]string{
+	TEMPLATE_INDEX: page.Template,
+	TEMPLATE_ERROR: page.Error,
+}
+
+type loadedTemplate struct {
+	tmpl    *template.Template
+	modTime time.Time
+	// 是否来自模板目录
+	fromDir bool
+}
+
+// Templates 管理页面模板，优先使用模板目录中的文件，reload 为 true 时在文件变化后重新加载
+type Templates struct {
+	dir    string
+	reload bool
+
+	mu     sync.Mutex
+	loaded map[string]loadedTemplate
+}
+
+// NewTemplates 加载页面模板，dir 为空时只使用内置模板
+func NewTemplates(dir string, reload bool) *Templates {
+	t := &Templates{dir: dir, reload: reload, loaded: map[string]loadedTemplate{}}
+	for name := range embeddedTemplates {
+		t.load(name)
+	}
+	return t
+}
+
+// Lookup 返回指定页面的模板
+func (t *Templates) Lookup(name string) *template.Template {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reload {
+		t.refresh(name)
+	}
+	return t.loaded[name].tmpl
+}
+
+func (t *Templates) load(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loaded[name] = loadedTemplate{tmpl: template.Must(template.New(name).Parse(embeddedTemplates[name]))}
+	t.refresh(name)
+}
+
+// refresh 模板文件存在且有变化时重新解析，解析失败时继续使用之前的模板，
+// 文件被删除后回退到内置模板
+func (t *Templates) refresh(name string) {
+	if t.dir == "" {
+		return
+	}
+	current := t.loaded[name]
+	path := filepath.Join(t.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		if current.fromDir {
+			log.Printf("模板文件 %s 已不可用，使用内置模板: %v\n", path, err)
+			t.loaded[name] = loadedTemplate{tmpl: template.Must(template.New(name).Parse(embeddedTemplates[name]))}
+		}
+		return
+	}
+	if current.fromDir && info.ModTime().Equal(current.modTime) {
+		return
+	}
+
+	tmpl, err := template.New(name).ParseFiles(path)
+	if err != nil {
+		log.Printf("解析模板文件 %s 失败，继续使用之前的模板: %v\n", path, err)
+		// 记录修改时间，避免每个请求都重复解析同一个错误的文件
+		current.modTime = info.ModTime()
+		current.fromDir = true
+		t.loaded[name] = current
+		return
+	}
+	t.loaded[name] = loadedTemplate{tmpl: tmpl, modTime: info.ModTime(), fromDir: true}
+}
+
+// StaticDir 返回模板目录中的静态资源目录，不存在时返回空字符串
+func (t *Templates) StaticDir() string {
+	if t.dir == "" {
+		return ""
+	}
+	dir := filepath.Join(t.dir, TEMPLATE_STATIC_DIR)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
+}
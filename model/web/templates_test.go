@@ -0,0 +1,101 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/web"
+)
+
+func TestTemplates(t *testing.T) {
+	dir := t.TempDir()
+	index := filepath.Join(dir, web.TEMPLATE_INDEX)
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(index, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+		if err := os.Chtimes(index, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set template modification time: %v", err)
+		}
+	}
+	now := time.Now()
+	write("custom {{.IP}}", now)
+
+	render := func(templates *web.Templates) string {
+		t.Helper()
+		var buf strings.Builder
+		if err := templates.Lookup(web.TEMPLATE_INDEX).Execute(&buf, map[string]string{"IP": "192.0.2.1"}); err != nil {
+			t.Fatalf("Failed to execute template: %v", err)
+		}
+		return buf.String()
+	}
+
+	t.Run("Embedded fallback", func(t *testing.T) {
+		templates := web.NewTemplates("", true)
+		if got := render(templates); !strings.Contains(got, "<html") {
+			t.Errorf("Expected embedded page template, got %q", got)
+		}
+		if templates.Lookup(web.TEMPLATE_ERROR) == nil {
+			t.Error("Expected embedded error template")
+		}
+	})
+
+	t.Run("Without reload", func(t *testing.T) {
+		templates := web.NewTemplates(dir, false)
+		if got := render(templates); got != "custom 192.0.2.1" {
+			t.Errorf("Expected custom template, got %q", got)
+		}
+		write("changed {{.IP}}", now.Add(time.Second))
+		if got := render(templates); got != "custom 192.0.2.1" {
+			t.Errorf("Expected template to stay loaded without reload, got %q", got)
+		}
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		templates := web.NewTemplates(dir, true)
+		write("updated {{.IP}}", now.Add(2*time.Second))
+		if got := render(templates); got != "updated 192.0.2.1" {
+			t.Errorf("Expected reloaded template, got %q", got)
+		}
+
+		write("broken {{.IP", now.Add(3*time.Second))
+		if got := render(templates); got != "updated 192.0.2.1" {
+			t.Errorf("Expected previous template after parse error, got %q", got)
+		}
+
+		os.Remove(index)
+		if got := render(templates); !strings.Contains(got, "<html") {
+			t.Errorf("Expected embedded template after file removal, got %q", got)
+		}
+	})
+}
+
+func TestTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, web.TEMPLATE_ERROR), []byte("custom error {{.Code}}: {{.Message}}"), 0644)
+	os.MkdirAll(filepath.Join(dir, web.TEMPLATE_STATIC_DIR), 0755)
+	os.WriteFile(filepath.Join(dir, web.TEMPLATE_STATIC_DIR, "theme.css"), []byte("body{color:red}"), 0644)
+
+	router := newTestRouter(t, &define.Config{Domain: "http://localhost:8080", TemplateDir: dir}, web.Features{})
+
+	request := func(target string) *httptest.ResponseRecorder {
+		return doRequest(router, http.MethodGet, target, nil, map[string]string{"User-Agent": "Mozilla/5.0"})
+	}
+
+	if w := request("/theme.css"); w.Code != 200 || w.Body.String() != "body{color:red}" {
+		t.Errorf("Expected static asset from template directory, got %d %q", w.Code, w.Body.String())
+	}
+	if w := request("/ip/not-an-ip"); w.Code != 400 || w.Body.String() != "custom error 400: 请输入有效的 IP 地址" {
+		t.Errorf("Expected custom error page, got %d %q", w.Code, w.Body.String())
+	}
+	if w := request("/"); w.Code != 200 || !strings.Contains(w.Body.String(), "Test Network") {
+		t.Errorf("Expected embedded page template when index.html is missing, got %d", w.Code)
+	}
+}
@@ -1,22 +1,29 @@
 package web
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"html/template"
+	"io/fs"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
 
-	static "github.com/soulteary/gin-static"
 	"github.com/soulteary/ip-helper/model/define"
 	"github.com/soulteary/ip-helper/model/fn"
 	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
-	"github.com/soulteary/ip-helper/model/page"
+	"github.com/soulteary/ip-helper/model/leaktest"
 	"github.com/soulteary/ip-helper/model/response"
+	"github.com/soulteary/ip-helper/model/stun"
 )
 
-func GetClientIP(c *gin.Context, ip string, ipdb *ipInfo.IPDB) (resultIP string, resultDBInfo []string, err error) {
+func GetClientIP(c *gin.Context, ip string, ipdb ipInfo.Provider) (resultIP string, resultDBInfo ipInfo.Result, err error) {
 	if ip == "" {
 		info, exists := c.Get("ip_info")
 		if !exists {
@@ -24,17 +31,46 @@ func GetClientIP(c *gin.Context, ip string, ipdb *ipInfo.IPDB) (resultIP string,
 		}
 		ip = info.(ipInfo.Info).RealIP
 	}
-	return ip, ipdb.FindByIPIP(ip), nil
+	return ip, ipInfo.Lookup(ipdb, ip, RequestLanguages(c)...), nil
 }
 
-func Response(c *gin.Context, config *define.Config, ipdb *ipInfo.IPDB, ip string, template []byte) {
-	err := error(nil)
-	if config.Debug {
-		template, err = fn.HTTPGet(fmt.Sprintf("http://localhost:%s/index.template.html", config.Port))
-		if err != nil {
-			log.Fatalf("读取模板文件失败: %v\n", err)
-			return
-		}
+// RequestLanguages 获取请求偏好的语言列表，`lang` 参数优先于 Accept-Language 请求头
+func RequestLanguages(c *gin.Context) []string {
+	if c.Request == nil {
+		return nil
+	}
+	languages := []string{}
+	if lang := c.Query("lang"); lang != "" {
+		languages = append(languages, lang)
+	}
+	return append(languages, fn.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+}
+
+// RenderError 返回错误信息，浏览器请求时使用 tmpl 渲染错误页面，其他情况返回 JSON
+func RenderError(c *gin.Context, config *define.Config, tmpl *template.Template, code int, message string, input string) {
+	addVary(c, "Accept", "User-Agent")
+	if format, err := NegotiateFormat(c, config.DownloadTools); err != nil || format != FORMAT_HTML {
+		c.JSON(code, gin.H{"error": message})
+		return
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, gin.H{"Code": code, "Message": message, "Input": input}); err != nil {
+		c.JSON(code, gin.H{"error": message})
+		return
+	}
+	c.Data(code, CONTENT_TYPES[FORMAT_HTML], buf.Bytes())
+}
+
+func Response(c *gin.Context, config *define.Config, ipdb ipInfo.Provider, ip string, tmpl *template.Template) {
+	format, err := NegotiateFormat(c, config.DownloadTools)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	callback := c.DefaultQuery("callback", "callback")
+	if format == FORMAT_JSONP && !ValidCallback(callback) {
+		c.JSON(400, gin.H{"error": "回调函数名称无效"})
+		return
 	}
 
 	ipAddr, dbInfo, err := GetClientIP(c, ip, ipdb)
@@ -43,23 +79,76 @@ func Response(c *gin.Context, config *define.Config, ipdb *ipInfo.IPDB, ip strin
 		return
 	}
 
-	userAgent := c.GetHeader("User-Agent")
-	if fn.IsDownloadTool(userAgent) {
-		c.Data(200, "application/json; charset=utf-8", response.RenderJSON(ipAddr, dbInfo))
-	} else {
-		c.Data(200, "text/html; charset=utf-8", response.RenderHTML(config, c.Request.URL.Path, template, ipAddr, dbInfo))
+	// 未指定 `format` 参数时，响应内容取决于以下请求头
+	addVary(c, "Accept", "Accept-Language", "User-Agent")
+	var body []byte
+	switch format {
+	case FORMAT_JSON:
+		body = response.RenderJSON(ipAddr, dbInfo)
+	case FORMAT_TEXT:
+		body = response.RenderText(ipAddr, dbInfo)
+	case FORMAT_YAML:
+		body = response.RenderYAML(ipAddr, dbInfo)
+	case FORMAT_XML:
+		body = response.RenderXML(ipAddr, dbInfo)
+	case FORMAT_CSV:
+		body = response.RenderCSV(ipAddr, dbInfo)
+	case FORMAT_SHELL:
+		body = response.RenderShell(ipAddr, dbInfo)
+	case FORMAT_JSONP:
+		c.Header("X-Content-Type-Options", "nosniff")
+		body = response.RenderJSONP(callback, ipAddr, dbInfo)
+	default:
+		body, err = response.RenderHTML(config, c.Request.URL.Path, tmpl, ipAddr, dbInfo.Info)
+		if err != nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("渲染页面失败: %v", err)})
+			return
+		}
+	}
+	// 访问者自己的地址随网络变化，只缓存查询指定 IP 的结果
+	if ip != "" && cacheLookup(c, config, ipdb, body) {
+		return
 	}
+	c.Data(200, CONTENT_TYPES[format], body)
 }
 
 type IPForm struct {
 	IP string `form:"ip" binding:"required"`
 }
 
-func Server(config *define.Config, ipdb *ipInfo.IPDB) {
+// Features 依赖其他服务数据的可选功能，字段为 nil 时不注册对应的接口
+type Features struct {
+	// DNS 服务记录的泄露测试会话
+	LeakTest *leaktest.Store
+	// STUN 服务观察到的映射地址
+	STUN *stun.Observations
+	// 内置的静态资源，可以被模板目录和静态资源目录中的同名文件覆盖
+	Assets fs.FS
+}
+
+// NewRouter 创建 WEB 服务的路由
+func NewRouter(config *define.Config, ipdb ipInfo.Provider, features Features) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 	r.Use(gin.Recovery())
-	r.Use(gzip.Gzip(gzip.BestCompression))
+
+	// 与 IPAnalyzerMiddleware 使用相同的受信任代理，保证 `/ip` 与 `/` 返回的地址一致
+	trusted, err := ipInfo.ParseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		log.Printf("受信任代理配置错误，不信任任何代理: %v\n", err)
+		trusted = nil
+	}
+	if err := r.SetTrustedProxies(trusted.CIDRs()); err != nil {
+		log.Printf("受信任代理配置错误，不信任任何代理: %v\n", err)
+	}
+	// gin 无法解析 Forwarded 请求头，其余请求头与 IPAnalyzerMiddleware 保持相同的顺序
+	r.RemoteIPHeaders = []string{}
+	for _, header := range config.ClientIPHeaders {
+		if !strings.EqualFold(header, "Forwarded") {
+			r.RemoteIPHeaders = append(r.RemoteIPHeaders, header)
+		}
+	}
+	r.Use(gzip.Gzip(gzip.BestCompression, gzip.WithExcludedPaths([]string{BATCH_STREAM_PATH})))
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -68,18 +157,25 @@ func Server(config *define.Config, ipdb *ipInfo.IPDB) {
 		})
 	})
 
-	r.Use(CacheMiddleware())
-	r.Use(static.Serve("/", static.LocalFile("./public", false)))
-	r.Use(AuthMiddleware(config))
-	r.Use(IPAnalyzerMiddleware())
+	// 调试模式下模板文件变化后自动重新加载，静态资源每次都从磁盘读取
+	templates := NewTemplates(config.TemplateDir, config.Debug)
 
-	globalTemplate := []byte(page.Template)
-	if config.Debug {
-		os.WriteFile("./public/index.template.html", globalTemplate, 0644)
+	// 静态资源依次从模板目录、静态资源目录和内置文件中查找
+	layers := []fs.FS{}
+	if dir := templates.StaticDir(); dir != "" {
+		layers = append(layers, os.DirFS(dir))
 	}
+	if config.AssetsDir != "" {
+		layers = append(layers, os.DirFS(config.AssetsDir))
+	}
+	r.Use(ServeAssets(NewAssets(append(layers, features.Assets)...)))
+
+	r.Use(CacheMiddleware())
+	r.Use(AuthMiddleware(config))
+	r.Use(IPAnalyzerMiddleware(trusted, config.ClientIPHeaders))
 
 	r.GET("/", func(c *gin.Context) {
-		Response(c, config, ipdb, "", globalTemplate)
+		Response(c, config, ipdb, "", templates.Lookup(TEMPLATE_INDEX))
 	})
 
 	r.POST("/", func(c *gin.Context) {
@@ -107,16 +203,67 @@ func Server(config *define.Config, ipdb *ipInfo.IPDB) {
 			c.JSON(500, gin.H{"error": "IP info not found"})
 			return
 		}
-		c.String(200, info.(ipInfo.Info).ClientIP)
+		c.String(200, info.(ipInfo.Info).RealIP)
+	})
+
+	r.GET("/metrics", func(c *gin.Context) {
+		c.Data(200, "text/plain; version=0.0.4; charset=utf-8", RenderMetrics(ipdb))
+	})
+
+	r.GET("/languages", func(c *gin.Context) {
+		c.JSON(200, gin.H{"languages": ipdb.Languages()})
 	})
 
 	r.GET("/ip/:ip", func(c *gin.Context) {
-		Response(c, config, ipdb, c.Param("ip"), globalTemplate)
+		if !fn.IsValidIPAddress(c.Param("ip")) {
+			RenderError(c, config, templates.Lookup(TEMPLATE_ERROR), 400, "请输入有效的 IP 地址", c.Param("ip"))
+			return
+		}
+		Response(c, config, ipdb, c.Param("ip"), templates.Lookup(TEMPLATE_INDEX))
 	})
 
+	registerBatch(r, config, ipdb)
+
+	if features.LeakTest != nil {
+		registerLeakTest(r, config, ipdb, features.LeakTest)
+	}
+	if features.STUN != nil {
+		registerSTUN(r, ipdb, features.STUN)
+	}
+
+	return r
+}
+
+func Server(config *define.Config, ipdb ipInfo.Provider) error {
 	serverAddr := fmt.Sprintf(":%s", config.Port)
+	listener, err := net.Listen("tcp", serverAddr)
+	if err != nil {
+		return fmt.Errorf("WEB 服务器启动失败: %v", err)
+	}
 	log.Printf("WEB 启动服务器于 %s\n", config.Port)
-	if err := r.Run(serverAddr); err != nil {
-		log.Fatalf("WEB 服务器启动失败: %v", err)
+	return Serve(context.Background(), config, ipdb, Features{}, listener)
+}
+
+// Serve 在已绑定的监听器上提供 WEB 服务，ctx 结束后停止接受新请求并等待进行中的请求完成
+func Serve(ctx context.Context, config *define.Config, ipdb ipInfo.Provider, features Features, listener net.Listener) error {
+	server := &http.Server{Handler: NewRouter(config, ipdb, features)}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	// 由调用方控制排空超时，这里会一直等待进行中的请求完成
+	if err := server.Shutdown(context.Background()); err != nil {
+		return err
+	}
+	if err := <-errCh; err != http.ErrServerClosed {
+		return err
 	}
+	return nil
 }
@@ -1,11 +1,18 @@
 package web_test
 
 import (
+	"context"
 	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/soulteary/ip-helper/model/define"
@@ -13,6 +20,30 @@ import (
 	"github.com/soulteary/ip-helper/model/web"
 )
 
+// newTestRouter 创建使用内存数据库的路由，192.0.2.0/24 的查询结果为 `Test Network`
+func newTestRouter(t *testing.T, config *define.Config, features web.Features) *gin.Engine {
+	t.Helper()
+	memory, err := ipInfo.NewMemoryProvider("memory", map[string][]string{"192.0.2.0/24": {"Test Network"}})
+	if err != nil {
+		t.Fatalf("Failed to create memory provider: %v", err)
+	}
+	return web.NewRouter(config, memory, features)
+}
+
+// doRequest 以 192.0.2.1 的身份发送请求，值为空的请求头不会设置
+func doRequest(router http.Handler, method string, target string, body io.Reader, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, body)
+	req.RemoteAddr = "192.0.2.1:1234"
+	for key, value := range header {
+		if value != "" {
+			req.Header.Set(key, value)
+		}
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
 func GetIPDB() (*ipInfo.IPDB, error) {
 	workDir, _ := os.Getwd()
 	dbPath := filepath.Join(workDir, "../../data/ipipfree.ipdb")
@@ -102,13 +133,13 @@ func TestGetClientIP(t *testing.T) {
 				t.Errorf("Expected IP %s, got %s", tt.expectedIP, ip)
 			}
 			if !tt.expectError {
-				if len(dbInfo) != len(tt.expectedDBInfo) {
-					fmt.Println(dbInfo)
-					t.Errorf("Expected DB info length %d, got %d", len(tt.expectedDBInfo), len(dbInfo))
+				if len(dbInfo.Info) != len(tt.expectedDBInfo) {
+					fmt.Println(dbInfo.Info)
+					t.Errorf("Expected DB info length %d, got %d", len(tt.expectedDBInfo), len(dbInfo.Info))
 				}
 				for i := range tt.expectedDBInfo {
-					if dbInfo[i] != tt.expectedDBInfo[i] {
-						t.Errorf("Expected DB info[%d]=%s, got %s", i, tt.expectedDBInfo[i], dbInfo[i])
+					if dbInfo.Info[i] != tt.expectedDBInfo[i] {
+						t.Errorf("Expected DB info[%d]=%s, got %s", i, tt.expectedDBInfo[i], dbInfo.Info[i])
 					}
 				}
 			}
@@ -189,7 +220,7 @@ func TestResponse(t *testing.T) {
 			tt.setupContext(c)
 
 			// 执行测试
-			web.Response(c, tt.config, db, tt.ip, tt.template)
+			web.Response(c, tt.config, db, tt.ip, template.Must(template.New("index").Parse(string(tt.template))))
 
 			// 验证响应状态码
 			if w.Code != tt.expectedCode {
@@ -210,3 +241,97 @@ func TestResponse(t *testing.T) {
 		})
 	}
 }
+
+// 测试 RequestLanguages 函数
+func TestRequestLanguages(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		acceptLanguage string
+		expected       []string
+	}{
+		{"No preference", "/", "", []string{}},
+		{"Accept-Language only", "/", "en-US,zh;q=0.5", []string{"en-US", "zh"}},
+		{"Query overrides header", "/?lang=EN", "zh-CN", []string{"EN", "zh-CN"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", tt.url, nil)
+			c.Request.Header.Set("Accept-Language", tt.acceptLanguage)
+
+			got := web.RequestLanguages(c)
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected languages %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// 没有请求对象时不应该出错
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if got := web.RequestLanguages(c); len(got) != 0 {
+		t.Errorf("Expected no languages, got %v", got)
+	}
+}
+
+func TestServe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	memory, _ := ipInfo.NewMemoryProvider("memory", nil)
+	config := &define.Config{Domain: "http://localhost:8080"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- web.Serve(ctx, config, memory, web.Features{}, listener)
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/health")
+	if err != nil {
+		t.Fatalf("Failed to request health check: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status code 200, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected graceful shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve should return after shutdown")
+	}
+}
+
+func TestInvalidIPParam(t *testing.T) {
+	router := newTestRouter(t, &define.Config{Domain: "http://localhost:8080"}, web.Features{})
+
+	request := func(target string, userAgent string) *httptest.ResponseRecorder {
+		return doRequest(router, http.MethodGet, target, nil, map[string]string{"User-Agent": userAgent})
+	}
+
+	w := request("/ip/%22%3E%3Csvg%20onload=alert(1)%3E", "Mozilla/5.0")
+	if w.Code != 400 || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected 400 HTML page, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if strings.Contains(w.Body.String(), "<svg") || !strings.Contains(w.Body.String(), "&lt;svg onload=alert(1)&gt;") {
+		t.Errorf("Expected escaped input in error page, got %s", w.Body.String())
+	}
+
+	w = request("/ip/not-an-ip", "curl/7.64.1")
+	if w.Code != 400 || !strings.Contains(w.Body.String(), `"error"`) {
+		t.Errorf("Expected 400 JSON error for command line tools, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = request("/ip/192.0.2.8", "Mozilla/5.0")
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `value="192.0.2.8"`) || !strings.Contains(w.Body.String(), "Test Network") {
+		t.Errorf("Expected rendered page for valid IP, got %d", w.Code)
+	}
+}
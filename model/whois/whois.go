@@ -0,0 +1,137 @@
+package whois
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/soulteary/ip-helper/model/banner"
+	"github.com/soulteary/ip-helper/model/define"
+	"github.com/soulteary/ip-helper/model/fn"
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+)
+
+// Protocol 按 RFC 3912 读取一行查询，输出结果后关闭连接
+var Protocol = &banner.Protocol{
+	Name: "WHOIS",
+	Respond: func(s *banner.Session, line string) (string, bool) {
+		// 发送时会补充结尾的换行
+		return strings.TrimSuffix(Answer(s.IPDB, line, s.RemoteAddr), "\r\n"), true
+	},
+	// 返回错误后关闭连接，避免客户端一直等待
+	TooLong:       "%ERROR: 查询过长",
+	QuitTooLong:   true,
+	Timeout:       define.WHOIS_TIMEOUT,
+	MaxLineLength: define.WHOIS_MAX_QUERY_LENGTH,
+}
+
+// Answer 根据查询内容生成 whois 格式的响应，查询可以是 IP、CIDR 或 `me`，
+// 为空时同样查询连接的来源地址。部分客户端会在查询前添加参数，只使用最后一个字段
+func Answer(ipdb ipInfo.Provider, query string, remote net.Addr) string {
+	fields := strings.Fields(query)
+	keyword := "me"
+	if len(fields) > 0 {
+		keyword = fields[len(fields)-1]
+	}
+
+	var b strings.Builder
+	b.WriteString("% IP Helper whois 服务\r\n")
+	b.WriteString("% 查询: " + keyword + "\r\n\r\n")
+
+	var prefix netip.Prefix
+	self := strings.EqualFold(keyword, "me")
+	switch {
+	case self:
+		addr, err := netip.ParseAddr(fn.GetBaseIP(remote.String()))
+		if err != nil {
+			return b.String() + "%ERROR: 无法识别客户端地址\r\n"
+		}
+		addr = addr.Unmap()
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	case strings.Contains(keyword, "/"):
+		parsed, err := netip.ParsePrefix(keyword)
+		if err != nil {
+			return b.String() + "%ERROR: 无法识别的查询，支持 IP、CIDR 或 me\r\n"
+		}
+		prefix = parsed.Masked()
+	default:
+		addr, err := netip.ParseAddr(keyword)
+		if err != nil {
+			return b.String() + "%ERROR: 无法识别的查询，支持 IP、CIDR 或 me\r\n"
+		}
+		addr = addr.Unmap()
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+
+	ip := prefix.Addr().String()
+	result := ipInfo.Lookup(ipdb, ip)
+	field(&b, "ip", ip)
+	if !prefix.IsSingleIP() {
+		field(&b, "cidr", prefix.String())
+		field(&b, "range", ip+" - "+lastAddr(prefix).String())
+	}
+	version := "4"
+	if prefix.Addr().Is6() {
+		version = "6"
+	}
+	field(&b, "version", version)
+	field(&b, "scope", scope(prefix.Addr()))
+	field(&b, "country", result.Country)
+	field(&b, "country-code", result.CountryCode)
+	field(&b, "region", result.Region)
+	field(&b, "city", result.City)
+	field(&b, "isp", result.ISP)
+	field(&b, "owner", result.Owner)
+	field(&b, "timezone", result.Timezone)
+	if result.Latitude != nil && result.Longitude != nil {
+		field(&b, "latitude", strconv.FormatFloat(*result.Latitude, 'f', -1, 64))
+		field(&b, "longitude", strconv.FormatFloat(*result.Longitude, 'f', -1, 64))
+	}
+	field(&b, "descr", strings.Join(fn.RemoveDuplicates(result.Info), " "))
+	field(&b, "source", result.Source)
+
+	// 查询自己时附带连接的分析结果
+	if self {
+		b.WriteString("\r\n% 连接信息\r\n")
+		field(&b, "remote-addr", remote.String())
+		field(&b, "network", remote.Network())
+	}
+	return b.String()
+}
+
+// field 按 whois 的习惯输出对齐的 `key: value` 行，忽略空值
+func field(b *strings.Builder, key string, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(b, "%-16s%s\r\n", key+":", value)
+}
+
+// scope 返回地址的类型
+func scope(addr netip.Addr) string {
+	switch {
+	case addr.IsLoopback():
+		return "loopback"
+	case addr.IsPrivate():
+		return "private"
+	case addr.IsLinkLocalUnicast():
+		return "link-local"
+	case addr.IsMulticast():
+		return "multicast"
+	case addr.IsUnspecified():
+		return "unspecified"
+	}
+	return "global"
+}
+
+// lastAddr 返回地址段的最后一个地址
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	raw := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(raw)*8; i++ {
+		raw[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(raw)
+	return addr
+}
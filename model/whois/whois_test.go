@@ -0,0 +1,127 @@
+package whois_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	ipInfo "github.com/soulteary/ip-helper/model/ip-info"
+	"github.com/soulteary/ip-helper/model/testutil"
+	"github.com/soulteary/ip-helper/model/whois"
+)
+
+func newProvider(t *testing.T) ipInfo.Provider {
+	memory, err := ipInfo.NewMemoryProvider("memory", map[string][]string{
+		"192.0.2.0/24":  {"中国", "北京", "北京", "测试网络"},
+		"2001:db8::/32": {"Documentation"},
+	})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	return memory
+}
+
+func TestAnswer(t *testing.T) {
+	ipdb := newProvider(t)
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 50000}
+
+	tests := []struct {
+		name     string
+		query    string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "IP",
+			query:    "192.0.2.1\r\n",
+			contains: []string{"ip:             192.0.2.1\r\n", "version:        4\r\n", "country:        中国\r\n", "isp:            测试网络\r\n", "source:         memory\r\n"},
+			excludes: []string{"cidr:", "remote-addr:"},
+		},
+		{
+			name:     "CIDR",
+			query:    "192.0.2.77/24\r\n",
+			contains: []string{"ip:             192.0.2.0\r\n", "cidr:           192.0.2.0/24\r\n", "range:          192.0.2.0 - 192.0.2.255\r\n"},
+		},
+		{
+			name:     "IPv6",
+			query:    "2001:db8::1\r\n",
+			contains: []string{"version:        6\r\n", "descr:          Documentation\r\n"},
+		},
+		{
+			name:     "Me",
+			query:    "ME\r\n",
+			contains: []string{"ip:             192.0.2.10\r\n", "remote-addr:    192.0.2.10:50000\r\n", "network:        tcp\r\n"},
+		},
+		{
+			name:     "Empty query",
+			query:    "\r\n",
+			contains: []string{"ip:             192.0.2.10\r\n"},
+		},
+		{
+			name:     "Client flags",
+			query:    "-B 192.0.2.1\r\n",
+			contains: []string{"ip:             192.0.2.1\r\n"},
+		},
+		{
+			name:     "Private address",
+			query:    "10.0.0.1\r\n",
+			contains: []string{"scope:          private\r\n"},
+		},
+		{
+			name:     "Invalid query",
+			query:    "example.com\r\n",
+			contains: []string{"%ERROR:"},
+			excludes: []string{"ip:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer := whois.Answer(ipdb, tt.query, remote)
+			if !strings.HasPrefix(answer, "% ") {
+				t.Errorf("响应应该以注释开头，实际为 %q", answer)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(answer, want) {
+					t.Errorf("响应应该包含 %q，实际为 %q", want, answer)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(answer, unwanted) {
+					t.Errorf("响应不应该包含 %q，实际为 %q", unwanted, answer)
+				}
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	ipdb := newProvider(t)
+	addr := testutil.Serve(t, func(ctx context.Context, listener net.Listener) error {
+		return whois.Protocol.Serve(ctx, ipdb, listener)
+	})
+
+	query := func(data string) string {
+		conn := testutil.Dial(t, addr)
+		conn.Write([]byte(data))
+		conn.(*net.TCPConn).CloseWrite()
+		// 服务端输出结果后关闭连接
+		response, err := io.ReadAll(conn)
+		if err != nil {
+			t.Fatalf("读取响应失败: %v", err)
+		}
+		return string(response)
+	}
+
+	if response := query("192.0.2.1\r\n"); !strings.Contains(response, "country:        中国") || !strings.HasSuffix(response, "source:         memory\r\n") {
+		t.Errorf("响应应该包含查询结果，实际为 %q", response)
+	}
+	if response := query("me"); !strings.Contains(response, "remote-addr:    127.0.0.1:") {
+		t.Errorf("没有换行的查询同样应该返回结果，实际为 %q", response)
+	}
+	if response := query(strings.Repeat("1", 1024) + "\r\n"); response != "%ERROR: 查询过长\r\n" {
+		t.Errorf("过长的查询应该返回错误，实际为 %q", response)
+	}
+}